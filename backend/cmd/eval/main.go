@@ -0,0 +1,197 @@
+// eval 图片分析离线评估工具
+//
+// 对 fixtures 目录中的图片逐一调用 ImageAnalysisService，统计结构合法率、重点知识点召回率、
+// 分类准确率、延迟和 token 用量，输出 JSON 和 Markdown 报告，用于对比不同提示词版本。
+//
+// 用法：
+//
+//	go run ./cmd/eval -fixtures testdata/eval -mode live -record          # 调用真实模型并录制响应
+//	go run ./cmd/eval -fixtures testdata/eval -json report.json -md report.md  # 使用录制的响应回放
+package main
+
+import (
+	"ai-note-service/internal/application/app"
+	"ai-note-service/internal/application/common"
+	"ai-note-service/internal/application/eval"
+	"ai-note-service/internal/application/experiment"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/logger"
+	"ai-note-service/internal/application/profile"
+	"ai-note-service/internal/application/prompt"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "配置文件路径，AI 服务地址、密钥和模型从中读取")
+	fixtures := flag.String("fixtures", "", "用例目录：图片及同名 .yaml 期望结果（必填）")
+	mode := flag.String("mode", eval.ModeReplay, "上游模式：live 调用真实模型，replay 使用录制的响应")
+	recordings := flag.String("recordings", "", "录制文件目录，默认为 <fixtures>/recordings")
+	record := flag.Bool("record", false, "live 模式下录制响应，供之后 replay 使用")
+	lang := flag.String("lang", "", "分析语言，默认使用 i18n.default_language")
+	model := flag.String("model", "", "模型名称，默认使用 ai.default_model")
+	variant := flag.String("variant", "", "提示词模板变体，如 concise 对应 analysis+concise.<lang>.tmpl")
+	promptDir := flag.String("prompt-dir", "", "提示词模板目录，默认使用配置中的 prompt.dir")
+	threshold := flag.Float64("threshold", eval.DefaultThreshold, "知识点标题模糊匹配的相似度阈值（0-1）")
+	jsonOut := flag.String("json", "", "JSON 报告输出路径，- 表示标准输出")
+	mdOut := flag.String("md", "", "Markdown 报告输出路径，- 表示标准输出；未指定任何输出时默认输出 Markdown 到标准输出")
+	verbose := flag.Bool("v", false, "输出服务日志")
+	flag.Parse()
+
+	if err := run(options{
+		configPath: *configPath,
+		fixtures:   *fixtures,
+		mode:       *mode,
+		recordings: *recordings,
+		record:     *record,
+		lang:       *lang,
+		model:      *model,
+		variant:    *variant,
+		promptDir:  *promptDir,
+		threshold:  *threshold,
+		jsonOut:    *jsonOut,
+		mdOut:      *mdOut,
+		verbose:    *verbose,
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "eval:", err)
+		os.Exit(1)
+	}
+}
+
+// options 命令行参数
+type options struct {
+	configPath, fixtures, mode, recordings string
+	record                                 bool
+	lang, model, variant, promptDir        string
+	threshold                              float64
+	jsonOut, mdOut                         string
+	verbose                                bool
+}
+
+// run 加载用例、启动本地上游服务并执行评估
+func run(opts options) error {
+	if opts.fixtures == "" {
+		return fmt.Errorf("-fixtures is required")
+	}
+	if opts.recordings == "" {
+		opts.recordings = filepath.Join(opts.fixtures, "recordings")
+	}
+	if opts.jsonOut == "" && opts.mdOut == "" {
+		opts.mdOut = "-"
+	}
+
+	cases, err := eval.LoadCases(opts.fixtures)
+	if err != nil {
+		return fmt.Errorf("load fixtures: %w", err)
+	}
+
+	cfg, err := common.LoadAppConfig(opts.configPath, false)
+	if err != nil {
+		return err
+	}
+	cfg.Log.Level = "error"
+	if opts.verbose {
+		cfg.Log.Level = "debug"
+	}
+	if err := logger.Init(cfg.Log); err != nil {
+		return err
+	}
+
+	language := i18n.Language(cfg.I18n.DefaultLanguage)
+	if opts.lang != "" {
+		l, ok := i18n.Match(opts.lang)
+		if !ok {
+			return fmt.Errorf("unsupported language %q, expected one of %v", opts.lang, i18n.Supported())
+		}
+		language = l
+	}
+	if language == "" {
+		language = i18n.ZhCN
+	}
+
+	if opts.promptDir != "" {
+		cfg.Prompt.Dir = opts.promptDir
+	}
+	prompts, err := prompt.Load(cfg.Prompt.Dir)
+	if err != nil {
+		return fmt.Errorf("load prompt templates: %w", err)
+	}
+	if opts.variant != "" && !prompts.HasVariant(prompt.NameAnalysis, opts.variant) {
+		return fmt.Errorf("prompt variant %q not found for %s", opts.variant, prompt.NameAnalysis)
+	}
+
+	// 上游请求经过本地服务：replay 模式直接返回录制内容，live 模式转发并统计 token 用量
+	recordDir := opts.recordings
+	if opts.mode == eval.ModeLive && !opts.record {
+		recordDir = ""
+	}
+	upstream, err := eval.NewUpstream(opts.mode, cfg.AI.BaseURL, recordDir, cases)
+	if err != nil {
+		return err
+	}
+	baseURL, err := upstream.Start()
+	if err != nil {
+		return err
+	}
+	defer upstream.Close()
+
+	// 评估时关闭缓存和配置中的实验，模板变体和模型通过一个单变体实验指定
+	if opts.model == "" {
+		opts.model = cfg.AI.DefaultModel
+	}
+	cfg.AI.BaseURL = baseURL
+	cfg.Cache.Enabled = false
+	cfg.Experiments = []global.ExperimentConfig{{
+		Name:     "eval",
+		Target:   experiment.TargetAnalysis,
+		Enabled:  true,
+		Variants: []global.VariantConfig{{Name: "eval", Weight: 1, PromptVariant: opts.variant, Model: opts.model}},
+	}}
+	// 评估不写入反馈文件
+	container, err := app.New(global.Static(cfg), app.Options{Prompts: prompts, Feedback: feedback.NewMemoryStore(), Profiles: profile.NewMemoryStore()})
+	if err != nil {
+		return err
+	}
+	defer container.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report := eval.Run(ctx, container.ImageAnalysis, upstream, cases, eval.Options{
+		Mode:      opts.mode,
+		Model:     opts.model,
+		Variant:   opts.variant,
+		Language:  language,
+		Threshold: opts.threshold,
+	})
+
+	if err := writeReport(report, opts.jsonOut, "json"); err != nil {
+		return err
+	}
+	return writeReport(report, opts.mdOut, "md")
+}
+
+// writeReport 将报告写入文件或标准输出，path 为空时跳过
+func writeReport(report *eval.Report, path, format string) error {
+	if path == "" {
+		return nil
+	}
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return report.Write(w, format)
+}
@@ -0,0 +1,40 @@
+// openapi 根据控制器注释和 schema 类型生成 OpenAPI 3 文档
+//
+// 用法：
+//
+//	go run ./cmd/openapi -o internal/application/openapi/openapi.json
+package main
+
+import (
+	"ai-note-service/internal/application/openapi"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	root := flag.String("root", ".", "模块根目录（go.mod 所在目录）")
+	out := flag.String("o", "", "输出文件，为空时输出到标准输出")
+	flag.Parse()
+
+	if err := run(*root, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "openapi:", err)
+		os.Exit(1)
+	}
+}
+
+func run(root, out string) error {
+	doc, err := openapi.Generate(root)
+	if err != nil {
+		return err
+	}
+	data, err := openapi.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(out, data, 0o644)
+}
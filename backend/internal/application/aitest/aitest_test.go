@@ -0,0 +1,183 @@
+package aitest
+
+import (
+	"ai-note-service/internal/application/schema"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	return resp
+}
+
+const chatBody = `{"model":"m","messages":[{"role":"user","content":"hi"}]}`
+
+func TestServerRepliesInOrder(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.Enqueue(Reply{Content: "first"}, Reply{Status: http.StatusTooManyRequests}, Reply{Malformed: true})
+	s.Handle(func(req *schema.ChatRequest) Reply {
+		return Reply{Content: "echo: " + ContentText(req.Messages[0])}
+	})
+
+	var resp schema.ChatResponse
+	r := post(t, http.DefaultClient, s.URL+"/chat/completions", chatBody)
+	json.NewDecoder(r.Body).Decode(&resp)
+	r.Body.Close()
+	if got := resp.Choices[0].Message.Content; got != "first" || resp.Usage.TotalTokens == 0 {
+		t.Errorf("first reply = %v, usage %+v", got, resp.Usage)
+	}
+
+	r = post(t, http.DefaultClient, s.URL+"/chat/completions", chatBody)
+	r.Body.Close()
+	if r.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("second reply status = %d, want 429", r.StatusCode)
+	}
+
+	r = post(t, http.DefaultClient, s.URL+"/chat/completions", chatBody)
+	data, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+	if json.Valid(data) {
+		t.Errorf("third reply should be malformed JSON: %s", data)
+	}
+
+	r = post(t, http.DefaultClient, s.URL+"/chat/completions", chatBody)
+	json.NewDecoder(r.Body).Decode(&resp)
+	r.Body.Close()
+	if got := resp.Choices[0].Message.Content; got != "echo: hi" {
+		t.Errorf("handler reply = %v", got)
+	}
+	if n := len(s.Requests()); n != 4 {
+		t.Errorf("recorded %d requests, want 4", n)
+	}
+}
+
+func TestServerStreams(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.Enqueue(Reply{Content: "勾股定理 explained"})
+
+	r := post(t, http.DefaultClient, s.URL+"/chat/completions", `{"model":"m","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	defer r.Body.Close()
+	if ct := r.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	var content strings.Builder
+	var done, finished bool
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", data, err)
+		}
+		content.WriteString(chunk.Choices[0].Delta["content"])
+		if chunk.Choices[0].FinishReason != nil {
+			finished = chunk.Usage != nil
+		}
+	}
+	if content.String() != "勾股定理 explained" || !done || !finished {
+		t.Errorf("streamed %q, done=%v, finished with usage=%v", content.String(), done, finished)
+	}
+}
+
+func TestServerEmbeddingsAreDeterministic(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetEmbeddingDim(4)
+
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	r := post(t, http.DefaultClient, s.URL+"/embeddings", `{"model":"e","input":["a","b"]}`)
+	json.NewDecoder(r.Body).Decode(&resp)
+	r.Body.Close()
+
+	if len(resp.Data) != 2 || len(resp.Data[0].Embedding) != 4 {
+		t.Fatalf("unexpected embeddings: %+v", resp)
+	}
+	want := Embedding("b", 4)
+	for i, v := range resp.Data[1].Embedding {
+		if v != want[i] {
+			t.Fatalf("embedding for b = %v, want %v", resp.Data[1].Embedding, want)
+		}
+	}
+}
+
+func TestServerLatencyRespectsCancellation(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetLatency(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, s.URL+"/chat/completions", strings.NewReader(chatBody))
+	start := time.Now()
+	_, err := http.DefaultClient.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("request was not cancelled promptly")
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	s := NewServer()
+	dir := t.TempDir()
+	s.Enqueue(Reply{Content: "recorded answer"})
+
+	recorder := &http.Client{Transport: NewRecorder(dir, nil)}
+	r := post(t, recorder, s.URL+"/chat/completions", chatBody)
+	r.Body.Close()
+	s.Close()
+
+	// 回放时不访问网络；字段顺序、空白和请求头不同不影响匹配
+	replayer := &http.Client{Transport: NewReplayer(dir)}
+	req, _ := http.NewRequest(http.MethodPost, "http://offline.invalid/chat/completions",
+		strings.NewReader(`{"messages": [{"content": "hi", "role": "user"}], "model": "m"}`))
+	req.Header.Set("Authorization", "Bearer other-key")
+	r, err := replayer.Do(req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	var resp schema.ChatResponse
+	json.NewDecoder(r.Body).Decode(&resp)
+	r.Body.Close()
+	if got := resp.Choices[0].Message.Content; got != "recorded answer" {
+		t.Errorf("replayed content = %v", got)
+	}
+
+	_, err = replayer.Post("http://offline.invalid/chat/completions", "application/json", strings.NewReader(`{"model":"other"}`))
+	if !errors.Is(err, ErrNoRecording) {
+		t.Errorf("unrecorded request error = %v, want ErrNoRecording", err)
+	}
+}
@@ -0,0 +1,382 @@
+// Package aitest 提供测试用的 OpenAI 兼容模型服务和 HTTP 录制/回放，使测试无需访问真实模型
+package aitest
+
+import (
+	"ai-note-service/internal/application/schema"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultContent 未设置回复时返回的内容
+const DefaultContent = "fake reply"
+
+// DefaultEmbeddingDim 默认的 embedding 维度
+const DefaultEmbeddingDim = 8
+
+// Reply 一次 chat completions 请求的回复
+type Reply struct {
+	Content   string        // 助手回复内容
+	Status    int           // 非 0 且非 200 时返回 OpenAI 格式的错误
+	Malformed bool          // 返回无法解析的 JSON（流式请求时为无法解析的数据块）
+	Latency   time.Duration // 返回前等待的时间，请求被取消时提前结束
+	Usage     *schema.Usage // 为空时按内容长度估算
+}
+
+// Server OpenAI 兼容的测试服务，支持 /chat/completions（含流式）和 /embeddings
+// 回复按以下顺序决定：Enqueue 排队的回复 > Handle 设置的处理函数 > 返回 DefaultContent
+type Server struct {
+	URL string // 可直接用作 ai.base_url
+
+	server *httptest.Server
+
+	mu           sync.Mutex
+	queue        []Reply
+	handler      func(*schema.ChatRequest) Reply
+	latency      time.Duration
+	embeddingDim int
+	requests     []*schema.ChatRequest
+	headers      []http.Header // 与 requests 一一对应的请求头
+}
+
+// NewServer 启动测试服务，使用完毕后需调用 Close
+func NewServer() *Server {
+	s := &Server{embeddingDim: DefaultEmbeddingDim}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /chat/completions", s.chatCompletions)
+	mux.HandleFunc("POST /embeddings", s.embeddings)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close 关闭服务
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Enqueue 按顺序为后续请求排队回复，每个回复只使用一次
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, replies...)
+}
+
+// Handle 设置回复处理函数，排队的回复用完后使用
+func (s *Server) Handle(fn func(*schema.ChatRequest) Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = fn
+}
+
+// SetLatency 设置所有请求的默认延迟，Reply.Latency 不为 0 时以其为准
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetEmbeddingDim 设置 embedding 维度
+func (s *Server) SetEmbeddingDim(dim int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embeddingDim = dim
+}
+
+// Requests 返回收到的 chat completions 请求
+func (s *Server) Requests() []*schema.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*schema.ChatRequest(nil), s.requests...)
+}
+
+// LastRequest 返回最近一次 chat completions 请求，没有请求时返回 nil
+func (s *Server) LastRequest() *schema.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return nil
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// LastHeader 返回最近一次 chat completions 请求的请求头，没有请求时返回 nil
+func (s *Server) LastHeader() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.headers) == 0 {
+		return nil
+	}
+	return s.headers[len(s.headers)-1]
+}
+
+// next 取出下一个回复并记录请求
+func (s *Server) next(req *schema.ChatRequest, header http.Header) Reply {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.headers = append(s.headers, header.Clone())
+	var reply Reply
+	switch {
+	case len(s.queue) > 0:
+		reply = s.queue[0]
+		s.queue = s.queue[1:]
+	case s.handler != nil:
+		handler := s.handler
+		s.mu.Unlock()
+		reply = handler(req)
+		s.mu.Lock()
+	default:
+		reply = Reply{Content: DefaultContent}
+	}
+	if reply.Latency == 0 {
+		reply.Latency = s.latency
+	}
+	s.mu.Unlock()
+	return reply
+}
+
+// chatCompletions 处理 chat completions 请求
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req schema.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	reply := s.next(&req, r.Header)
+	if !wait(r, reply.Latency) {
+		return
+	}
+	if reply.Status != 0 && reply.Status != http.StatusOK {
+		writeError(w, reply.Status, "fake_error", http.StatusText(reply.Status))
+		return
+	}
+
+	usage := reply.Usage
+	if usage == nil {
+		usage = estimateUsage(&req, reply.Content)
+	}
+	if req.Stream {
+		s.stream(w, &req, reply, usage)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if reply.Malformed {
+		io.WriteString(w, `{"id": "chatcmpl-fake", "choices": [`)
+		return
+	}
+	json.NewEncoder(w).Encode(schema.ChatResponse{
+		ID:      "chatcmpl-fake",
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []schema.Choice{{
+			Message:      schema.NewTextMessage("assistant", reply.Content),
+			FinishReason: "stop",
+		}},
+		Usage: *usage,
+	})
+}
+
+// streamChunk 流式响应的数据块
+type streamChunk struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []streamChoice `json:"choices"`
+	Usage   *schema.Usage  `json:"usage,omitempty"`
+}
+
+// streamChoice 流式响应的增量内容
+type streamChoice struct {
+	Index        int               `json:"index"`
+	Delta        map[string]string `json:"delta"`
+	FinishReason *string           `json:"finish_reason"`
+}
+
+// stream 以 SSE 格式分块返回回复内容，最后一块带有 finish_reason 和 usage
+func (s *Server) stream(w http.ResponseWriter, req *schema.ChatRequest, reply Reply, usage *schema.Usage) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	send := func(data string) {
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	chunk := func(delta map[string]string, finish *string, usage *schema.Usage) string {
+		data, _ := json.Marshal(streamChunk{
+			ID:      "chatcmpl-fake",
+			Object:  "chat.completion.chunk",
+			Created: time.Now().Unix(),
+			Model:   req.Model,
+			Choices: []streamChoice{{Delta: delta, FinishReason: finish}},
+			Usage:   usage,
+		})
+		return string(data)
+	}
+
+	send(chunk(map[string]string{"role": "assistant"}, nil, nil))
+	if reply.Malformed {
+		send(`{"choices": [{"delta": `)
+		return
+	}
+	for _, piece := range splitContent(reply.Content, 4) {
+		send(chunk(map[string]string{"content": piece}, nil, nil))
+	}
+	stop := "stop"
+	send(chunk(map[string]string{}, &stop, usage))
+	send("[DONE]")
+}
+
+// embeddingRequest embeddings 请求
+type embeddingRequest struct {
+	Model string          `json:"model"`
+	Input json.RawMessage `json:"input"` // string 或 []string
+}
+
+// embeddings 返回由输入内容确定的伪 embedding，相同输入得到相同向量（已归一化）
+func (s *Server) embeddings(w http.ResponseWriter, r *http.Request) {
+	var req embeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	var inputs []string
+	if err := json.Unmarshal(req.Input, &inputs); err != nil {
+		var single string
+		if err := json.Unmarshal(req.Input, &single); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "input must be a string or an array of strings")
+			return
+		}
+		inputs = []string{single}
+	}
+
+	s.mu.Lock()
+	dim, latency := s.embeddingDim, s.latency
+	s.mu.Unlock()
+	if !wait(r, latency) {
+		return
+	}
+
+	data := make([]map[string]any, len(inputs))
+	var tokens int
+	for i, input := range inputs {
+		data[i] = map[string]any{"object": "embedding", "index": i, "embedding": Embedding(input, dim)}
+		tokens += countTokens(input)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"object": "list",
+		"model":  req.Model,
+		"data":   data,
+		"usage":  map[string]int{"prompt_tokens": tokens, "total_tokens": tokens},
+	})
+}
+
+// Embedding 计算 fake 服务为 input 返回的向量，便于测试断言
+func Embedding(input string, dim int) []float64 {
+	vec := make([]float64, dim)
+	var norm float64
+	for i := range vec {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", i, input)))
+		vec[i] = float64(binary.BigEndian.Uint32(sum[:4]))/math.MaxUint32*2 - 1
+		norm += vec[i] * vec[i]
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
+
+// wait 等待指定时间，请求被取消时返回 false
+func wait(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// writeError 以 OpenAI 兼容格式返回错误
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{"message": message, "type": errType},
+	})
+}
+
+// estimateUsage 按字符数粗略估算 token 用量
+func estimateUsage(req *schema.ChatRequest, content string) *schema.Usage {
+	var prompt int
+	for _, m := range req.Messages {
+		data, _ := json.Marshal(m.Content)
+		prompt += countTokens(string(data))
+	}
+	completion := countTokens(content)
+	return &schema.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
+
+// countTokens 约每 4 个字符计 1 个 token
+func countTokens(s string) int {
+	return (len([]rune(s)) + 3) / 4
+}
+
+// splitContent 将内容按字符切分为若干块
+func splitContent(content string, size int) []string {
+	runes := []rune(content)
+	var pieces []string
+	for len(runes) > 0 {
+		n := min(size, len(runes))
+		pieces = append(pieces, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return pieces
+}
+
+// JSONContent 将 v 序列化为回复内容，便于构造结构化的模型输出
+func JSONContent(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// ContentText 返回消息中的文本内容（多模态消息拼接所有文本片段）
+func ContentText(m schema.Message) string {
+	switch v := m.Content.(type) {
+	case string:
+		return v
+	case []any:
+		var parts []string
+		for _, p := range v {
+			if part, ok := p.(map[string]any); ok {
+				if text, ok := part["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
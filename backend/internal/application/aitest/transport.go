@@ -0,0 +1,179 @@
+package aitest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// 录制/回放模式
+const (
+	ModeRecord = "record" // 转发请求并保存响应
+	ModeReplay = "replay" // 只从录制文件返回响应，未录制的请求返回错误
+)
+
+// ErrNoRecording 回放时找不到对应的录制文件
+var ErrNoRecording = errors.New("no recording for request")
+
+// Recording 录制文件内容，一个请求对应一个文件
+type Recording struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest 录制的请求，只保存用于匹配和排查的信息，不保存请求头（避免泄露密钥）
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse 录制的响应
+type RecordedResponse struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"contentType,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"` // JSON 响应原样保存，便于阅读和修改
+	Text        string          `json:"text,omitempty"` // 非 JSON 响应（如流式 SSE）
+}
+
+// Transport 录制/回放 HTTP 请求的 http.RoundTripper，用作 AIService 的 HTTP 客户端传输层
+// 请求按 方法 + 路径 + 规范化后的 JSON 请求体 计算摘要，对应 <dir>/<摘要>.json；
+// 请求头（Authorization、traceparent 等）不参与匹配，录制文件中也不保存
+type Transport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+}
+
+// NewRecorder 创建录制传输层，next 为空时使用 http.DefaultTransport
+func NewRecorder(dir string, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{mode: ModeRecord, dir: dir, next: next}
+}
+
+// NewReplayer 创建回放传输层，不访问网络
+func NewReplayer(dir string) *Transport {
+	return &Transport{mode: ModeReplay, dir: dir}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	key := RequestKey(req.Method, req.URL.Path, body)
+	path := filepath.Join(t.dir, key+".json")
+
+	if t.mode == ModeReplay {
+		return t.replay(req, path)
+	}
+	return t.record(req, body, path)
+}
+
+// replay 从录制文件构造响应
+func (t *Transport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s (%s)", ErrNoRecording, req.Method, req.URL.Path, filepath.Base(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("parse recording %s: %w", filepath.Base(path), err)
+	}
+	respBody := []byte(rec.Response.Text)
+	if len(rec.Response.Body) > 0 {
+		respBody = rec.Response.Body
+	}
+
+	header := make(http.Header)
+	if rec.Response.ContentType != "" {
+		header.Set("Content-Type", rec.Response.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Response.Status, http.StatusText(rec.Response.Status)),
+		StatusCode:    rec.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// record 转发请求并保存响应
+func (t *Transport) record(req *http.Request, body []byte, path string) (*http.Response, error) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	rec := Recording{
+		Request: RecordedRequest{Method: req.Method, Path: req.URL.Path},
+		Response: RecordedResponse{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+		},
+	}
+	if json.Valid(body) {
+		rec.Request.Body = body
+	}
+	if json.Valid(respBody) {
+		rec.Response.Body = respBody
+	} else {
+		rec.Response.Text = string(respBody)
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, fmt.Errorf("save recording: %w", err)
+	}
+	return resp, nil
+}
+
+// RequestKey 计算请求的录制文件名：JSON 请求体先规范化（重新序列化），字段顺序和空白不影响匹配
+func RequestKey(method, path string, body []byte) string {
+	var v any
+	if json.Unmarshal(body, &v) == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
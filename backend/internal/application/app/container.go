@@ -0,0 +1,122 @@
+// Package app 应用依赖容器：根据配置构造共享的模型客户端、存储和业务服务，由 main 创建后注入路由
+package app
+
+import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/experiment"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/fetch"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/profile"
+	"ai-note-service/internal/application/prompt"
+	"ai-note-service/internal/application/service"
+	"ai-note-service/internal/application/tutor"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Container 应用依赖容器，所有服务共用同一个模型客户端、实验统计和结果快照
+type Container struct {
+	Config        global.Provider
+	AI            *service.AIService
+	Tracker       *experiment.Tracker
+	Results       *feedback.Results
+	Feedback      *feedback.Store
+	Profiles      *profile.Store
+	Tutoring      *tutor.Sessions
+	ImageAnalysis *service.ImageAnalysisService
+	Knowledge     *service.KnowledgeService
+
+	prompts atomic.Pointer[prompt.Registry] // 当前生效的模板注册表，热加载时整体替换
+}
+
+// Options 容器的可选依赖，测试中可替换为假实现
+type Options struct {
+	HTTPClient *http.Client      // 模型服务的 HTTP 客户端，为空时使用默认客户端
+	Prompts    *prompt.Registry  // 模板注册表，为空时从 prompt.dir 加载
+	Feedback   *feedback.Store   // 反馈存储，为空时打开 feedback.path
+	Profiles   *profile.Store    // 学习者画像存储，为空时打开 profile.path
+	Renderer   document.Renderer // PDF 页面渲染器，为空时按 pdf.render_command 调用外部命令
+}
+
+// New 根据配置创建容器，config 在每次请求时调用，传入 global.Load 时支持配置热加载
+func New(config global.Provider, opts Options) (*Container, error) {
+	cfg := config()
+
+	prompts := opts.Prompts
+	if prompts == nil {
+		var err error
+		if prompts, err = prompt.Load(cfg.Prompt.Dir); err != nil {
+			return nil, fmt.Errorf("load prompt templates: %w", err)
+		}
+	}
+
+	store := opts.Feedback
+	if store == nil {
+		var err error
+		if store, err = feedback.Open(cfg.Feedback.Path); err != nil {
+			return nil, fmt.Errorf("open feedback store: %w", err)
+		}
+	}
+
+	profiles := opts.Profiles
+	if profiles == nil {
+		var err error
+		if profiles, err = profile.Open(cfg.Profile.Path); err != nil {
+			return nil, fmt.Errorf("open profile store: %w", err)
+		}
+	}
+
+	c := &Container{
+		Config:   config,
+		AI:       service.NewAIService(config, opts.HTTPClient),
+		Tracker:  experiment.NewTracker(experiment.DefaultMaxResults),
+		Results:  feedback.NewResults(feedback.DefaultMaxResults),
+		Feedback: store,
+		Profiles: profiles,
+		Tutoring: tutor.NewSessions(tutor.DefaultMaxSessions),
+	}
+	c.prompts.Store(prompts)
+
+	renderer := opts.Renderer
+	if renderer == nil {
+		renderer = document.ConfiguredRenderer(config)
+	}
+
+	deps := service.Deps{
+		Config:   config,
+		AI:       c.AI,
+		Prompts:  c.Prompts,
+		Tracker:  c.Tracker,
+		Results:  c.Results,
+		Renderer: renderer,
+		Fetcher:  fetch.NewFetcher(config),
+		Pages:    service.NewConfiguredPageStore(config),
+		Tutoring: c.Tutoring,
+	}
+	c.ImageAnalysis = service.NewImageAnalysisService(deps)
+	c.Knowledge = service.NewKnowledgeService(deps)
+	return c, nil
+}
+
+// Prompts 返回当前模板注册表
+func (c *Container) Prompts() *prompt.Registry {
+	return c.prompts.Load()
+}
+
+// ReloadPrompts 从 dir 重新加载模板，加载失败时保留当前模板
+func (c *Container) ReloadPrompts(dir string) (*prompt.Registry, error) {
+	prompts, err := prompt.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	c.prompts.Store(prompts)
+	return prompts, nil
+}
+
+// Close 释放容器持有的资源
+func (c *Container) Close() error {
+	return errors.Join(c.Feedback.Close(), c.Profiles.Close())
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/prompt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewUsesInjectedDependencies(t *testing.T) {
+	store := feedback.NewMemoryStore()
+	c, err := New(global.Static(&global.AppConfig{}), Options{Feedback: store})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer c.Close()
+
+	if c.Feedback != store {
+		t.Error("injected feedback store was not used")
+	}
+	if c.AI == nil || c.ImageAnalysis == nil || c.Knowledge == nil {
+		t.Fatalf("services not constructed: %+v", c)
+	}
+	if !c.Prompts().HasVariant(prompt.NameAnalysis, "") {
+		t.Error("embedded templates not loaded")
+	}
+}
+
+func TestReloadPromptsKeepsCurrentOnError(t *testing.T) {
+	c, err := New(global.Static(&global.AppConfig{}), Options{Feedback: feedback.NewMemoryStore()})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	before := c.Prompts()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "analysis.zh-CN.tmpl"), []byte("{{define \"system\"}}{{.Missing"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReloadPrompts(dir); err == nil {
+		t.Fatal("expected error for invalid template")
+	}
+	if c.Prompts() != before {
+		t.Error("templates replaced after failed reload")
+	}
+}
+
+func TestNewOpensFeedbackFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feedback.jsonl")
+	c, err := New(global.Static(&global.AppConfig{Feedback: global.FeedbackConfig{Path: path}}), Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("feedback file not created: %v", err)
+	}
+}
@@ -1,10 +1,26 @@
 package common
 
 import (
-	"gopkg.in/yaml.v3"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/schema"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
+// EnvPrefix 配置环境变量前缀，如 AI_NOTE_AI_API_KEY 覆盖 ai.api_key
+const EnvPrefix = "AI_NOTE"
+
+// redactedValue 配置输出时密钥的占位内容
+const redactedValue = "[REDACTED]"
+
 // LoadConfig 加载配置文件
 func LoadConfig(path string, config interface{}) error {
 	data, err := os.ReadFile(path)
@@ -15,3 +31,374 @@ func LoadConfig(path string, config interface{}) error {
 	return yaml.Unmarshal(data, config)
 }
 
+// LoadAppConfig 加载应用配置：读取配置文件，应用环境变量覆盖，读取密钥文件并校验
+// path 为空时只使用环境变量；required 为 false 时允许配置文件不存在
+func LoadAppConfig(path string, required bool) (*global.AppConfig, error) {
+	cfg := &global.AppConfig{}
+
+	if path != "" {
+		if err := LoadConfig(path, cfg); err != nil {
+			if required || !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("read config file %s: %w", path, err)
+			}
+		}
+	}
+
+	if err := ApplyEnvOverrides(EnvPrefix, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := resolveSecretFiles(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// ApplyEnvOverrides 按 yaml 标签路径使用环境变量覆盖配置字段
+// 例如 prefix 为 AI_NOTE 时，server.port 对应 AI_NOTE_SERVER_PORT；
+// map[string]string 字段使用 "k1=v1,k2=v2" 格式，[]string 字段使用逗号分隔；
+// 结构体切片（如 experiments）使用 JSON 数组，字段名与 yaml 标签一致
+func ApplyEnvOverrides(prefix string, config interface{}) error {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config must be a pointer to struct")
+	}
+	return applyEnv(prefix, v.Elem())
+}
+
+// applyEnv 递归处理结构体字段
+func applyEnv(prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		envName := prefix + "_" + strings.ToUpper(name)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnv(envName, fv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+		if err := setFieldFromString(fv, raw); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", envName, err)
+		}
+	}
+	return nil
+}
+
+// setFieldFromString 将环境变量字符串解析并写入字段
+func setFieldFromString(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String || fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map type %s", fv.Type())
+		}
+		m := reflect.MakeMap(fv.Type())
+		for _, pair := range strings.Split(raw, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			k, val, found := strings.Cut(pair, "=")
+			if !found {
+				return fmt.Errorf("expected key=value, got %q", pair)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)), reflect.ValueOf(strings.TrimSpace(val)))
+		}
+		fv.Set(m)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Struct {
+			// JSON 是 YAML 的子集，按 yaml 解码以复用配置文件的字段标签
+			items := reflect.New(fv.Type())
+			if err := yaml.Unmarshal([]byte(raw), items.Interface()); err != nil {
+				return err
+			}
+			fv.Set(items.Elem())
+			return nil
+		}
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", fv.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// resolveSecretFiles 从文件中读取密钥（如 Docker/K8s secret 挂载）
+func resolveSecretFiles(cfg *global.AppConfig) error {
+	if cfg.AI.APIKeyFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(cfg.AI.APIKeyFile)
+	if err != nil {
+		return fmt.Errorf("read ai.api_key_file: %w", err)
+	}
+	cfg.AI.APIKey = strings.TrimSpace(string(data))
+	return nil
+}
+
+// ValidateConfig 校验配置，一次性返回所有错误
+func ValidateConfig(cfg *global.AppConfig) error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// server
+	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
+		add("server.port must be between 1 and 65535, got %d", cfg.Server.Port)
+	}
+	for _, field := range []struct {
+		name  string
+		value int
+	}{
+		{"server.read_header_timeout", cfg.Server.ReadHeaderTimeout},
+		{"server.read_timeout", cfg.Server.ReadTimeout},
+		{"server.write_timeout", cfg.Server.WriteTimeout},
+		{"server.idle_timeout", cfg.Server.IdleTimeout},
+		{"server.shutdown_timeout", cfg.Server.ShutdownTimeout},
+		{"server.max_header_bytes", cfg.Server.MaxHeaderBytes},
+	} {
+		if field.value < 0 {
+			add("%s must not be negative, got %d", field.name, field.value)
+		}
+	}
+
+	// ai
+	if u, err := url.Parse(cfg.AI.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("ai.base_url must be an absolute http(s) URL, got %q", cfg.AI.BaseURL)
+	}
+	if cfg.AI.Timeout <= 0 {
+		add("ai.timeout must be a positive number of seconds, got %d", cfg.AI.Timeout)
+	}
+	if strings.TrimSpace(cfg.AI.DefaultModel) == "" {
+		add("ai.default_model must not be empty")
+	}
+
+	// tracing
+	if cfg.Tracing.Enabled {
+		switch cfg.Tracing.Exporter {
+		case "", "stdout", "otlp":
+		default:
+			add("tracing.exporter must be stdout or otlp, got %q", cfg.Tracing.Exporter)
+		}
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be between 0 and 1, got %v", cfg.Tracing.SampleRatio)
+	}
+
+	// log
+	switch strings.ToLower(cfg.Log.Level) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		add("log.level must be one of debug, info, warn, error, got %q", cfg.Log.Level)
+	}
+	switch strings.ToLower(cfg.Log.Format) {
+	case "", "json", "text":
+	default:
+		add("log.format must be json or text, got %q", cfg.Log.Format)
+	}
+
+	// i18n
+	if cfg.I18n.DefaultLanguage != "" {
+		if _, ok := i18n.Match(cfg.I18n.DefaultLanguage); !ok {
+			add("i18n.default_language must be one of %v, got %q", i18n.Supported(), cfg.I18n.DefaultLanguage)
+		}
+	}
+
+	// prompt
+	if cfg.Prompt.Dir != "" {
+		if info, err := os.Stat(cfg.Prompt.Dir); err != nil || !info.IsDir() {
+			add("prompt.dir must be an existing directory, got %q", cfg.Prompt.Dir)
+		}
+	}
+
+	// cache
+	if cfg.Cache.Enabled && cfg.Cache.Size <= 0 {
+		add("cache.size must be positive when cache is enabled, got %d", cfg.Cache.Size)
+	}
+	if cfg.Cache.TTL < 0 {
+		add("cache.ttl must not be negative, got %d", cfg.Cache.TTL)
+	}
+
+	// analysis
+	if cfg.Analysis.MaxImages < 0 {
+		add("analysis.max_images must not be negative, got %d", cfg.Analysis.MaxImages)
+	}
+	if cfg.Analysis.ImagesPerRequest < 0 {
+		add("analysis.images_per_request must not be negative, got %d", cfg.Analysis.ImagesPerRequest)
+	}
+	if cfg.Analysis.PageStoreMB < 0 {
+		add("analysis.page_store_mb must not be negative, got %d", cfg.Analysis.PageStoreMB)
+	}
+	switch cfg.Analysis.Depth {
+	case "", schema.DepthQuick, schema.DepthStandard, schema.DepthDeep:
+	default:
+		add("analysis.depth must be one of quick, standard, deep, got %q", cfg.Analysis.Depth)
+	}
+	for _, count := range []struct {
+		name string
+		n    int
+	}{
+		{"key_points", cfg.Analysis.KeyPoints},
+		{"prerequisites", cfg.Analysis.Prerequisites},
+		{"postrequisites", cfg.Analysis.Postrequisites},
+	} {
+		if count.n < 0 || count.n > 10 {
+			add("analysis.%s must be between 0 and 10, got %d", count.name, count.n)
+		}
+	}
+	if cfg.Analysis.FunExamples < 0 || cfg.Analysis.FunExamples > 3 {
+		add("analysis.fun_examples must be between 0 and 3, got %d", cfg.Analysis.FunExamples)
+	}
+
+	// pdf
+	if cfg.PDF.MaxPages < 0 {
+		add("pdf.max_pages must not be negative, got %d", cfg.PDF.MaxPages)
+	}
+	if cfg.PDF.RenderDPI < 0 {
+		add("pdf.render_dpi must not be negative, got %d", cfg.PDF.RenderDPI)
+	}
+
+	// fetch
+	if cfg.Fetch.Timeout < 0 {
+		add("fetch.timeout must not be negative, got %d", cfg.Fetch.Timeout)
+	}
+	if cfg.Fetch.MaxSizeMB < 0 {
+		add("fetch.max_size_mb must not be negative, got %d", cfg.Fetch.MaxSizeMB)
+	}
+
+	// experiments
+	errs = append(errs, validateExperiments(cfg.Experiments)...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+}
+
+// validateExperiments 校验实验配置：名称唯一，同一 target 只能有一个启用的实验，变体名称唯一且权重之和为正
+func validateExperiments(experiments []global.ExperimentConfig) []error {
+	var errs []error
+	names := make(map[string]bool)
+	enabledTargets := make(map[string]string)
+	for i, exp := range experiments {
+		prefix := fmt.Sprintf("experiments[%d]", i)
+		if exp.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name must not be empty", prefix))
+		} else if names[exp.Name] {
+			errs = append(errs, fmt.Errorf("%s.name %q is duplicated", prefix, exp.Name))
+		}
+		names[exp.Name] = true
+
+		switch exp.Target {
+		case "analysis", "dialogue":
+		default:
+			errs = append(errs, fmt.Errorf("%s.target must be analysis or dialogue, got %q", prefix, exp.Target))
+		}
+		if exp.Enabled {
+			if other, ok := enabledTargets[exp.Target]; ok {
+				errs = append(errs, fmt.Errorf("%s: only one enabled experiment per target, %q already targets %s", prefix, other, exp.Target))
+			}
+			enabledTargets[exp.Target] = exp.Name
+		}
+
+		if len(exp.Variants) == 0 {
+			errs = append(errs, fmt.Errorf("%s.variants must not be empty", prefix))
+		}
+		variants := make(map[string]bool)
+		total := 0
+		for j, v := range exp.Variants {
+			if v.Name == "" {
+				errs = append(errs, fmt.Errorf("%s.variants[%d].name must not be empty", prefix, j))
+			} else if variants[v.Name] {
+				errs = append(errs, fmt.Errorf("%s.variants[%d].name %q is duplicated", prefix, j, v.Name))
+			}
+			variants[v.Name] = true
+			if v.Weight < 0 {
+				errs = append(errs, fmt.Errorf("%s.variants[%d].weight must not be negative, got %d", prefix, j, v.Weight))
+			}
+			total += v.Weight
+		}
+		if len(exp.Variants) > 0 && total <= 0 {
+			errs = append(errs, fmt.Errorf("%s: sum of variant weights must be positive", prefix))
+		}
+	}
+	return errs
+}
+
+// RedactedConfig 返回脱敏后的配置（按 yaml 键名），用于启动时输出
+func RedactedConfig(cfg *global.AppConfig) map[string]interface{} {
+	redacted := *cfg
+	if redacted.AI.APIKey != "" {
+		redacted.AI.APIKey = redactedValue
+	}
+	if redacted.Admin.Token != "" {
+		redacted.Admin.Token = redactedValue
+	}
+	if len(cfg.Tracing.Headers) > 0 {
+		redacted.Tracing.Headers = make(map[string]string, len(cfg.Tracing.Headers))
+		for k := range cfg.Tracing.Headers {
+			redacted.Tracing.Headers[k] = redactedValue
+		}
+	}
+
+	data, err := yaml.Marshal(redacted)
+	if err != nil {
+		return nil
+	}
+	var out map[string]interface{}
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return out
+}
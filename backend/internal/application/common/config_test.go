@@ -0,0 +1,198 @@
+package common
+
+import (
+	"ai-note-service/internal/application/global"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func validConfig() *global.AppConfig {
+	return &global.AppConfig{
+		Server: global.ServerConfig{Port: 8080, Host: "0.0.0.0"},
+		AI: global.AIConfig{
+			BaseURL:      "http://ai.example.com/v1",
+			DefaultModel: "test-model",
+			Timeout:      30,
+		},
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	t.Setenv("AI_NOTE_SERVER_PORT", "9090")
+	t.Setenv("AI_NOTE_AI_API_KEY", "env-key")
+	t.Setenv("AI_NOTE_TRACING_ENABLED", "true")
+	t.Setenv("AI_NOTE_TRACING_SAMPLE_RATIO", "0.5")
+	t.Setenv("AI_NOTE_TRACING_HEADERS", "x-token=abc, x-team=notes")
+
+	cfg := validConfig()
+	if err := ApplyEnvOverrides(EnvPrefix, cfg); err != nil {
+		t.Fatalf("ApplyEnvOverrides failed: %v", err)
+	}
+
+	if cfg.Server.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", cfg.Server.Port)
+	}
+	if cfg.AI.APIKey != "env-key" {
+		t.Errorf("Expected api key env-key, got %s", cfg.AI.APIKey)
+	}
+	if !cfg.Tracing.Enabled || cfg.Tracing.SampleRatio != 0.5 {
+		t.Errorf("Expected tracing enabled with ratio 0.5, got %+v", cfg.Tracing)
+	}
+	if cfg.Tracing.Headers["x-team"] != "notes" {
+		t.Errorf("Expected header x-team=notes, got %v", cfg.Tracing.Headers)
+	}
+}
+
+func TestApplyEnvOverridesExperiments(t *testing.T) {
+	t.Setenv("AI_NOTE_EXPERIMENTS", `[{"name":"exp","target":"analysis","enabled":true,
+		"variants":[{"name":"a","weight":1},{"name":"b","weight":3,"prompt_variant":"v2","model":"m2"}]}]`)
+
+	cfg := validConfig()
+	if err := ApplyEnvOverrides(EnvPrefix, cfg); err != nil {
+		t.Fatalf("ApplyEnvOverrides failed: %v", err)
+	}
+	if len(cfg.Experiments) != 1 || !cfg.Experiments[0].Enabled || len(cfg.Experiments[0].Variants) != 2 {
+		t.Fatalf("Expected one enabled experiment with two variants, got %+v", cfg.Experiments)
+	}
+	if v := cfg.Experiments[0].Variants[1]; v.Weight != 3 || v.PromptVariant != "v2" || v.Model != "m2" {
+		t.Errorf("Expected variant b with prompt_variant v2 and model m2, got %+v", v)
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Experiments from env should validate: %v", err)
+	}
+
+	t.Setenv("AI_NOTE_EXPERIMENTS", "not json")
+	if err := ApplyEnvOverrides(EnvPrefix, validConfig()); err == nil || !strings.Contains(err.Error(), "AI_NOTE_EXPERIMENTS") {
+		t.Errorf("Expected error naming AI_NOTE_EXPERIMENTS, got %v", err)
+	}
+}
+
+func TestApplyEnvOverridesInvalidValue(t *testing.T) {
+	t.Setenv("AI_NOTE_SERVER_PORT", "not-a-number")
+
+	err := ApplyEnvOverrides(EnvPrefix, validConfig())
+	if err == nil || !strings.Contains(err.Error(), "AI_NOTE_SERVER_PORT") {
+		t.Fatalf("Expected error naming AI_NOTE_SERVER_PORT, got %v", err)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	if err := ValidateConfig(validConfig()); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+
+	cfg := validConfig()
+	cfg.Server.Port = 70000
+	cfg.AI.BaseURL = "not a url"
+	cfg.AI.Timeout = 0
+	cfg.AI.DefaultModel = " "
+	cfg.Analysis.Depth = "thorough"
+	cfg.Analysis.Prerequisites = 11
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, field := range []string{"server.port", "ai.base_url", "ai.timeout", "ai.default_model", "analysis.depth", "analysis.prerequisites"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected error to mention %s, got %v", field, err)
+		}
+	}
+}
+
+func TestValidateExperiments(t *testing.T) {
+	cfg := validConfig()
+	cfg.Experiments = []global.ExperimentConfig{
+		{Name: "a", Target: "analysis", Enabled: true, Variants: []global.VariantConfig{{Name: "control", Weight: 1}}},
+		{Name: "a", Target: "analysis", Enabled: true, Variants: []global.VariantConfig{{Name: "x", Weight: 0}, {Name: "x", Weight: -1}}},
+		{Name: "c", Target: "summary"},
+	}
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, msg := range []string{
+		`experiments[1].name "a" is duplicated`,
+		"only one enabled experiment per target",
+		`experiments[1].variants[1].name "x" is duplicated`,
+		"experiments[1].variants[1].weight must not be negative",
+		"experiments[1]: sum of variant weights must be positive",
+		"experiments[2].target must be analysis or dialogue",
+		"experiments[2].variants must not be empty",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("Expected error to mention %q, got %v", msg, err)
+		}
+	}
+}
+
+func TestLoadAppConfigAPIKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "api_key")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+	content := "server:\n  port: 8080\nai:\n  base_url: \"http://ai.example.com/v1\"\n  api_key_file: \"" + keyFile + "\"\n  default_model: \"m\"\n  timeout: 10\n"
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadAppConfig(configFile, true)
+	if err != nil {
+		t.Fatalf("LoadAppConfig failed: %v", err)
+	}
+	if cfg.AI.APIKey != "file-key" {
+		t.Errorf("Expected api key from file, got %q", cfg.AI.APIKey)
+	}
+
+	redacted := RedactedConfig(cfg)
+	ai, _ := redacted["ai"].(map[string]interface{})
+	if ai["api_key"] != redactedValue {
+		t.Errorf("Expected redacted api key, got %v", ai["api_key"])
+	}
+}
+
+func TestConfigReloaderRejectsInvalidConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(model string, timeout int) {
+		content := "server:\n  port: 8080\nai:\n  base_url: \"http://ai.example.com/v1\"\n  default_model: \"" + model + "\"\n  timeout: " + strconv.Itoa(timeout) + "\n"
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("model-a", 30)
+	reloader := NewConfigReloader(configFile, true)
+	var hookCalls int
+	reloader.OnReload(func(_, _ *global.AppConfig) { hookCalls++ })
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("initial reload failed: %v", err)
+	}
+
+	write("model-b", 0)
+	if err := reloader.Reload(); err == nil {
+		t.Fatal("Expected invalid reload to fail")
+	}
+	if got := global.Load().AI.DefaultModel; got != "model-a" {
+		t.Errorf("Expected previous config to be kept, got model %s", got)
+	}
+
+	write("model-b", 10)
+	if !reloader.changed() {
+		t.Error("Expected file change to be detected")
+	}
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("valid reload failed: %v", err)
+	}
+	if got := global.Load().AI.DefaultModel; got != "model-b" {
+		t.Errorf("Expected reloaded model model-b, got %s", got)
+	}
+	if hookCalls != 2 {
+		t.Errorf("Expected hook to run for 2 successful reloads, got %d", hookCalls)
+	}
+}
@@ -0,0 +1,152 @@
+package common
+
+import (
+	"ai-note-service/internal/application/global"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultReloadInterval 配置文件检查间隔默认值
+const defaultReloadInterval = 5 * time.Second
+
+// ReloadHook 配置替换后的回调，用于刷新不直接读取快照的组件（如日志级别）
+type ReloadHook func(oldCfg, newCfg *global.AppConfig)
+
+// ConfigReloader 配置热加载器
+// 重新加载时完整执行 LoadAppConfig（文件、环境变量、密钥文件、校验），
+// 失败时保留旧配置并记录错误，成功后原子替换全局快照
+type ConfigReloader struct {
+	path     string
+	required bool
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	hooks   []ReloadHook
+}
+
+// NewConfigReloader 创建配置热加载器
+func NewConfigReloader(path string, required bool) *ConfigReloader {
+	r := &ConfigReloader{path: path, required: required}
+	r.modTime, r.size = r.stat()
+	return r
+}
+
+// OnReload 注册配置替换后的回调
+func (r *ConfigReloader) OnReload(hook ReloadHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook)
+}
+
+// Reload 重新加载配置
+func (r *ConfigReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modTime, r.size = r.stat()
+
+	newCfg, err := LoadAppConfig(r.path, r.required)
+	if err != nil {
+		slog.Error("config reload rejected, keeping previous config", "path", r.path, "error", err)
+		return err
+	}
+
+	oldCfg := global.Load()
+	if oldCfg != nil {
+		warnRestartRequired(oldCfg, newCfg)
+	}
+
+	global.Store(newCfg)
+	for _, hook := range r.hooks {
+		hook(oldCfg, newCfg)
+	}
+
+	slog.Info("config reloaded", "path", r.path, "config", RedactedConfig(newCfg))
+	return nil
+}
+
+// WatchSignal 收到 SIGHUP 时重新加载配置，ctx 取消后退出
+func (r *ConfigReloader) WatchSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			slog.Info("SIGHUP received, reloading config")
+			_ = r.Reload()
+		}
+	}
+}
+
+// WatchFile 定期检查配置文件的修改时间和大小，变化时重新加载，ctx 取消后退出
+func (r *ConfigReloader) WatchFile(ctx context.Context, interval time.Duration) {
+	if r.path == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if r.changed() {
+				slog.Info("config file changed, reloading config", "path", r.path)
+				_ = r.Reload()
+			}
+		}
+	}
+}
+
+// changed 判断配置文件是否发生变化
+func (r *ConfigReloader) changed() bool {
+	modTime, size := r.stat()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !modTime.Equal(r.modTime) || size != r.size
+}
+
+// stat 读取配置文件的修改时间和大小，文件不存在时返回零值
+func (r *ConfigReloader) stat() (time.Time, int64) {
+	if r.path == "" {
+		return time.Time{}, 0
+	}
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// warnRestartRequired 对只在启动时生效的配置变更给出提示
+func warnRestartRequired(oldCfg, newCfg *global.AppConfig) {
+	if !reflect.DeepEqual(oldCfg.Server, newCfg.Server) {
+		slog.Warn("server config changed, restart required for it to take effect")
+	}
+	if !reflect.DeepEqual(oldCfg.Tracing, newCfg.Tracing) {
+		slog.Warn("tracing config changed, restart required for it to take effect")
+	}
+	if oldCfg.Log.Format != newCfg.Log.Format {
+		slog.Warn("log.format changed, restart required for it to take effect")
+	}
+	if oldCfg.Reload != newCfg.Reload {
+		slog.Warn("reload config changed, restart required for it to take effect")
+	}
+}
@@ -2,10 +2,23 @@ package common
 
 import (
 	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/logger"
 	"ai-note-service/internal/application/schema"
+	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	// MIMEProblemJSON RFC 7807 错误响应类型
+	MIMEProblemJSON = "application/problem+json"
+
+	// problemTypePrefix 错误类型 URI 前缀，后接小写的错误标识
+	problemTypePrefix = "urn:ai-note-service:error:"
 )
 
 // SuccessResponse 成功响应
@@ -19,22 +32,98 @@ func SuccessResponse(c *gin.Context, data interface{}) {
 
 // ErrorResponse 错误响应
 func ErrorResponse(c *gin.Context, err *errcode.ErrCode, detail string) {
-	message := err.Message
-	if detail != "" {
-		message = message + ": " + detail
+	writeError(c, &errcode.Error{Code: err, Detail: detail})
+}
+
+// LocalizedErrorResponse 错误响应，detail 按请求语言从文案目录中取
+func LocalizedErrorResponse(c *gin.Context, err *errcode.ErrCode, key string, args ...interface{}) {
+	writeError(c, &errcode.Error{Code: err, DetailKey: key, DetailArgs: args})
+}
+
+// HandleError 统一处理 service 层返回的错误：按错误码映射 HTTP 状态码，
+// 只向客户端返回错误码说明和 detail，底层错误只写入日志
+func HandleError(c *gin.Context, err error) {
+	e := errcode.From(err)
+
+	ctx := c.Request.Context()
+	if e.Code.HTTPStatus >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "request failed", "reason", e.Code.Reason, "error", err)
+	} else {
+		slog.WarnContext(ctx, "request failed", "reason", e.Code.Reason, "error", err)
 	}
-	c.JSON(http.StatusOK, schema.Response{
-		Code:    err.Code,
-		Message: message,
+
+	writeError(c, e)
+}
+
+// ValidationErrorResponse 参数绑定/校验失败响应，返回字段级错误
+func ValidationErrorResponse(c *gin.Context, err error) {
+	writeError(c, &errcode.Error{
+		Code:   errcode.InvalidParams,
+		Fields: FieldErrors(err, i18n.FromContext(c.Request.Context())),
 	})
 }
 
-// InternalErrorResponse 内部错误响应
+// InternalErrorResponse 内部错误响应，错误内容只写入日志，不返回给客户端
 func InternalErrorResponse(c *gin.Context, err error) {
-	c.JSON(http.StatusOK, schema.Response{
-		Code:    errcode.InternalError.Code,
-		Message: errcode.InternalError.Message,
-		Data:    err.Error(),
+	slog.ErrorContext(c.Request.Context(), "internal error", "error", err)
+	writeError(c, &errcode.Error{Code: errcode.InternalError})
+}
+
+// writeError 根据 Accept 头输出统一响应结构或 RFC 7807 problem+json
+// 错误码说明和 detail 按请求语言本地化
+func writeError(c *gin.Context, e *errcode.Error) {
+	status := e.Code.HTTPStatus
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	ctx := c.Request.Context()
+	requestID := logger.RequestIDFrom(ctx)
+	lang := i18n.FromContext(ctx)
+
+	title := e.Code.Message
+	if key := "error." + e.Code.Reason; i18n.Has(key) {
+		title = i18n.T(lang, key)
+	}
+	detail := e.Detail
+	if e.DetailKey != "" {
+		detail = i18n.T(lang, e.DetailKey, e.DetailArgs...)
+	}
+	c.Header("Content-Language", string(lang))
+
+	if wantsProblemJSON(c) {
+		c.Header("Content-Type", MIMEProblemJSON)
+		c.JSON(status, schema.ProblemDetails{
+			Type:      problemTypePrefix + strings.ToLower(e.Code.Reason),
+			Title:     title,
+			Status:    status,
+			Detail:    detail,
+			Instance:  c.Request.URL.Path,
+			Code:      e.Code.Code,
+			Reason:    e.Code.Reason,
+			Retryable: e.Code.Retryable,
+			Errors:    e.Fields,
+			RequestID: requestID,
+		})
+		return
+	}
+
+	message := title
+	if detail != "" {
+		message = message + ": " + detail
+	}
+	c.JSON(status, schema.Response{
+		Code:    e.Code.Code,
+		Message: message,
+		Error: &schema.ErrorInfo{
+			Reason:    e.Code.Reason,
+			Retryable: e.Code.Retryable,
+			Fields:    e.Fields,
+		},
+		RequestID: requestID,
 	})
 }
 
+// wantsProblemJSON 客户端是否通过 Accept 头要求 application/problem+json
+func wantsProblemJSON(c *gin.Context) bool {
+	return c.NegotiateFormat(binding.MIMEJSON, MIMEProblemJSON) == MIMEProblemJSON
+}
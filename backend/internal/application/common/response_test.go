@@ -0,0 +1,82 @@
+package common
+
+import (
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/schema"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestContext(accept string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/analyze/image", nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	return c, w
+}
+
+func TestHandleErrorMapsStatusAndHidesCause(t *testing.T) {
+	c, w := newTestContext("")
+	cause := errors.New("dial tcp 10.0.0.1:443: secret upstream detail")
+	HandleError(c, fmt.Errorf("AI分析失败: %w", errcode.Wrap(errcode.AIServiceTimeout, cause, "")))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret upstream detail") {
+		t.Errorf("Response leaked underlying error: %s", w.Body.String())
+	}
+
+	var resp schema.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if resp.Code != errcode.AIServiceTimeout.Code || resp.Error == nil || resp.Error.Reason != "AI_SERVICE_TIMEOUT" || !resp.Error.Retryable {
+		t.Errorf("Unexpected error body: %+v", resp)
+	}
+}
+
+func TestHandleErrorUnknownErrorIsInternal(t *testing.T) {
+	c, w := newTestContext("")
+	HandleError(c, errors.New("boom"))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "boom") {
+		t.Errorf("Response leaked underlying error: %s", w.Body.String())
+	}
+}
+
+func TestProblemJSONNegotiation(t *testing.T) {
+	c, w := newTestContext("application/problem+json")
+	ValidationErrorResponse(c, errcode.NewValidationError(errcode.FieldError{Field: "image", Message: "is required"}))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, MIMEProblemJSON) {
+		t.Errorf("Expected problem+json content type, got %s", ct)
+	}
+
+	var problem schema.ProblemDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if problem.Status != http.StatusBadRequest || problem.Reason != "INVALID_PARAMS" || problem.Instance != "/api/analyze/image" {
+		t.Errorf("Unexpected problem body: %+v", problem)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "image" {
+		t.Errorf("Expected field error for image, got %+v", problem.Errors)
+	}
+}
@@ -0,0 +1,114 @@
+package common
+
+import (
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/i18n"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// 校验错误中使用 json 字段名，而不是 Go 结构体字段名
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// FieldErrors 将参数绑定错误转换为字段级错误，说明文案使用 lang 指定的语言
+func FieldErrors(err error, lang i18n.Language) []errcode.FieldError {
+	var codeErr *errcode.Error
+	if errors.As(err, &codeErr) && len(codeErr.Fields) > 0 {
+		return codeErr.Fields
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]errcode.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, errcode.FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Message: validationMessage(fe, lang),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []errcode.FieldError{{
+			Field:   typeErr.Field,
+			Message: i18n.T(lang, "validation.type", jsonKind(typeErr.Type)),
+		}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return []errcode.FieldError{{
+			Field:   "body",
+			Message: i18n.T(lang, "validation.malformed_json", syntaxErr.Offset),
+		}}
+	}
+
+	return []errcode.FieldError{{Field: "body", Message: i18n.T(lang, "validation.invalid_body")}}
+}
+
+// fieldPath 去掉命名空间中的顶层结构体名，如 ChatRequest.messages[0].role -> messages[0].role
+func fieldPath(namespace string) string {
+	if _, rest, found := strings.Cut(namespace, "."); found {
+		return rest
+	}
+	return namespace
+}
+
+// validationMessage 生成字段校验失败的说明
+func validationMessage(fe validator.FieldError, lang i18n.Language) string {
+	switch fe.Tag() {
+	case "required":
+		return i18n.T(lang, "validation.required")
+	case "min", "max":
+		// 数值字段的 min/max 表示取值范围，而不是长度或数量
+		if jsonKind(fe.Type()) == "number" {
+			tag := map[string]string{"min": "gte", "max": "lte"}[fe.Tag()]
+			return i18n.T(lang, "validation."+tag, fe.Param())
+		}
+		return i18n.T(lang, "validation."+fe.Tag(), fe.Param())
+	case "oneof", "gte", "lte":
+		return i18n.T(lang, "validation."+fe.Tag(), fe.Param())
+	default:
+		return i18n.T(lang, "validation.rule", fe.Tag())
+	}
+}
+
+// jsonKind 返回 Go 类型对应的 JSON 类型名称
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "value"
+	}
+}
@@ -7,8 +7,4 @@ const (
 	RoleUser = "user"
 	// RoleAssistant 助手角色
 	RoleAssistant = "assistant"
-
-	// DefaultSystemPrompt 默认系统提示词
-	DefaultSystemPrompt = "You are a helpful assistant."
 )
-
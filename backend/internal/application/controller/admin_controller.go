@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"ai-note-service/internal/application/common"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/experiment"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/prompt"
+	"ai-note-service/internal/application/schema"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminController 管理接口控制器
+type AdminController struct {
+	config   global.Provider
+	prompts  func() *prompt.Registry
+	tracker  *experiment.Tracker
+	feedback *feedback.Store
+}
+
+// NewAdminController 创建管理接口控制器
+func NewAdminController(config global.Provider, prompts func() *prompt.Registry, tracker *experiment.Tracker, store *feedback.Store) *AdminController {
+	return &AdminController{
+		config:   config,
+		prompts:  prompts,
+		tracker:  tracker,
+		feedback: store,
+	}
+}
+
+// ListPrompts 列出提示词模板
+// @Summary 提示词模板列表
+// @Description 列出当前生效的提示词模板及其版本
+// @Tags admin
+// @Produce json
+// @Success 200 {object} schema.Response{data=[]schema.PromptTemplateInfo}
+// @Security AdminToken
+// @Router /api/admin/prompts [get]
+func (ctrl *AdminController) ListPrompts(c *gin.Context) {
+	templates := ctrl.prompts().List()
+	list := make([]schema.PromptTemplateInfo, 0, len(templates))
+	for _, t := range templates {
+		list = append(list, schema.PromptTemplateInfo{
+			Name:        t.Name,
+			Variant:     t.Variant,
+			Language:    string(t.Language),
+			Version:     t.Version,
+			VersionID:   t.VersionID(),
+			Description: t.Description,
+			Sections:    t.Sections,
+			Variables:   prompt.VarNames(t.Name),
+			Source:      t.Source,
+		})
+	}
+
+	common.SuccessResponse(c, list)
+}
+
+// PreviewPrompt 预览渲染后的提示词
+// @Summary 提示词预览
+// @Description 使用给定变量渲染提示词模板，不调用 AI 服务
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "模板名称"
+// @Param request body schema.PromptPreviewRequest true "预览请求"
+// @Success 200 {object} schema.Response{data=schema.PromptPreviewResponse}
+// @Security AdminToken
+// @Router /api/admin/prompts/{name}/preview [post]
+func (ctrl *AdminController) PreviewPrompt(c *gin.Context) {
+	name := c.Param("name")
+	vars, err := prompt.NewVars(name)
+	if err != nil {
+		common.LocalizedErrorResponse(c, errcode.NotFound, "prompt.unknown_template", name)
+		return
+	}
+
+	var req schema.PromptPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+
+	lang := i18n.FromContext(c.Request.Context())
+	if req.Language != "" {
+		l, ok := i18n.Match(req.Language)
+		if !ok {
+			common.HandleError(c, errcode.NewValidationError(errcode.FieldError{
+				Field:   "language",
+				Message: i18n.T(lang, "validation.oneof", "zh-CN en"),
+			}))
+			return
+		}
+		lang = l
+	}
+
+	// 变量按模板声明的类型解码，拒绝未知字段
+	if len(req.Variables) > 0 && string(req.Variables) != "null" {
+		decoder := json.NewDecoder(bytes.NewReader(req.Variables))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(vars); err != nil {
+			common.LocalizedErrorResponse(c, errcode.InvalidParams, "prompt.invalid_variables", err.Error())
+			return
+		}
+	}
+
+	p, err := ctrl.prompts().RenderVariant(name, req.Variant, lang, reflect.ValueOf(vars).Elem().Interface())
+	if err != nil {
+		common.HandleError(c, errcode.WrapLocalized(errcode.InternalError, err, "prompt.render_failed", name))
+		return
+	}
+
+	common.SuccessResponse(c, schema.PromptPreviewResponse{
+		VersionID: p.VersionID,
+		System:    p.System,
+		User:      p.User,
+	})
+}
+
+// ExperimentReport 实验报告
+// @Summary 实验报告
+// @Description 汇总各实验变体的请求数、解析成功率、延迟、token 用量和用户评分
+// @Tags admin
+// @Produce json
+// @Success 200 {object} schema.Response{data=[]schema.ExperimentReport}
+// @Security AdminToken
+// @Router /api/admin/experiments/report [get]
+func (ctrl *AdminController) ExperimentReport(c *gin.Context) {
+	common.SuccessResponse(c, ctrl.tracker.Report(ctrl.config().Experiments))
+}
+
+// FeedbackSummary 反馈汇总
+// @Summary 反馈汇总
+// @Description 按对象类型、提示词版本和实验变体汇总点赞、点踩数量和原因分布
+// @Tags admin
+// @Produce json
+// @Success 200 {object} schema.Response{data=[]schema.FeedbackSummary}
+// @Security AdminToken
+// @Router /api/admin/feedback/summary [get]
+func (ctrl *AdminController) FeedbackSummary(c *gin.Context) {
+	common.SuccessResponse(c, ctrl.feedback.Summary())
+}
+
+// ExportFeedback 导出反馈
+// @Summary 导出反馈
+// @Description 以 JSONL 格式导出反馈记录及被评价结果的上下文，用于离线评估
+// @Tags admin
+// @Produce application/x-ndjson
+// @Param kind query string false "对象类型" Enums(analysis, message)
+// @Param rating query string false "评价" Enums(up, down)
+// @Param since query string false "起始时间（RFC 3339）"
+// @Success 200 {string} string "JSONL"
+// @Security AdminToken
+// @Router /api/admin/feedback/export [get]
+func (ctrl *AdminController) ExportFeedback(c *gin.Context) {
+	var query schema.FeedbackExportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+
+	filter := feedback.Filter{Kind: query.Kind, Rating: query.Rating}
+	if query.Since != "" {
+		since, err := time.Parse(time.RFC3339, query.Since)
+		if err != nil {
+			lang := i18n.FromContext(c.Request.Context())
+			common.HandleError(c, errcode.NewValidationError(errcode.FieldError{
+				Field:   "since",
+				Message: i18n.T(lang, "feedback.invalid_since"),
+			}))
+			return
+		}
+		filter.Since = since
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="feedback.jsonl"`)
+	c.Status(http.StatusOK)
+	if err := ctrl.feedback.Export(c.Writer, filter); err != nil {
+		// 响应已开始写入，只能记录日志
+		slog.ErrorContext(c.Request.Context(), "feedback export failed", "error", err)
+	}
+}
@@ -3,6 +3,8 @@ package controller
 import (
 	"ai-note-service/internal/application/common"
 	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/prompt"
 	"ai-note-service/internal/application/schema"
 	"ai-note-service/internal/application/service"
 
@@ -12,12 +14,14 @@ import (
 // ChatController 聊天控制器
 type ChatController struct {
 	aiService *service.AIService
+	prompts   func() *prompt.Registry
 }
 
 // NewChatController 创建聊天控制器
-func NewChatController() *ChatController {
+func NewChatController(aiService *service.AIService, prompts func() *prompt.Registry) *ChatController {
 	return &ChatController{
-		aiService: service.NewAIService(),
+		aiService: aiService,
+		prompts:   prompts,
 	}
 }
 
@@ -35,14 +39,14 @@ func (ctrl *ChatController) Chat(c *gin.Context) {
 
 	// 绑定请求参数
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.ErrorResponse(c, errcode.InvalidParams, err.Error())
+		common.ValidationErrorResponse(c, err)
 		return
 	}
 
 	// 调用AI服务
-	resp, err := ctrl.aiService.Chat(&req)
+	resp, err := ctrl.aiService.Chat(c.Request.Context(), &req)
 	if err != nil {
-		common.ErrorResponse(c, errcode.AIServiceError, err.Error())
+		common.HandleError(c, err)
 		return
 	}
 
@@ -56,18 +60,22 @@ func (ctrl *ChatController) Chat(c *gin.Context) {
 // @Tags chat
 // @Accept json
 // @Produce json
-// @Param request body map[string]string true "简化聊天请求"
+// @Param request body schema.SimpleChatRequest true "简化聊天请求"
 // @Success 200 {object} schema.Response{data=schema.ChatResponse}
 // @Router /api/v1/chat/simple [post]
 func (ctrl *ChatController) SimpleChat(c *gin.Context) {
-	var simpleReq struct {
-		Message string `json:"message" binding:"required"`
-		Model   string `json:"model"`
-	}
+	var simpleReq schema.SimpleChatRequest
 
 	// 绑定请求参数
 	if err := c.ShouldBindJSON(&simpleReq); err != nil {
-		common.ErrorResponse(c, errcode.InvalidParams, err.Error())
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+
+	// 渲染系统提示词模板
+	p, err := ctrl.prompts().Render(prompt.NameChat, i18n.FromContext(c.Request.Context()), prompt.ChatVars{})
+	if err != nil {
+		common.HandleError(c, errcode.Wrap(errcode.InternalError, err, ""))
 		return
 	}
 
@@ -75,19 +83,18 @@ func (ctrl *ChatController) SimpleChat(c *gin.Context) {
 	req := schema.ChatRequest{
 		Model: simpleReq.Model,
 		Messages: []schema.Message{
-			schema.NewTextMessage("system", "You are a helpful assistant."),
+			schema.NewTextMessage("system", p.System),
 			schema.NewTextMessage("user", simpleReq.Message),
 		},
 	}
 
 	// 调用AI服务
-	resp, err := ctrl.aiService.Chat(&req)
+	resp, err := ctrl.aiService.Chat(c.Request.Context(), &req)
 	if err != nil {
-		common.ErrorResponse(c, errcode.AIServiceError, err.Error())
+		common.HandleError(c, err)
 		return
 	}
 
 	// 返回成功响应
 	common.SuccessResponse(c, resp)
 }
-
@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"ai-note-service/internal/application/openapi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DocsController 接口文档控制器
+type DocsController struct{}
+
+// NewDocsController 创建接口文档控制器
+func NewDocsController() *DocsController {
+	return &DocsController{}
+}
+
+// Spec 返回 OpenAPI 文档
+// @Summary OpenAPI 文档
+// @Description 由控制器注释和 schema 类型生成的 OpenAPI 3 文档
+// @Tags docs
+// @Produce json
+// @Success 200 {object} map[string]any
+// @Router /openapi.json [get]
+func (ctrl *DocsController) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openapi.Spec())
+}
+
+// UI 返回 Swagger UI 页面
+// @Summary 接口文档页面
+// @Description 浏览和调试接口的 Swagger UI 页面
+// @Tags docs
+// @Produce html
+// @Success 200 {string} string "HTML"
+// @Router /docs [get]
+func (ctrl *DocsController) UI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsPage())
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"ai-note-service/internal/application/common"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/experiment"
+	"ai-note-service/internal/application/schema"
+	"errors"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExperimentController 实验结果控制器
+type ExperimentController struct {
+	tracker *experiment.Tracker
+}
+
+// NewExperimentController 创建实验结果控制器
+func NewExperimentController(tracker *experiment.Tracker) *ExperimentController {
+	return &ExperimentController{
+		tracker: tracker,
+	}
+}
+
+// RateResult 对分析结果或 AI 回复评分
+// @Summary 结果评分
+// @Description 对分析结果（id）或对话回复（messageId）打 1-5 分，参与实验的结果会计入对应变体
+// @Tags experiment
+// @Accept json
+// @Produce json
+// @Param resultId path string true "分析结果ID或对话消息ID"
+// @Param request body schema.RatingRequest true "评分"
+// @Success 200 {object} schema.Response
+// @Router /api/results/{resultId}/rating [post]
+func (ctrl *ExperimentController) RateResult(c *gin.Context) {
+	resultID := c.Param("resultId")
+
+	var req schema.RatingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+
+	if err := ctrl.tracker.Rate(resultID, req.Score); err != nil {
+		if errors.Is(err, experiment.ErrUnknownResult) {
+			common.LocalizedErrorResponse(c, errcode.NotFound, "experiment.unknown_result", resultID)
+			return
+		}
+		common.HandleError(c, err)
+		return
+	}
+
+	slog.InfoContext(c.Request.Context(), "result rated", "result_id", resultID, "score", req.Score)
+	common.SuccessResponse(c, nil)
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"ai-note-service/internal/application/common"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/identity"
+	"ai-note-service/internal/application/schema"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeedbackController 用户反馈控制器
+type FeedbackController struct {
+	results *feedback.Results
+	store   *feedback.Store
+}
+
+// NewFeedbackController 创建用户反馈控制器
+func NewFeedbackController(results *feedback.Results, store *feedback.Store) *FeedbackController {
+	return &FeedbackController{
+		results: results,
+		store:   store,
+	}
+}
+
+// AnalysisFeedback 对分析结果反馈
+// @Summary 分析结果反馈
+// @Description 对图片分析结果点赞或点踩，可附带原因分类、文字说明和有问题的知识点ID
+// @Tags feedback
+// @Accept json
+// @Produce json
+// @Param id path string true "分析结果ID"
+// @Param request body schema.FeedbackRequest true "反馈内容"
+// @Success 200 {object} schema.Response{data=schema.FeedbackResponse}
+// @Router /api/analyses/{id}/feedback [post]
+func (ctrl *FeedbackController) AnalysisFeedback(c *gin.Context) {
+	analysisID := c.Param("id")
+
+	var req schema.FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+
+	snapshot, ok := ctrl.results.Lookup(feedback.KindAnalysis, analysisID)
+	if !ok {
+		common.LocalizedErrorResponse(c, errcode.NotFound, "feedback.unknown_analysis", analysisID)
+		return
+	}
+	if req.KeyPointID != "" && !hasKnowledgePoint(snapshot.Analysis, req.KeyPointID) {
+		lang := i18n.FromContext(c.Request.Context())
+		common.HandleError(c, errcode.NewValidationError(errcode.FieldError{
+			Field:   "keyPointId",
+			Message: i18n.T(lang, "feedback.unknown_keypoint", req.KeyPointID),
+		}))
+		return
+	}
+
+	record := newFeedbackRecord(c, &req, snapshot)
+	record.AnalysisID = analysisID
+	record.KeyPointID = req.KeyPointID
+	record.Analysis = snapshot.Analysis
+	ctrl.save(c, record)
+}
+
+// MessageFeedback 对 AI 回复反馈
+// @Summary AI 回复反馈
+// @Description 对对话中的 AI 回复点赞或点踩，可附带原因分类和文字说明
+// @Tags feedback
+// @Accept json
+// @Produce json
+// @Param id path string true "会话ID"
+// @Param msgId path string true "AI 回复消息ID"
+// @Param request body schema.FeedbackRequest true "反馈内容"
+// @Success 200 {object} schema.Response{data=schema.FeedbackResponse}
+// @Router /api/conversations/{id}/messages/{msgId}/feedback [post]
+func (ctrl *FeedbackController) MessageFeedback(c *gin.Context) {
+	conversationID := c.Param("id")
+	messageID := c.Param("msgId")
+
+	var req schema.FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+
+	snapshot, ok := ctrl.results.Lookup(feedback.KindMessage, messageID)
+	if !ok || snapshot.ConversationID != conversationID {
+		common.LocalizedErrorResponse(c, errcode.NotFound, "feedback.unknown_message", conversationID, messageID)
+		return
+	}
+	if req.KeyPointID != "" {
+		lang := i18n.FromContext(c.Request.Context())
+		common.HandleError(c, errcode.NewValidationError(errcode.FieldError{
+			Field:   "keyPointId",
+			Message: i18n.T(lang, "feedback.keypoint_not_allowed"),
+		}))
+		return
+	}
+
+	record := newFeedbackRecord(c, &req, snapshot)
+	record.ConversationID = conversationID
+	record.MessageID = messageID
+	record.KnowledgePointID = snapshot.KnowledgePointID
+	record.KnowledgePointTitle = snapshot.KnowledgePointTitle
+	record.UserMessage = snapshot.UserMessage
+	record.Reply = snapshot.Reply
+	ctrl.save(c, record)
+}
+
+// save 保存反馈并返回反馈ID
+func (ctrl *FeedbackController) save(c *gin.Context, record *feedback.Record) {
+	if err := ctrl.store.Add(record); err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	slog.InfoContext(c.Request.Context(), "feedback received",
+		"feedback_id", record.ID,
+		"kind", record.Kind,
+		"rating", record.Rating,
+		"reasons", record.Reasons,
+		"prompt_version", record.PromptVersion,
+	)
+	common.SuccessResponse(c, schema.FeedbackResponse{ID: record.ID})
+}
+
+// newFeedbackRecord 根据请求和结果快照创建反馈记录
+func newFeedbackRecord(c *gin.Context, req *schema.FeedbackRequest, snapshot *feedback.Snapshot) *feedback.Record {
+	userID := identity.UserIDFrom(c.Request.Context())
+	if userID == "" {
+		userID = snapshot.UserID
+	}
+	return &feedback.Record{
+		ID:             identity.NewID(),
+		CreatedAt:      time.Now(),
+		Kind:           snapshot.Kind,
+		UserID:         userID,
+		Rating:         req.Rating,
+		Reasons:        req.Reasons,
+		Comment:        req.Comment,
+		Language:       snapshot.Language,
+		PromptVersion:  snapshot.PromptVersion,
+		ProfileVersion: snapshot.ProfileVersion,
+		Experiment:     snapshot.Experiment,
+	}
+}
+
+// hasKnowledgePoint 判断知识点ID是否属于分析结果（重点、前置或后置知识点）
+func hasKnowledgePoint(analysis *schema.KnowledgeAnalysisResponse, id string) bool {
+	if analysis == nil {
+		return false
+	}
+	for _, points := range [][]schema.KnowledgePoint{analysis.KeyPoints, analysis.Prerequisites, analysis.Postrequisites} {
+		for _, p := range points {
+			if p.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
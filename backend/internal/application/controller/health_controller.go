@@ -23,8 +23,7 @@ func NewHealthController() *HealthController {
 // @Router /health [get]
 func (ctrl *HealthController) Check(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
+		"status":  "ok",
 		"service": "ai-note-service",
 	})
 }
-
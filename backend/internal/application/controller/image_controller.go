@@ -2,13 +2,24 @@ package controller
 
 import (
 	"ai-note-service/internal/application/common"
+	"ai-note-service/internal/application/document"
 	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/schema"
 	"ai-note-service/internal/application/service"
-	"log"
+	"ai-note-service/internal/application/telemetry"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ImageController 图片控制器
@@ -17,63 +28,376 @@ type ImageController struct {
 }
 
 // NewImageController 创建图片控制器
-func NewImageController() *ImageController {
+func NewImageController(imageAnalysisService *service.ImageAnalysisService) *ImageController {
 	return &ImageController{
-		imageAnalysisService: service.NewImageAnalysisService(),
+		imageAnalysisService: imageAnalysisService,
 	}
 }
 
-// AnalyzeImage 分析图片并提取知识点
+// AnalyzeImage 分析图片并提取知识点，mode=check 时批改手写作业
 // @Summary 图片知识点分析
-// @Description 上传图片文件，分析图片内容并返回检测到的知识点及其关系
+// @Description 上传一张或多张图片（如连续的课本页，按上传顺序编号为第 1、2… 页），分析内容并返回合并后的知识点及其关系；多页时每个重点知识点的 sources 给出来源页码。mode=check 时按手写作业批改，逐步转写解答并找出第一个错误步骤，返回批改结果（mode 字段为 check）
 // @Tags 图片分析
 // @Accept multipart/form-data
 // @Produce json
-// @Param image formData file true "图片文件"
-// @Success 200 {object} schema.Response{data=schema.KnowledgeAnalysisResponse}
+// @Param image formData []file true "图片文件，可重复传入多页，按顺序分析"
+// @Param mode query string false "analyze 知识点分析（默认），check 批改手写作业" Enums(analyze, check)
+// @Param depth query string false "分析深度，默认按配置（standard）" Enums(quick, standard, deep)
+// @Param keyPoints query int false "重点知识点数量上限（1-10），默认按深度预设"
+// @Param prerequisites query int false "前置知识点数量（1-10），默认按深度预设"
+// @Param postrequisites query int false "后置知识点数量（1-10），默认按深度预设"
+// @Param funExamples query int false "每个重点知识点的趣味示例数量（1-3），默认按深度预设"
+// @Param lang query string false "输出语言（zh-CN、en），默认按学习者画像中的偏好语言或 Accept-Language"
+// @Param gradeLevel query string false "本次请求覆盖学习者画像中的年级" Enums(primary, middle, high, university, adult)
+// @Param strengths query string false "本次请求覆盖学习者画像中擅长的科目，逗号分隔"
+// @Param style query string false "本次请求覆盖学习者画像中的讲解方式" Enums(concise, detailed, examples)
+// @Success 200 {object} schema.Response{data=schema.KnowledgeAnalysisResponse|schema.HomeworkCheckResponse}
 // @Router /api/analyze/image [post]
 func (ctrl *ImageController) AnalyzeImage(c *gin.Context) {
-	// 1. 接收图片文件
-	file, err := c.FormFile("image")
-	if err != nil {
-		common.ErrorResponse(c, errcode.InvalidParams, "请上传图片文件")
+	ctx, span := telemetry.StartSpan(c.Request.Context(), "ImageController.AnalyzeImage")
+	defer span.End()
+
+	mode := c.DefaultQuery("mode", "analyze")
+	if mode != "analyze" && mode != schema.AnalysisModeCheck {
+		common.LocalizedErrorResponse(c, errcode.InvalidParams, "analysis.invalid_mode", mode)
+		return
+	}
+	var depth schema.AnalysisDepth
+	if err := c.ShouldBindQuery(&depth); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+	ctx, ok := withLearnerOverride(ctx, c)
+	if !ok {
 		return
 	}
 
-	// 2. 验证图片格式
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowedExts := []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
-	valid := false
-	for _, allowed := range allowedExts {
-		if ext == allowed {
-			valid = true
-			break
+	// 1. 接收图片文件，同名字段可重复传入多页
+	var files []*multipart.FileHeader
+	form, err := c.MultipartForm()
+	if err == nil {
+		files = form.File["image"]
+	}
+	if len(files) == 0 {
+		if err == nil {
+			err = http.ErrMissingFile
 		}
+		telemetry.RecordError(span, err)
+		common.LocalizedErrorResponse(c, errcode.InvalidParams, "image.required")
+		return
 	}
-	if !valid {
-		common.ErrorResponse(c, errcode.InvalidParams, "不支持的图片格式，请上传 jpg, jpeg, png, gif 或 webp 格式的图片")
+	if maxImages := ctrl.imageAnalysisService.MaxImages(); len(files) > maxImages {
+		common.LocalizedErrorResponse(c, errcode.InvalidParams, "image.too_many", maxImages)
 		return
 	}
 
-	// 3. 验证文件大小（最大 10MB）
-	maxSize := int64(10 * 1024 * 1024) // 10MB
-	if file.Size > maxSize {
-		common.ErrorResponse(c, errcode.InvalidParams, "图片文件过大，最大支持 10MB")
-		return
+	var totalSize int64
+	images := make([][]byte, 0, len(files))
+	for _, file := range files {
+		// 2. 验证图片格式
+		if !allowedImageExt(file.Filename) {
+			common.LocalizedErrorResponse(c, errcode.UnsupportedMediaType, "image.unsupported_format")
+			return
+		}
+
+		// 3. 验证文件大小（每张最大 10MB）
+		if file.Size > service.MaxImageSizeMB*1024*1024 {
+			common.LocalizedErrorResponse(c, errcode.PayloadTooLarge, "image.too_large", service.MaxImageSizeMB)
+			return
+		}
+		totalSize += file.Size
+
+		data, err := readFormFile(ctx, file)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			common.HandleError(c, err)
+			return
+		}
+		images = append(images, data)
 	}
+	span.SetAttributes(
+		attribute.Int("file.count", len(files)),
+		attribute.Int64("file.size", totalSize),
+	)
 
 	// 4. 使用AI服务分析图片
-	log.Printf("开始分析图片: %s (大小: %d bytes)", file.Filename, file.Size)
-	
-	analysisResult, err := ctrl.imageAnalysisService.AnalyzeImage(file)
+	slog.InfoContext(ctx, "image analysis started", "mode", mode, "file_name", files[0].Filename, "file_count", len(files), "file_size", totalSize)
+
+	if mode == schema.AnalysisModeCheck {
+		checkResult, err := ctrl.imageAnalysisService.CheckHomework(ctx, document.ImageSource(images), i18n.FromContext(ctx))
+		if err != nil {
+			telemetry.RecordError(span, err)
+			common.HandleError(c, err)
+			return
+		}
+		slog.InfoContext(ctx, "homework check succeeded", "steps", len(checkResult.Steps), "first_error_step", checkResult.FirstErrorStep)
+		common.SuccessResponse(c, checkResult)
+		return
+	}
+
+	analysisResult, err := ctrl.imageAnalysisService.Analyze(ctx, document.ImageSource(images), depth, i18n.FromContext(ctx))
 	if err != nil {
-		log.Printf("图片分析失败: %v", err)
-		common.ErrorResponse(c, errcode.AIServiceError, err.Error())
+		telemetry.RecordError(span, err)
+		common.HandleError(c, err)
 		return
 	}
 
-	log.Printf("图片分析成功，识别到 %d 个重点知识点", len(analysisResult.KeyPoints))
+	span.SetAttributes(attribute.Int("analysis.key_points", len(analysisResult.KeyPoints)))
+	slog.InfoContext(ctx, "image analysis succeeded", "key_points", len(analysisResult.KeyPoints))
 
 	// 5. 返回成功响应
 	common.SuccessResponse(c, analysisResult)
 }
+
+// AnalyzePDF 分析 PDF 文档并提取知识点
+// @Summary PDF 知识点分析
+// @Description 上传 PDF（如课件、论文），有文字层的页面直接提取文字，其余页面渲染为图片后一并分析；可通过 pages 选择页码范围，每个重点知识点的 sources 给出来源页码，无法处理而跳过的页面在 skippedPages 中列出
+// @Tags 图片分析
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "PDF 文件"
+// @Param pages formData string false "页码范围，如 1-3,5,8-，默认全部页面"
+// @Param depth query string false "分析深度，默认按配置（standard）" Enums(quick, standard, deep)
+// @Param keyPoints query int false "重点知识点数量上限（1-10），默认按深度预设"
+// @Param prerequisites query int false "前置知识点数量（1-10），默认按深度预设"
+// @Param postrequisites query int false "后置知识点数量（1-10），默认按深度预设"
+// @Param funExamples query int false "每个重点知识点的趣味示例数量（1-3），默认按深度预设"
+// @Param lang query string false "输出语言（zh-CN、en），默认按学习者画像中的偏好语言或 Accept-Language"
+// @Param gradeLevel query string false "本次请求覆盖学习者画像中的年级" Enums(primary, middle, high, university, adult)
+// @Param strengths query string false "本次请求覆盖学习者画像中擅长的科目，逗号分隔"
+// @Param style query string false "本次请求覆盖学习者画像中的讲解方式" Enums(concise, detailed, examples)
+// @Success 200 {object} schema.Response{data=schema.KnowledgeAnalysisResponse}
+// @Router /api/analyze/pdf [post]
+func (ctrl *ImageController) AnalyzePDF(c *gin.Context) {
+	ctx, span := telemetry.StartSpan(c.Request.Context(), "ImageController.AnalyzePDF")
+	defer span.End()
+
+	var depth schema.AnalysisDepth
+	if err := c.ShouldBindQuery(&depth); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+	ctx, ok := withLearnerOverride(ctx, c)
+	if !ok {
+		return
+	}
+
+	// 1. 接收 PDF 文件
+	file, err := c.FormFile("file")
+	if err != nil {
+		telemetry.RecordError(span, err)
+		common.LocalizedErrorResponse(c, errcode.InvalidParams, "pdf.required")
+		return
+	}
+
+	// 2. 验证格式与大小
+	if strings.ToLower(filepath.Ext(file.Filename)) != ".pdf" {
+		common.LocalizedErrorResponse(c, errcode.UnsupportedMediaType, "pdf.unsupported_format")
+		return
+	}
+	if file.Size > maxPDFSizeMB*1024*1024 {
+		common.LocalizedErrorResponse(c, errcode.PayloadTooLarge, "pdf.too_large", maxPDFSizeMB)
+		return
+	}
+	span.SetAttributes(attribute.Int64("file.size", file.Size))
+
+	data, err := readFormFile(ctx, file)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		common.HandleError(c, err)
+		return
+	}
+
+	// 3. 提取页面并分析
+	pages := c.PostForm("pages")
+	slog.InfoContext(ctx, "pdf analysis started", "file_name", file.Filename, "file_size", file.Size, "pages", pages)
+
+	analysisResult, err := ctrl.imageAnalysisService.AnalyzePDF(ctx, data, pages, depth, i18n.FromContext(ctx))
+	if err != nil {
+		telemetry.RecordError(span, err)
+		common.HandleError(c, err)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("analysis.key_points", len(analysisResult.KeyPoints)))
+	slog.InfoContext(ctx, "pdf analysis succeeded", "key_points", len(analysisResult.KeyPoints), "skipped_pages", len(analysisResult.SkippedPages))
+
+	common.SuccessResponse(c, analysisResult)
+}
+
+// AnalyzeURL 抓取链接内容并提取知识点
+// @Summary 链接知识点分析
+// @Description 抓取链接指向的图片、PDF、网页或纯文本并分析，返回与图片分析相同结构的结果；网页只提取正文文字，PDF 可通过 pages 选择页码范围。链接不能指向内网或保留地址，内容大小和抓取时间受 fetch.* 配置限制
+// @Tags 图片分析
+// @Accept json
+// @Produce json
+// @Param request body schema.AnalyzeURLRequest true "链接"
+// @Param depth query string false "分析深度，默认按配置（standard）" Enums(quick, standard, deep)
+// @Param keyPoints query int false "重点知识点数量上限（1-10），默认按深度预设"
+// @Param prerequisites query int false "前置知识点数量（1-10），默认按深度预设"
+// @Param postrequisites query int false "后置知识点数量（1-10），默认按深度预设"
+// @Param funExamples query int false "每个重点知识点的趣味示例数量（1-3），默认按深度预设"
+// @Param lang query string false "输出语言（zh-CN、en），默认按学习者画像中的偏好语言或 Accept-Language"
+// @Param gradeLevel query string false "本次请求覆盖学习者画像中的年级" Enums(primary, middle, high, university, adult)
+// @Param strengths query string false "本次请求覆盖学习者画像中擅长的科目，逗号分隔"
+// @Param style query string false "本次请求覆盖学习者画像中的讲解方式" Enums(concise, detailed, examples)
+// @Success 200 {object} schema.Response{data=schema.KnowledgeAnalysisResponse}
+// @Router /api/analyze/url [post]
+func (ctrl *ImageController) AnalyzeURL(c *gin.Context) {
+	ctx, span := telemetry.StartSpan(c.Request.Context(), "ImageController.AnalyzeURL")
+	defer span.End()
+
+	var req schema.AnalyzeURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+	var depth schema.AnalysisDepth
+	if err := c.ShouldBindQuery(&depth); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+	ctx, ok := withLearnerOverride(ctx, c)
+	if !ok {
+		return
+	}
+
+	slog.InfoContext(ctx, "url analysis started", "pages", req.Pages)
+	analysisResult, err := ctrl.imageAnalysisService.AnalyzeURL(ctx, req.URL, req.Pages, depth, i18n.FromContext(ctx))
+	if err != nil {
+		telemetry.RecordError(span, err)
+		common.HandleError(c, err)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("analysis.key_points", len(analysisResult.KeyPoints)))
+	slog.InfoContext(ctx, "url analysis succeeded", "key_points", len(analysisResult.KeyPoints))
+
+	common.SuccessResponse(c, analysisResult)
+}
+
+// AnalyzeText 分析粘贴的笔记文本并提取知识点
+// @Summary 文本知识点分析
+// @Description 分析粘贴的笔记、讲义或 Markdown 文本，返回与图片分析相同结构的结果；较长的文本按段落分页分析后合并
+// @Tags 图片分析
+// @Accept json
+// @Produce json
+// @Param request body schema.AnalyzeTextRequest true "笔记文本"
+// @Param depth query string false "分析深度，默认按配置（standard）" Enums(quick, standard, deep)
+// @Param keyPoints query int false "重点知识点数量上限（1-10），默认按深度预设"
+// @Param prerequisites query int false "前置知识点数量（1-10），默认按深度预设"
+// @Param postrequisites query int false "后置知识点数量（1-10），默认按深度预设"
+// @Param funExamples query int false "每个重点知识点的趣味示例数量（1-3），默认按深度预设"
+// @Param lang query string false "输出语言（zh-CN、en），默认按学习者画像中的偏好语言或 Accept-Language"
+// @Param gradeLevel query string false "本次请求覆盖学习者画像中的年级" Enums(primary, middle, high, university, adult)
+// @Param strengths query string false "本次请求覆盖学习者画像中擅长的科目，逗号分隔"
+// @Param style query string false "本次请求覆盖学习者画像中的讲解方式" Enums(concise, detailed, examples)
+// @Success 200 {object} schema.Response{data=schema.KnowledgeAnalysisResponse}
+// @Router /api/analyze/text [post]
+func (ctrl *ImageController) AnalyzeText(c *gin.Context) {
+	ctx, span := telemetry.StartSpan(c.Request.Context(), "ImageController.AnalyzeText")
+	defer span.End()
+
+	var req schema.AnalyzeTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+	var depth schema.AnalysisDepth
+	if err := c.ShouldBindQuery(&depth); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+	ctx, ok := withLearnerOverride(ctx, c)
+	if !ok {
+		return
+	}
+	span.SetAttributes(attribute.Int("text.length", len(req.Text)))
+
+	slog.InfoContext(ctx, "text analysis started", "text_length", len(req.Text))
+	analysisResult, err := ctrl.imageAnalysisService.AnalyzeText(ctx, req.Text, depth, i18n.FromContext(ctx))
+	if err != nil {
+		telemetry.RecordError(span, err)
+		common.HandleError(c, err)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("analysis.key_points", len(analysisResult.KeyPoints)))
+	slog.InfoContext(ctx, "text analysis succeeded", "key_points", len(analysisResult.KeyPoints))
+
+	common.SuccessResponse(c, analysisResult)
+}
+
+// KeyPointCrop 返回知识点在原图中的区域截图
+// @Summary 知识点区域截图
+// @Description 按分析结果中知识点 sources 给出的区域截取原图（四周略微外扩），供界面高亮和对话引用。原图只在内存中保留最近的分析结果，过期后返回 404
+// @Tags 图片分析
+// @Produce png
+// @Param id path string true "分析结果ID"
+// @Param keyPointId path string true "重点知识点ID"
+// @Param source query int false "sources 中的下标，默认第一个带区域的来源"
+// @Success 200 {file} file "PNG 图片"
+// @Router /api/analyses/{id}/key-points/{keyPointId}/crop [get]
+func (ctrl *ImageController) KeyPointCrop(c *gin.Context) {
+	ctx, span := telemetry.StartSpan(c.Request.Context(), "ImageController.KeyPointCrop")
+	defer span.End()
+
+	source := -1
+	if v := c.Query("source"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			common.LocalizedErrorResponse(c, errcode.InvalidParams, "crop.invalid_source")
+			return
+		}
+		source = n
+	}
+
+	image, src, err := ctrl.imageAnalysisService.KeyPointCrop(ctx, c.Param("id"), c.Param("keyPointId"), source)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		common.HandleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Header("X-Source-Page", strconv.Itoa(src.Page))
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// maxPDFSizeMB PDF 文件的大小上限
+const maxPDFSizeMB = 20
+
+// readFormFile 读取上传文件的全部内容，读取耗时记录在 ImageController.readFormFile span 中
+func readFormFile(ctx context.Context, file *multipart.FileHeader) (data []byte, err error) {
+	_, span := telemetry.StartSpan(ctx, "ImageController.readFormFile")
+	span.SetAttributes(
+		attribute.String("file.name", file.Filename),
+		attribute.Int64("file.size", file.Size),
+	)
+	defer func() {
+		telemetry.RecordError(span, err)
+		span.End()
+	}()
+
+	f, err := file.Open()
+	if err != nil {
+		return nil, errcode.Wrap(errcode.InternalError, fmt.Errorf("read uploaded file failed: %w", err), "")
+	}
+	defer f.Close()
+	data, err = io.ReadAll(f)
+	if err != nil {
+		return nil, errcode.Wrap(errcode.InternalError, fmt.Errorf("read uploaded file failed: %w", err), "")
+	}
+	return data, nil
+}
+
+// allowedImageExt 判断文件扩展名是否为支持的图片格式
+func allowedImageExt(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, allowed := range []string{".jpg", ".jpeg", ".png", ".gif", ".webp"} {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
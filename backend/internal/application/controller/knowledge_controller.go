@@ -3,9 +3,11 @@ package controller
 import (
 	"ai-note-service/internal/application/common"
 	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/logger"
 	"ai-note-service/internal/application/schema"
 	"ai-note-service/internal/application/service"
-	"log"
+	"log/slog"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,19 +18,23 @@ type KnowledgeController struct {
 }
 
 // NewKnowledgeController 创建知识点控制器
-func NewKnowledgeController() *KnowledgeController {
+func NewKnowledgeController(knowledgeService *service.KnowledgeService) *KnowledgeController {
 	return &KnowledgeController{
-		knowledgeService: service.NewKnowledgeService(),
+		knowledgeService: knowledgeService,
 	}
 }
 
 // GetDialogue 获取知识点的AI对话响应
 // @Summary AI对话接口
-// @Description 发送用户消息，获取AI助手关于特定知识点的回复
+// @Description 发送用户消息，获取AI助手关于特定知识点的回复。strategy 选择辅导策略并沿用到整个会话，非直接讲解的策略在响应的 tutoring 中报告学生是否已达成目标
 // @Tags AI对话
 // @Accept json
 // @Produce json
 // @Param knowledgePointId path string true "知识点ID"
+// @Param lang query string false "回复语言（zh-CN、en），默认按学习者画像中的偏好语言或 Accept-Language"
+// @Param gradeLevel query string false "本次请求覆盖学习者画像中的年级" Enums(primary, middle, high, university, adult)
+// @Param strengths query string false "本次请求覆盖学习者画像中擅长的科目，逗号分隔"
+// @Param style query string false "本次请求覆盖学习者画像中的讲解方式" Enums(concise, detailed, examples)
 // @Param request body schema.DialogueRequest true "对话请求"
 // @Success 200 {object} schema.Response{data=schema.DialogueResponse}
 // @Router /api/knowledge-points/{knowledgePointId}/dialogue [post]
@@ -36,40 +42,51 @@ func (ctrl *KnowledgeController) GetDialogue(c *gin.Context) {
 	// 1. 获取知识点ID
 	knowledgePointId := c.Param("knowledgePointId")
 	if knowledgePointId == "" {
-		common.ErrorResponse(c, errcode.InvalidParams, "知识点ID不能为空")
+		common.LocalizedErrorResponse(c, errcode.InvalidParams, "knowledge_point.id_required")
 		return
 	}
 
 	// 2. 绑定请求参数
 	var req schema.DialogueRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.ErrorResponse(c, errcode.InvalidParams, err.Error())
+		common.ValidationErrorResponse(c, err)
 		return
 	}
 
-	// 3. 验证前端必须传递知识点信息
-	if req.KnowledgePointTitle == "" || req.KnowledgePointDesc == "" {
-		common.ErrorResponse(c, errcode.InvalidParams, "请求参数不完整：必须提供 knowledgePointTitle 和 knowledgePointDesc")
+	ctx, ok := withLearnerOverride(c.Request.Context(), c)
+	if !ok {
 		return
 	}
+	lang := i18n.FromContext(ctx)
+	slog.InfoContext(ctx, "dialogue request",
+		"knowledge_point_id", knowledgePointId,
+		logger.UserContent("knowledge_point_title", req.KnowledgePointTitle),
+		"history_length", len(req.ConversationHistory),
+		"image_context", req.ImageContext,
+		"strategy", req.Strategy,
+		logger.UserContent("message", req.Message),
+	)
 
-	log.Printf("对话请求 - ID: %s, Title: %s, Description: %s", knowledgePointId, req.KnowledgePointTitle, req.KnowledgePointDesc)
-
-	// 4. 调用AI服务获取对话响应
+	// 3. 调用AI服务获取对话响应
 	response, err := ctrl.knowledgeService.GetDialogueResponse(
+		ctx,
+		req.ConversationID,
 		knowledgePointId,
 		req.KnowledgePointTitle,
 		req.KnowledgePointDesc,
 		req.Message,
 		req.ConversationHistory,
+		req.AnalysisID,
+		req.ImageContext,
+		req.Strategy,
+		lang,
 	)
 
 	if err != nil {
-		log.Printf("AI对话失败: %v", err)
-		common.ErrorResponse(c, errcode.AIServiceError, err.Error())
+		common.HandleError(c, err)
 		return
 	}
 
-	// 5. 返回成功响应
+	// 4. 返回成功响应
 	common.SuccessResponse(c, response)
 }
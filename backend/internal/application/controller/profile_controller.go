@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"ai-note-service/internal/application/common"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/identity"
+	"ai-note-service/internal/application/profile"
+	"ai-note-service/internal/application/schema"
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProfileController 学习者画像控制器
+type ProfileController struct {
+	store *profile.Store
+}
+
+// NewProfileController 创建学习者画像控制器
+func NewProfileController(store *profile.Store) *ProfileController {
+	return &ProfileController{
+		store: store,
+	}
+}
+
+// GetProfile 查询当前用户的学习者画像
+// @Summary 查询学习者画像
+// @Description 返回 X-User-ID 对应用户保存的学习者画像（年级、擅长科目、偏好语言和讲解方式）
+// @Tags profile
+// @Produce json
+// @Success 200 {object} schema.Response{data=schema.LearnerProfile}
+// @Router /api/profile [get]
+func (ctrl *ProfileController) GetProfile(c *gin.Context) {
+	userID := identity.UserIDFrom(c.Request.Context())
+	if userID == "" {
+		common.LocalizedErrorResponse(c, errcode.InvalidParams, "profile.user_required")
+		return
+	}
+	p, ok := ctrl.store.Get(userID)
+	if !ok {
+		common.LocalizedErrorResponse(c, errcode.NotFound, "profile.not_found", userID)
+		return
+	}
+	common.SuccessResponse(c, p)
+}
+
+// PutProfile 保存当前用户的学习者画像
+// @Summary 保存学习者画像
+// @Description 整体替换 X-User-ID 对应用户的学习者画像，版本号加 1。之后的分析和对话按画像调整难度和讲解方式，结果的 profileVersion 记录所用的画像版本；未指定 lang 时按画像中的偏好语言输出
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Param request body schema.LearnerProfileRequest true "学习者画像"
+// @Success 200 {object} schema.Response{data=schema.LearnerProfile}
+// @Router /api/profile [put]
+func (ctrl *ProfileController) PutProfile(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := identity.UserIDFrom(ctx)
+	if userID == "" {
+		common.LocalizedErrorResponse(c, errcode.InvalidParams, "profile.user_required")
+		return
+	}
+
+	var req schema.LearnerProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return
+	}
+
+	p, err := ctrl.store.Put(userID, &req, time.Now())
+	if err != nil {
+		common.HandleError(c, errcode.Wrap(errcode.InternalError, err, ""))
+		return
+	}
+	slog.InfoContext(ctx, "learner profile saved", "version", p.Version, "grade_level", p.GradeLevel, "style", p.Style)
+	common.SuccessResponse(c, p)
+}
+
+// withLearnerOverride 读取查询参数中对学习者画像的覆盖，叠加到 context 中已保存的画像上
+// 参数不合法时写入错误响应并返回 false
+func withLearnerOverride(ctx context.Context, c *gin.Context) (context.Context, bool) {
+	var override schema.LearnerOverride
+	if err := c.ShouldBindQuery(&override); err != nil {
+		common.ValidationErrorResponse(c, err)
+		return ctx, false
+	}
+	return profile.WithLearner(ctx, profile.LearnerFrom(ctx).Apply(override)), true
+}
@@ -1,39 +1,104 @@
 package controller
 
 import (
+	"ai-note-service/internal/application/app"
+	"ai-note-service/internal/application/common"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/identity"
+	"ai-note-service/internal/application/logger"
+	"ai-note-service/internal/application/openapi"
+	"ai-note-service/internal/application/profile"
+	"ai-note-service/internal/application/telemetry"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Router 路由配置
 type Router struct {
-	engine              *gin.Engine
-	chatController      *ChatController
-	healthController    *HealthController
-	imageController     *ImageController
-	knowledgeController *KnowledgeController
+	engine               *gin.Engine
+	config               global.Provider
+	doc                  *openapi.Document
+	chatController       *ChatController
+	healthController     *HealthController
+	imageController      *ImageController
+	knowledgeController  *KnowledgeController
+	adminController      *AdminController
+	experimentController *ExperimentController
+	feedbackController   *FeedbackController
+	profileController    *ProfileController
+	docsController       *DocsController
 }
 
-// NewRouter 创建路由
-func NewRouter() *Router {
-	engine := gin.Default()
+// NewRouter 创建路由，控制器依赖由应用容器注入
+func NewRouter(c *app.Container) *Router {
+	engine := gin.New()
+
+	// 请求ID、链路追踪、访问日志和异常恢复中间件
+	engine.Use(requestIDMiddleware())
+	engine.Use(tracingMiddleware())
+	engine.Use(accessLogMiddleware())
+	engine.Use(recoveryMiddleware())
+	engine.Use(languageMiddleware())
+	engine.Use(userMiddleware())
+	engine.Use(profileMiddleware(c.Profiles))
 
 	// 添加CORS中间件
 	engine.Use(corsMiddleware())
 
+	// 按 OpenAPI 文档校验请求和响应；文档编译进二进制，内容由测试保证有效
+	doc, err := openapi.Load()
+	if err != nil {
+		panic(fmt.Sprintf("load embedded OpenAPI spec: %v", err))
+	}
+	engine.Use(validationMiddleware(doc, c.Config))
+
 	return &Router{
-		engine:              engine,
-		chatController:      NewChatController(),
-		healthController:    NewHealthController(),
-		imageController:     NewImageController(),
-		knowledgeController: NewKnowledgeController(),
+		engine:               engine,
+		config:               c.Config,
+		doc:                  doc,
+		chatController:       NewChatController(c.AI, c.Prompts),
+		healthController:     NewHealthController(),
+		imageController:      NewImageController(c.ImageAnalysis),
+		knowledgeController:  NewKnowledgeController(c.Knowledge),
+		adminController:      NewAdminController(c.Config, c.Prompts, c.Tracker, c.Feedback),
+		experimentController: NewExperimentController(c.Tracker),
+		feedbackController:   NewFeedbackController(c.Results, c.Feedback),
+		profileController:    NewProfileController(c.Profiles),
+		docsController:       NewDocsController(),
 	}
 }
 
 // Setup 设置路由
 func (r *Router) Setup() *gin.Engine {
+	// 未匹配的路由和方法返回统一错误结构
+	r.engine.HandleMethodNotAllowed = true
+	r.engine.NoRoute(func(c *gin.Context) {
+		common.ErrorResponse(c, errcode.NotFound, c.Request.URL.Path)
+	})
+	r.engine.NoMethod(func(c *gin.Context) {
+		common.ErrorResponse(c, errcode.MethodNotAllowed, c.Request.Method)
+	})
+
 	// 健康检查
 	r.engine.GET("/health", r.healthController.Check)
 
+	// 接口文档
+	r.engine.GET("/openapi.json", r.docsController.Spec)
+	r.engine.GET("/docs", r.docsController.UI)
+
 	// API路由组
 	api := r.engine.Group("/api")
 	{
@@ -41,6 +106,9 @@ func (r *Router) Setup() *gin.Engine {
 		analyze := api.Group("/analyze")
 		{
 			analyze.POST("/image", r.imageController.AnalyzeImage)
+			analyze.POST("/pdf", r.imageController.AnalyzePDF)
+			analyze.POST("/url", r.imageController.AnalyzeURL)
+			analyze.POST("/text", r.imageController.AnalyzeText)
 		}
 
 		// 知识点相关路由
@@ -48,6 +116,30 @@ func (r *Router) Setup() *gin.Engine {
 		{
 			knowledgePoints.POST("/:knowledgePointId/dialogue", r.knowledgeController.GetDialogue)
 		}
+
+		// 结果评分
+		api.POST("/results/:resultId/rating", r.experimentController.RateResult)
+
+		// 知识点区域截图
+		api.GET("/analyses/:id/key-points/:keyPointId/crop", r.imageController.KeyPointCrop)
+
+		// 学习者画像，按 X-User-ID 区分用户
+		api.GET("/profile", r.profileController.GetProfile)
+		api.PUT("/profile", r.profileController.PutProfile)
+
+		// 用户反馈
+		api.POST("/analyses/:id/feedback", r.feedbackController.AnalysisFeedback)
+		api.POST("/conversations/:id/messages/:msgId/feedback", r.feedbackController.MessageFeedback)
+
+		// 管理接口，需要 admin.token；请求在鉴权通过后才按文档校验
+		admin := api.Group("/admin", adminAuthMiddleware(r.config), securedValidationMiddleware(r.doc, r.config))
+		{
+			admin.GET("/prompts", r.adminController.ListPrompts)
+			admin.POST("/prompts/:name/preview", r.adminController.PreviewPrompt)
+			admin.GET("/experiments/report", r.adminController.ExperimentReport)
+			admin.GET("/feedback/summary", r.adminController.FeedbackSummary)
+			admin.GET("/feedback/export", r.adminController.ExportFeedback)
+		}
 	}
 
 	// API v1 路由组（保留原有的聊天接口）
@@ -69,7 +161,8 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, X-User-ID, Accept-Language, traceparent, tracestate")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Content-Language")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -80,3 +173,153 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// tracingMiddleware 链路追踪中间件
+// 从请求头中提取 W3C trace-context，并为每个请求创建服务端 span
+func tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		ctx, span := telemetry.StartSpan(ctx, fmt.Sprintf("%s %s", c.Request.Method, route),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+	}
+}
+
+// requestIDMiddleware 请求ID中间件
+// 透传客户端传入的 X-Request-ID（不合法时重新生成），写入响应头和请求 context
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(logger.HeaderRequestID)
+		if !logger.ValidRequestID(requestID) {
+			requestID = logger.NewRequestID()
+		}
+
+		c.Set(logger.KeyRequestID, requestID)
+		c.Writer.Header().Set(logger.HeaderRequestID, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+	}
+}
+
+// accessLogMiddleware 访问日志中间件
+func accessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		slog.Log(c.Request.Context(), level, "http request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"response_bytes", c.Writer.Size(),
+		)
+	}
+}
+
+// recoveryMiddleware 异常恢复中间件，panic 时记录日志并返回内部错误
+func recoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		common.InternalErrorResponse(c, fmt.Errorf("panic: %v", recovered))
+		c.Abort()
+	})
+}
+
+// languageMiddleware 语言协商中间件
+// 按 lang 查询参数 > Accept-Language 请求头 > 默认语言的顺序确定请求语言
+func languageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.Query(i18n.QueryParam), c.GetHeader("Accept-Language"))
+		c.Request = c.Request.WithContext(i18n.WithLanguage(c.Request.Context(), lang))
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		c.Next()
+	}
+}
+
+// profileMiddleware 学习者画像中间件
+// 按 X-User-ID 读取已保存的画像写入 context；请求未通过 lang 参数指定语言时，使用画像中的偏好语言
+func profileMiddleware(store *profile.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if p, ok := store.Get(identity.UserIDFrom(ctx)); ok {
+			ctx = profile.WithLearner(ctx, profile.FromProfile(p))
+			if _, explicit := i18n.Match(c.Query(i18n.QueryParam)); !explicit {
+				if lang, ok := i18n.Match(p.Language); ok {
+					ctx = i18n.WithLanguage(ctx, lang)
+				}
+			}
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		c.Next()
+	}
+}
+
+// adminAuthMiddleware 管理接口鉴权中间件
+// 未配置 admin.token 时管理接口关闭，按路由不存在处理；token 每次请求从当前配置读取，支持热加载
+func adminAuthMiddleware(config global.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := config().Admin.Token
+		if token == "" {
+			common.ErrorResponse(c, errcode.NotFound, c.Request.URL.Path)
+			c.Abort()
+			return
+		}
+
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			common.ErrorResponse(c, errcode.Unauthorized, "")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// userMiddleware 用户标识中间件
+// 读取 X-User-ID 请求头写入 context，用于实验分组等按用户保持一致的功能；格式不合法时忽略
+func userMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID := c.GetHeader(identity.HeaderUserID); identity.ValidUserID(userID) {
+			c.Request = c.Request.WithContext(identity.WithUserID(c.Request.Context(), userID))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,1181 @@
+package controller
+
+import (
+	"ai-note-service/internal/application/aitest"
+	"ai-note-service/internal/application/app"
+	"ai-note-service/internal/application/document/pdftest"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/identity"
+	"ai-note-service/internal/application/openapi"
+	"ai-note-service/internal/application/schema"
+	"ai-note-service/internal/application/service"
+	"ai-note-service/internal/application/telemetry"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testEnv 通过 Router.Setup 发起请求的端到端测试环境，模型服务由 aitest.Server 模拟
+type testEnv struct {
+	t      *testing.T
+	fake   *aitest.Server
+	engine *gin.Engine
+}
+
+// newTestEnv 创建测试环境，configure 可在默认测试配置上修改
+func newTestEnv(t *testing.T, configure ...func(*global.AppConfig)) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	fake := aitest.NewServer()
+	t.Cleanup(fake.Close)
+	cfg := &global.AppConfig{
+		AI:    global.AIConfig{BaseURL: fake.URL, APIKey: "test-key", DefaultModel: "test-model", Timeout: 5},
+		Admin: global.AdminConfig{Token: "admin-secret"},
+		// 测试中校验所有响应，接口实现与文档不一致时返回 500
+		Validation: global.ValidationConfig{Responses: true},
+		// 链接分析测试中的链接指向本机的 httptest 服务
+		Fetch: global.FetchConfig{AllowPrivate: true},
+	}
+	for _, f := range configure {
+		f(cfg)
+	}
+	c, err := app.New(global.Static(cfg), app.Options{Feedback: feedback.NewMemoryStore()})
+	if err != nil {
+		t.Fatalf("app.New: %v", err)
+	}
+	return &testEnv{t: t, fake: fake, engine: NewRouter(c).Setup()}
+}
+
+// do 发起请求并解析统一响应结构，data 不为 nil 时解析到 data
+func (e *testEnv) do(req *http.Request, data any) (*httptest.ResponseRecorder, schema.Response) {
+	e.t.Helper()
+	w := httptest.NewRecorder()
+	e.engine.ServeHTTP(w, req)
+
+	var resp schema.Response
+	if data != nil {
+		resp.Data = data
+	}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			e.t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+		}
+	}
+	return w, resp
+}
+
+func (e *testEnv) postJSON(path, body string, data any) (*httptest.ResponseRecorder, schema.Response) {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return e.do(req, data)
+}
+
+func (e *testEnv) uploadImage(name string, data any) (*httptest.ResponseRecorder, schema.Response) {
+	return e.uploadImages([]string{name}, data)
+}
+
+// uploadImages 按顺序上传多张图片，每张内容不同
+func (e *testEnv) uploadImages(names []string, data any) (*httptest.ResponseRecorder, schema.Response) {
+	contents := make([][]byte, len(names))
+	for i := range names {
+		contents[i] = fmt.Appendf(nil, "\x89PNG fake image content %d", i)
+	}
+	return e.uploadImageData("/api/analyze/image", names, contents, data)
+}
+
+// uploadImageData 向 path 上传指定内容的图片
+func (e *testEnv) uploadImageData(path string, names []string, contents [][]byte, data any) (*httptest.ResponseRecorder, schema.Response) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for i, name := range names {
+		part, _ := mw.CreateFormFile("image", name)
+		part.Write(contents[i])
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return e.do(req, data)
+}
+
+// uploadPDF 上传 PDF，pages 为空时不传页码范围
+func (e *testEnv) uploadPDF(name string, content []byte, pages string, data any) (*httptest.ResponseRecorder, schema.Response) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", name)
+	part.Write(content)
+	if pages != "" {
+		mw.WriteField("pages", pages)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze/pdf?lang=en", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return e.do(req, data)
+}
+
+// analysisReply 满足校验要求的分析结果
+func analysisReply() string {
+	return analysisReplyWith(schema.KnowledgePoint{ID: "kp_1", Title: "勾股定理", Category: "数学"})
+}
+
+// analysisReplyWith 包含指定重点知识点的分析结果
+func analysisReplyWith(keyPoints ...schema.KnowledgePoint) string {
+	points := func(prefix string) []schema.KnowledgePoint {
+		list := make([]schema.KnowledgePoint, 5)
+		for i := range list {
+			list[i] = schema.KnowledgePoint{ID: prefix + string(rune('1'+i)), Title: prefix + string(rune('1'+i))}
+		}
+		return list
+	}
+	return "```json\n" + aitest.JSONContent(schema.KnowledgeAnalysisResponse{
+		DetailedExplanation: "直角三角形两直角边的平方和等于斜边的平方。",
+		Prerequisites:       points("pre"),
+		KeyPoints:           keyPoints,
+		Postrequisites:      points("post"),
+		Conclusion:          "掌握勾股定理。",
+	}) + "\n```"
+}
+
+func TestHealth(t *testing.T) {
+	env := newTestEnv(t)
+	w, _ := env.do(httptest.NewRequest(http.MethodGet, "/health", nil), nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /health = %d", w.Code)
+	}
+}
+
+func TestAnalyzeImageAndFeedback(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()})
+
+	var result schema.KnowledgeAnalysisResponse
+	w, resp := env.uploadImage("triangle.png", &result)
+	if w.Code != http.StatusOK || resp.Code != 0 {
+		t.Fatalf("analyze = %d %s", w.Code, w.Body.String())
+	}
+	if result.ID == "" || len(result.KeyPoints) != 1 || !strings.HasPrefix(result.PromptVersion, "analysis/zh-CN@") {
+		t.Errorf("unexpected analysis: %+v", result)
+	}
+
+	// 请求中带有系统提示词和 base64 图片
+	req := env.fake.LastRequest()
+	if req.Model != "test-model" || len(req.Messages) != 2 {
+		t.Fatalf("unexpected upstream request: %+v", req)
+	}
+	if parts, _ := json.Marshal(req.Messages[1].Content); !strings.Contains(string(parts), "data:image/jpeg;base64,") {
+		t.Errorf("upstream request has no image: %s", parts)
+	}
+
+	w, _ = env.postJSON("/api/analyses/"+result.ID+"/feedback", `{"rating":"down","reasons":["incorrect"],"keyPointId":"kp_1"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("feedback = %d %s", w.Code, w.Body.String())
+	}
+	w, _ = env.postJSON("/api/analyses/"+result.ID+"/feedback", `{"rating":"down","keyPointId":"kp_404"}`, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("feedback with unknown key point = %d, want 400", w.Code)
+	}
+}
+
+func TestKeyPointCrop(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.Enqueue(aitest.Reply{Content: analysisReplyWith(
+		schema.KnowledgePoint{ID: "kp-001", Title: "勾股定理", Sources: []schema.Source{
+			{Page: 1, BBox: &schema.BoundingBox{X: 0.25, Y: 0.5, Width: 0.5, Height: 0.25}, Text: " a² + b² = c² "},
+		}},
+		schema.KnowledgePoint{ID: "kp-002", Title: "直角三角形", Sources: []schema.Source{{Page: 1}}},
+	)})
+
+	var page bytes.Buffer
+	png.Encode(&page, image.NewGray(image.Rect(0, 0, 200, 100)))
+	var result schema.KnowledgeAnalysisResponse
+	w, _ := env.uploadImageData("/api/analyze/image", []string{"triangle.png"}, [][]byte{page.Bytes()}, &result)
+	if w.Code != http.StatusOK {
+		t.Fatalf("analyze = %d %s", w.Code, w.Body.String())
+	}
+	if src := result.KeyPoints[0].Sources; len(src) != 1 || src[0].BBox == nil || src[0].Text != "a² + b² = c²" {
+		t.Fatalf("kp-001 sources = %+v", src)
+	}
+
+	w, _ = env.do(httptest.NewRequest(http.MethodGet, "/api/analyses/"+result.ID+"/key-points/kp-001/crop", nil), nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("crop = %d %s", w.Code, w.Body.String())
+	}
+	crop, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatalf("decode crop: %v", err)
+	}
+	// 区域 100x25，四周各外扩 2%
+	if size := crop.Bounds().Size(); size.X != 108 || size.Y != 29 {
+		t.Errorf("crop size = %v, want 108x29", size)
+	}
+
+	for path, want := range map[string]string{
+		"/api/analyses/missing/key-points/kp-001/crop":                    "NOT_FOUND",
+		"/api/analyses/" + result.ID + "/key-points/kp-404/crop":          "NOT_FOUND",
+		"/api/analyses/" + result.ID + "/key-points/kp-002/crop":          "NOT_FOUND",
+		"/api/analyses/" + result.ID + "/key-points/kp-001/crop?source=x": "INVALID_PARAMS",
+	} {
+		w, resp := env.do(httptest.NewRequest(http.MethodGet, path, nil), nil)
+		if resp.Error == nil || resp.Error.Reason != want {
+			t.Errorf("GET %s = %d %s, want %s", path, w.Code, w.Body.String(), want)
+		}
+	}
+}
+
+func TestCheckHomework(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.Enqueue(aitest.Reply{Content: "```json\n" + aitest.JSONContent(map[string]any{
+		"problem": "解方程 x^2 - 5x + 6 = 0",
+		"steps": []map[string]any{
+			{"index": 1, "content": "$x^2 - 5x + 6 = 0$", "correct": true},
+			{"index": 2, "content": "  ", "correct": true},
+			{"index": 3, "content": "$(x - 2)(x + 3) = 0$", "correct": false, "comment": "因式分解错误"},
+			{"index": 4, "content": "$x = 2$ 或 $x = -3$", "correct": true},
+		},
+		"correct":        true,
+		"firstErrorStep": 3,
+		"mistake": map[string]any{
+			"type":         " Arithmetic",
+			"description":  "-2 与 +3 相加得 +1",
+			"prerequisite": map[string]any{"id": "x", "title": "十字相乘法", "category": "数学"},
+		},
+		"correction": "$(x - 2)(x - 3) = 0$，所以 $x = 2$ 或 $x = 3$",
+	}) + "\n```"})
+
+	names, contents := []string{"homework.jpg"}, [][]byte{[]byte("\xff\xd8 handwritten")}
+	var result schema.HomeworkCheckResponse
+	w, _ := env.uploadImageData("/api/analyze/image?mode=check", names, contents, &result)
+	if w.Code != http.StatusOK {
+		t.Fatalf("check = %d %s", w.Code, w.Body.String())
+	}
+	// 空步骤被去掉后重新编号，correct 以第一个错误步骤为准
+	if result.Mode != "check" || result.ID == "" || len(result.Steps) != 3 || result.Correct || result.FirstErrorStep != 2 {
+		t.Fatalf("unexpected check result: %+v", result)
+	}
+	if m := result.Mistake; m == nil || m.Type != "arithmetic" || m.Prerequisite == nil || m.Prerequisite.ID != "kp-p001" {
+		t.Errorf("mistake = %+v", m)
+	}
+	if system := aitest.ContentText(env.fake.LastRequest().Messages[0]); !strings.Contains(system, "批改") {
+		t.Errorf("check prompt not used: %s", system)
+	}
+
+	// 模型给出的 firstErrorStep 晚于已标记为错误的步骤时，以更早的错误步骤为准
+	env.fake.Enqueue(aitest.Reply{Content: aitest.JSONContent(map[string]any{
+		"steps": []map[string]any{
+			{"index": 1, "content": "$2x + 3 = 7$", "correct": true},
+			{"index": 2, "content": "$2x = 10$", "correct": false},
+			{"index": 3, "content": "$x = 4$", "correct": false},
+		},
+		"firstErrorStep": 3,
+		"mistake":        map[string]any{"type": "arithmetic", "description": "移项时没有变号"},
+	})})
+	result = schema.HomeworkCheckResponse{}
+	if w, _ := env.uploadImageData("/api/analyze/image?mode=check", names, contents, &result); w.Code != http.StatusOK || result.Correct || result.FirstErrorStep != 2 {
+		t.Errorf("check with earlier marked error = %d %s, want firstErrorStep 2", w.Code, w.Body.String())
+	}
+
+	env.fake.Enqueue(aitest.Reply{Content: `{"steps":[{"index":1,"content":"$1 + 1 = 3$","correct":false}],"firstErrorStep":1,"mistake":{"type":"typo"}}`})
+	if w, resp := env.uploadImageData("/api/analyze/image?mode=check", names, contents, nil); resp.Error == nil || resp.Error.Reason != "AI_RESPONSE_INVALID" {
+		t.Errorf("invalid mistake type = %d %s, want AI_RESPONSE_INVALID", w.Code, w.Body.String())
+	}
+	if w, resp := env.uploadImageData("/api/analyze/image?mode=grade", names, contents, nil); resp.Error == nil || resp.Error.Reason != "INVALID_PARAMS" {
+		t.Errorf("unknown mode = %d %s, want INVALID_PARAMS", w.Code, w.Body.String())
+	}
+}
+
+func TestAnalyzeMultiplePages(t *testing.T) {
+	env := newTestEnv(t)
+	// 默认每次请求最多 4 张图片，5 页分两批；两批并发，按图片数区分回复
+	env.fake.Handle(func(req *schema.ChatRequest) aitest.Reply {
+		parts, _ := json.Marshal(req.Messages[1].Content)
+		if strings.Count(string(parts), `"type":"image_url"`) == 4 {
+			return aitest.Reply{Content: analysisReplyWith(
+				schema.KnowledgePoint{ID: "kp-001", Title: "勾股定理", Sources: []schema.Source{{Page: 2}, {Page: 9}}},
+			)}
+		}
+		return aitest.Reply{Content: analysisReplyWith(
+			schema.KnowledgePoint{ID: "kp-001", Title: "勾股定理 "},
+			schema.KnowledgePoint{ID: "kp-002", Title: "余弦定理"},
+		)}
+	})
+
+	var result schema.KnowledgeAnalysisResponse
+	w, _ := env.uploadImages([]string{"p1.png", "p2.png", "p3.png", "p4.png", "p5.jpg"}, &result)
+	if w.Code != http.StatusOK {
+		t.Fatalf("analyze = %d %s", w.Code, w.Body.String())
+	}
+	if n := len(env.fake.Requests()); n != 2 {
+		t.Fatalf("upstream requests = %d, want 2", n)
+	}
+	if result.Pages != 5 || len(result.KeyPoints) != 2 {
+		t.Fatalf("unexpected merged analysis: %+v", result)
+	}
+	pages := func(kp schema.KnowledgePoint) []int {
+		var out []int
+		for _, s := range kp.Sources {
+			out = append(out, s.Page)
+		}
+		return out
+	}
+	if got := pages(result.KeyPoints[0]); !reflect.DeepEqual(got, []int{2, 5}) {
+		t.Errorf("%s sources = %v, want [2 5]", result.KeyPoints[0].Title, got)
+	}
+	if got := pages(result.KeyPoints[1]); result.KeyPoints[1].ID != "kp-002" || !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("%s %s sources = %v, want kp-002 [5]", result.KeyPoints[1].ID, result.KeyPoints[1].Title, got)
+	}
+	if len(result.Prerequisites) != 5 || len(result.Postrequisites) != 5 {
+		t.Errorf("prerequisites/postrequisites = %d/%d, want 5/5", len(result.Prerequisites), len(result.Postrequisites))
+	}
+}
+
+func TestAnalyzeMultiplePagesKeepsEveryBatch(t *testing.T) {
+	env := newTestEnv(t)
+	// 5 页分两批，每批 3 个重点知识点：合并后 6 个都保留，不按标准深度的 5 个截掉第二批的知识点
+	env.fake.Handle(func(req *schema.ChatRequest) aitest.Reply {
+		parts, _ := json.Marshal(req.Messages[1].Content)
+		if strings.Count(string(parts), `"type":"image_url"`) == 4 {
+			return aitest.Reply{Content: analysisReplyWith(
+				schema.KnowledgePoint{ID: "kp-001", Title: "勾股定理", Sources: []schema.Source{{Page: 1}}},
+				schema.KnowledgePoint{ID: "kp-002", Title: "余弦定理", Sources: []schema.Source{{Page: 2}}},
+				schema.KnowledgePoint{ID: "kp-003", Title: "正弦定理", Sources: []schema.Source{{Page: 3}}},
+			)}
+		}
+		return aitest.Reply{Content: analysisReplyWith(
+			schema.KnowledgePoint{ID: "kp-001", Title: "海伦公式"},
+			schema.KnowledgePoint{ID: "kp-002", Title: "三角形面积"},
+			schema.KnowledgePoint{ID: "kp-003", Title: "中线定理"},
+		)}
+	})
+
+	var result schema.KnowledgeAnalysisResponse
+	w, _ := env.uploadImages([]string{"p1.png", "p2.png", "p3.png", "p4.png", "p5.jpg"}, &result)
+	if w.Code != http.StatusOK {
+		t.Fatalf("analyze = %d %s", w.Code, w.Body.String())
+	}
+	var titles []string
+	for _, kp := range result.KeyPoints {
+		titles = append(titles, kp.Title)
+	}
+	if want := []string{"勾股定理", "余弦定理", "正弦定理", "海伦公式", "三角形面积", "中线定理"}; !reflect.DeepEqual(titles, want) {
+		t.Fatalf("key points = %v, want %v", titles, want)
+	}
+	if last := result.KeyPoints[len(result.KeyPoints)-1]; len(last.Sources) != 1 || last.Sources[0].Page != 5 {
+		t.Errorf("%s sources = %+v, want page 5", last.Title, last.Sources)
+	}
+}
+
+func TestAnalyzeMultiplePagesCancelsOnFailure(t *testing.T) {
+	env := newTestEnv(t)
+	// 5 页分两批：4 页的批次很慢，另一批立即返回无法解析的结果，慢的批次应被取消
+	env.fake.Handle(func(req *schema.ChatRequest) aitest.Reply {
+		parts, _ := json.Marshal(req.Messages[1].Content)
+		if strings.Count(string(parts), `"type":"image_url"`) == 4 {
+			return aitest.Reply{Content: analysisReply(), Latency: 10 * time.Second}
+		}
+		return aitest.Reply{Content: "not json"}
+	})
+
+	start := time.Now()
+	w, resp := env.uploadImages([]string{"p1.png", "p2.png", "p3.png", "p4.png", "p5.jpg"}, nil)
+	if w.Code == http.StatusOK || resp.Error == nil || resp.Error.Reason != "AI_RESPONSE_INVALID" {
+		t.Fatalf("analyze = %d %s, want the failed batch's error", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("analyze took %s, the slow batch was not cancelled", elapsed)
+	}
+}
+
+func TestAnalyzeTooManyImages(t *testing.T) {
+	env := newTestEnv(t)
+	names := make([]string, 9)
+	for i := range names {
+		names[i] = fmt.Sprintf("p%d.png", i+1)
+	}
+	w, resp := env.uploadImages(names, nil)
+	if w.Code != http.StatusBadRequest || resp.Error == nil || resp.Error.Reason != "INVALID_PARAMS" {
+		t.Errorf("9 images = %d %s, want 400", w.Code, w.Body.String())
+	}
+}
+
+func TestAnalyzePDF(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.Enqueue(aitest.Reply{Content: analysisReplyWith(
+		schema.KnowledgePoint{ID: "kp-001", Title: "Pythagorean theorem", Sources: []schema.Source{{Page: 3}, {Page: 2}}},
+	)})
+	doc := pdftest.Build(
+		"Cover page of the geometry lecture notes",
+		"The Pythagorean theorem: a^2 + b^2 = c^2 for right triangles",
+		"",
+		"Proof by rearranging four congruent triangles",
+	)
+
+	var result schema.KnowledgeAnalysisResponse
+	w, _ := env.uploadPDF("lecture.pdf", doc, "2-", &result)
+	if w.Code != http.StatusOK {
+		t.Fatalf("analyze pdf = %d %s", w.Code, w.Body.String())
+	}
+
+	// 有文字层的页面以文字发送并标注页码，没有文字层的第 3 页因未配置渲染而跳过
+	content, _ := json.Marshal(env.fake.LastRequest().Messages[1].Content)
+	for _, want := range []string{"Page 2", "a^2 + b^2 = c^2", "Page 4", "four congruent triangles"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("upstream content %s does not contain %q", content, want)
+		}
+	}
+	if strings.Contains(string(content), "Cover page") || strings.Contains(string(content), "image_url") {
+		t.Errorf("unexpected upstream content %s", content)
+	}
+	if !reflect.DeepEqual(result.SkippedPages, []int{3}) || result.Pages != 2 {
+		t.Errorf("skippedPages = %v, pages = %d", result.SkippedPages, result.Pages)
+	}
+	// 模型给出的页码只保留实际发送的页面
+	if got := result.KeyPoints[0].Sources; !reflect.DeepEqual(got, []schema.Source{{Page: 2}}) {
+		t.Errorf("sources = %v, want [{2}]", got)
+	}
+}
+
+func TestAnalyzePDFErrors(t *testing.T) {
+	env := newTestEnv(t)
+	doc := pdftest.Build("A single page with enough text to be extracted", "")
+
+	tests := []struct {
+		name, file string
+		content    []byte
+		pages      string
+		status     int
+		reason     string
+	}{
+		{"not a pdf extension", "notes.txt", doc, "", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"corrupted", "broken.pdf", []byte("%PDF-1.4 broken"), "", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"page out of range", "notes.pdf", doc, "1-5", http.StatusBadRequest, "INVALID_PARAMS"},
+		{"no extractable content", "notes.pdf", doc, "2", http.StatusBadRequest, "INVALID_PARAMS"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w, resp := env.uploadPDF(tc.file, tc.content, tc.pages, nil)
+			if w.Code != tc.status || resp.Error == nil || resp.Error.Reason != tc.reason {
+				t.Errorf("got %d %s, want %d %s", w.Code, w.Body.String(), tc.status, tc.reason)
+			}
+		})
+	}
+	if n := len(env.fake.Requests()); n != 0 {
+		t.Errorf("upstream requests = %d, want 0", n)
+	}
+}
+
+func TestAnalyzeURL(t *testing.T) {
+	env := newTestEnv(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/notes.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>Geometry</title><script>alert(1)</script></head><body><p>Pythagorean theorem: a^2 + b^2 = c^2</p></body></html>`)
+	})
+	mux.HandleFunc("/slides.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdftest.Build("Slide one introduces right triangles", "Slide two proves the Pythagorean theorem"))
+	})
+	mux.HandleFunc("/board.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "\x89PNG fake image content")
+	})
+	mux.HandleFunc("/huge.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(bytes.Repeat([]byte{0}, service.MaxImageSizeMB*1024*1024+1))
+	})
+	mux.HandleFunc("/archive.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		fmt.Fprint(w, "PK")
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	upstream := func() string {
+		content, _ := json.Marshal(env.fake.LastRequest().Messages[1].Content)
+		return string(content)
+	}
+
+	// 网页只发送正文文字
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()})
+	var result schema.KnowledgeAnalysisResponse
+	w, _ := env.postJSON("/api/analyze/url", `{"url":"`+site.URL+`/notes.html"}`, &result)
+	if w.Code != http.StatusOK || len(result.KeyPoints) != 1 {
+		t.Fatalf("analyze html = %d %s", w.Code, w.Body.String())
+	}
+	if content := upstream(); !strings.Contains(content, "a^2 + b^2 = c^2") || strings.Contains(content, "alert") || strings.Contains(content, "image_url") {
+		t.Errorf("upstream content for html = %s", content)
+	}
+
+	// PDF 按页码范围提取
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()})
+	w, _ = env.postJSON("/api/analyze/url", `{"url":"`+site.URL+`/slides.pdf","pages":"2"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("analyze pdf url = %d %s", w.Code, w.Body.String())
+	}
+	if content := upstream(); !strings.Contains(content, "Slide two") || strings.Contains(content, "Slide one") {
+		t.Errorf("upstream content for pdf = %s", content)
+	}
+
+	// 图片按图片分析
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()})
+	w, _ = env.postJSON("/api/analyze/url", `{"url":"`+site.URL+`/board.png"}`, nil)
+	if w.Code != http.StatusOK || !strings.Contains(upstream(), "image_url") {
+		t.Fatalf("analyze image url = %d %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name, body string
+		status     int
+		reason     string
+	}{
+		{"unsupported type", `{"url":"` + site.URL + `/archive.zip"}`, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"image too large", `{"url":"` + site.URL + `/huge.png"}`, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE"},
+		{"not found", `{"url":"` + site.URL + `/missing"}`, http.StatusBadGateway, "SOURCE_FETCH_FAILED"},
+		{"invalid scheme", `{"url":"file:///etc/passwd"}`, http.StatusBadRequest, "INVALID_PARAMS"},
+		{"missing url", `{"pages":"1"}`, http.StatusBadRequest, "INVALID_PARAMS"},
+	}
+	requests := len(env.fake.Requests())
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w, resp := env.postJSON("/api/analyze/url", tc.body, nil)
+			if w.Code != tc.status || resp.Error == nil || resp.Error.Reason != tc.reason {
+				t.Errorf("got %d %s, want %d %s", w.Code, w.Body.String(), tc.status, tc.reason)
+			}
+		})
+	}
+	if n := len(env.fake.Requests()); n != requests {
+		t.Errorf("failed fetches sent %d upstream requests", n-requests)
+	}
+}
+
+func TestAnalyzeText(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()})
+
+	var result schema.KnowledgeAnalysisResponse
+	w, _ := env.postJSON("/api/analyze/text", `{"text":"## 勾股定理\n\n直角三角形两直角边的平方和等于斜边的平方。"}`, &result)
+	if w.Code != http.StatusOK || len(result.KeyPoints) != 1 || result.ID == "" {
+		t.Fatalf("analyze text = %d %s", w.Code, w.Body.String())
+	}
+	content, _ := json.Marshal(env.fake.LastRequest().Messages[1].Content)
+	if !strings.Contains(string(content), "## 勾股定理") || strings.Contains(string(content), "image_url") {
+		t.Errorf("upstream content = %s", content)
+	}
+
+	for _, body := range []string{`{}`, `{"text":"  \n "}`, `{"text":` + fmt.Sprintf("%q", strings.Repeat("长", 50001)) + `}`} {
+		w, resp := env.postJSON("/api/analyze/text", body, nil)
+		if w.Code != http.StatusBadRequest || resp.Error == nil || resp.Error.Reason != "INVALID_PARAMS" {
+			t.Errorf("analyze text %.40s = %d %s, want 400", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAnalyzeDepth(t *testing.T) {
+	env := newTestEnv(t)
+	const text = `{"text":"直角三角形两直角边的平方和等于斜边的平方。"}`
+
+	// 多出的前置、后置知识点被裁剪到要求的数量
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()})
+	var result schema.KnowledgeAnalysisResponse
+	w, _ := env.postJSON("/api/analyze/text?depth=quick&prerequisites=4", text, &result)
+	if w.Code != http.StatusOK || len(result.Prerequisites) != 4 || len(result.Postrequisites) != 3 {
+		t.Fatalf("quick analysis = %d %s", w.Code, w.Body.String())
+	}
+	if system := aitest.ContentText(env.fake.LastRequest().Messages[0]); !strings.Contains(system, "生成4个前置知识点") {
+		t.Errorf("system prompt = %s", system)
+	}
+
+	// 不足时补充一次，重复的不计入
+	env.fake.Enqueue(
+		aitest.Reply{Content: analysisReply()},
+		aitest.Reply{Content: aitest.JSONContent(map[string]any{
+			"prerequisites": []schema.KnowledgePoint{{ID: "kp-p001", Title: "pre1"}, {ID: "kp-p002", Title: "平方"}, {ID: "kp-p003", Title: "直角"}},
+		})},
+	)
+	result = schema.KnowledgeAnalysisResponse{}
+	w, _ = env.postJSON("/api/analyze/text?prerequisites=7", text, &result)
+	if w.Code != http.StatusOK || len(result.Prerequisites) != 7 || len(result.Postrequisites) != 5 {
+		t.Fatalf("top-up analysis = %d %s", w.Code, w.Body.String())
+	}
+	if last := result.Prerequisites[6]; last.ID != "kp-p007" || last.Title != "直角" {
+		t.Errorf("last prerequisite = %+v", last)
+	}
+	if user := aitest.ContentText(env.fake.LastRequest().Messages[1]); !strings.Contains(user, "- 勾股定理") || !strings.Contains(user, "- pre1") {
+		t.Errorf("top-up prompt = %s", user)
+	}
+
+	// 补充失败时保留已有的知识点
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()}, aitest.Reply{Content: "抱歉"})
+	result = schema.KnowledgeAnalysisResponse{}
+	w, _ = env.postJSON("/api/analyze/text?depth=deep", text, &result)
+	if w.Code != http.StatusOK || len(result.Prerequisites) != 5 || len(result.Postrequisites) != 5 {
+		t.Fatalf("failed top-up = %d %s", w.Code, w.Body.String())
+	}
+
+	for _, query := range []string{"depth=huge", "keyPoints=11", "funExamples=0x", "prerequisites=-1"} {
+		w, resp := env.postJSON("/api/analyze/text?"+query, text, nil)
+		if w.Code != http.StatusBadRequest || resp.Error == nil || resp.Error.Reason != "INVALID_PARAMS" {
+			t.Errorf("analyze text ?%s = %d %s, want 400", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAnalyzeCacheSkipsShortResults(t *testing.T) {
+	env := newTestEnv(t, func(cfg *global.AppConfig) {
+		cfg.Cache = global.CacheConfig{Enabled: true, Size: 10}
+	})
+	const path, text = "/api/analyze/text?prerequisites=7", `{"text":"直角三角形两直角边的平方和等于斜边的平方。"}`
+
+	// 补充失败的结果只有 5 个前置知识点，不缓存；相同的请求再次分析并补充
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()}, aitest.Reply{Content: "抱歉"})
+	var result schema.KnowledgeAnalysisResponse
+	if w, _ := env.postJSON(path, text, &result); w.Code != http.StatusOK || len(result.Prerequisites) != 5 {
+		t.Fatalf("failed top-up = %d %s", w.Code, w.Body.String())
+	}
+	env.fake.Enqueue(
+		aitest.Reply{Content: analysisReply()},
+		aitest.Reply{Content: aitest.JSONContent(map[string]any{
+			"prerequisites": []schema.KnowledgePoint{{ID: "kp-p001", Title: "平方"}, {ID: "kp-p002", Title: "直角"}},
+		})},
+	)
+	requests := len(env.fake.Requests())
+	result = schema.KnowledgeAnalysisResponse{}
+	if w, _ := env.postJSON(path, text, &result); w.Code != http.StatusOK || len(result.Prerequisites) != 7 {
+		t.Fatalf("retry = %d %s", w.Code, w.Body.String())
+	}
+	if n := len(env.fake.Requests()) - requests; n != 2 {
+		t.Errorf("retry sent %d upstream requests, want 2", n)
+	}
+
+	// 数量足够的结果被缓存
+	requests = len(env.fake.Requests())
+	result = schema.KnowledgeAnalysisResponse{}
+	if w, _ := env.postJSON(path, text, &result); w.Code != http.StatusOK || len(result.Prerequisites) != 7 {
+		t.Fatalf("cached = %d %s", w.Code, w.Body.String())
+	}
+	if n := len(env.fake.Requests()) - requests; n != 0 {
+		t.Errorf("cached result sent %d upstream requests", n)
+	}
+}
+
+// TestTracing 服务端 span、上传读取和模型调用的 span 及其属性，trace-context 透传到上游，解析失败记录在 span 上
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	if _, err := telemetry.InitTracer(global.TracingConfig{}); err != nil {
+		t.Fatalf("InitTracer: %v", err)
+	}
+
+	env := newTestEnv(t)
+	env.fake.Enqueue(aitest.Reply{Content: "不是 JSON", Usage: &schema.Usage{PromptTokens: 120, CompletionTokens: 8, TotalTokens: 128}})
+	if w, _ := env.uploadImage("triangle.png", nil); w.Code != http.StatusBadGateway {
+		t.Fatalf("analyze = %d %s, want 502", w.Code, w.Body.String())
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	attr := func(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+		for _, kv := range span.Attributes() {
+			if string(kv.Key) == key {
+				return kv.Value
+			}
+		}
+		return attribute.Value{}
+	}
+	for _, name := range []string{"POST /api/analyze/image", "ImageController.readFormFile", "AIService.Chat", "ImageAnalysisService.parseAIResponse"} {
+		if spans[name] == nil {
+			t.Fatalf("span %q not recorded, got %v", name, recorder.Ended())
+		}
+	}
+
+	server := spans["POST /api/analyze/image"]
+	if server.SpanKind() != trace.SpanKindServer || attr(server, "http.response.status_code").AsInt64() != http.StatusBadGateway || server.Status().Code != codes.Error {
+		t.Errorf("server span = kind %v status %v attrs %v", server.SpanKind(), server.Status(), server.Attributes())
+	}
+	if read := spans["ImageController.readFormFile"]; attr(read, "file.size").AsInt64() == 0 {
+		t.Errorf("readFormFile attrs = %v", read.Attributes())
+	}
+
+	chat := spans["AIService.Chat"]
+	if chat.Parent().TraceID() != server.SpanContext().TraceID() {
+		t.Error("AIService.Chat is not in the request trace")
+	}
+	if attr(chat, "gen_ai.request.model").AsString() != "test-model" ||
+		attr(chat, "gen_ai.usage.input_tokens").AsInt64() != 120 ||
+		attr(chat, "gen_ai.usage.output_tokens").AsInt64() != 8 ||
+		attr(chat, "http.response.status_code").AsInt64() != http.StatusOK {
+		t.Errorf("AIService.Chat attrs = %v", chat.Attributes())
+	}
+
+	// 上游收到的 traceparent 以 AIService.Chat span 为父 span
+	want := fmt.Sprintf("00-%s-%s-01", chat.SpanContext().TraceID(), chat.SpanContext().SpanID())
+	if got := env.fake.LastHeader().Get("traceparent"); got != want {
+		t.Errorf("upstream traceparent = %q, want %q", got, want)
+	}
+
+	if parse := spans["ImageAnalysisService.parseAIResponse"]; parse.Status().Code != codes.Error || len(parse.Events()) == 0 {
+		t.Errorf("parseAIResponse span = status %v events %v, want recorded error", parse.Status(), parse.Events())
+	}
+}
+
+func TestAnalyzeImageUpstreamFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      aitest.Reply
+		wantStatus int
+		wantReason string
+	}{
+		{"malformed upstream JSON", aitest.Reply{Malformed: true}, http.StatusBadGateway, "AI_RESPONSE_INVALID"},
+		{"model output is not JSON", aitest.Reply{Content: "抱歉，我无法识别"}, http.StatusBadGateway, "AI_RESPONSE_INVALID"},
+		{"rate limited", aitest.Reply{Status: http.StatusTooManyRequests}, http.StatusServiceUnavailable, "AI_SERVICE_BUSY"},
+		{"upstream error", aitest.Reply{Status: http.StatusInternalServerError}, http.StatusBadGateway, "AI_SERVICE_ERROR"},
+	}
+	env := newTestEnv(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.fake.Enqueue(tt.reply)
+			w, resp := env.uploadImage("triangle.png", nil)
+			if w.Code != tt.wantStatus || resp.Error == nil || resp.Error.Reason != tt.wantReason {
+				t.Errorf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestAnalyzeImageRejectsUnsupportedFormat(t *testing.T) {
+	env := newTestEnv(t)
+	w, _ := env.uploadImage("notes.txt", nil)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("upload .txt = %d, want 415", w.Code)
+	}
+	if n := len(env.fake.Requests()); n != 0 {
+		t.Errorf("model was called %d times for an invalid upload", n)
+	}
+}
+
+func TestDialogueAndMessageFeedback(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.Enqueue(aitest.Reply{Content: "Think about the longest side first."})
+
+	var reply schema.DialogueResponse
+	req := httptest.NewRequest(http.MethodPost, "/api/knowledge-points/kp_1/dialogue?lang=en",
+		strings.NewReader(`{"message":"Why a²+b²=c²?","knowledgePointTitle":"勾股定理","knowledgePointDesc":"直角三角形三边关系"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w, _ := env.do(req, &reply)
+	if w.Code != http.StatusOK {
+		t.Fatalf("dialogue = %d %s", w.Code, w.Body.String())
+	}
+	if reply.Message != "Think about the longest side first." || reply.Language != "en" || reply.ConversationID == "" {
+		t.Errorf("unexpected reply: %+v", reply)
+	}
+	if last := env.fake.LastRequest().Messages; aitest.ContentText(last[len(last)-1]) != "Why a²+b²=c²?" {
+		t.Errorf("user message not forwarded: %+v", last)
+	}
+
+	path := "/api/conversations/" + reply.ConversationID + "/messages/" + reply.MessageID + "/feedback"
+	if w, _ := env.postJSON(path, `{"rating":"up"}`, nil); w.Code != http.StatusOK {
+		t.Errorf("message feedback = %d %s", w.Code, w.Body.String())
+	}
+	if w, _ := env.postJSON("/api/conversations/other/messages/"+reply.MessageID+"/feedback", `{"rating":"up"}`, nil); w.Code != http.StatusNotFound {
+		t.Errorf("feedback with wrong conversation = %d, want 404", w.Code)
+	}
+}
+
+func TestDialogueWithImageContext(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.Enqueue(aitest.Reply{Content: analysisReplyWith(schema.KnowledgePoint{ID: "kp-001", Title: "勾股定理", Sources: []schema.Source{
+		{Page: 1, BBox: &schema.BoundingBox{X: 0.25, Y: 0.5, Width: 0.5, Height: 0.25}},
+	}})})
+	var page bytes.Buffer
+	png.Encode(&page, image.NewGray(image.Rect(0, 0, 200, 100)))
+	var result schema.KnowledgeAnalysisResponse
+	if w, _ := env.uploadImageData("/api/analyze/image", []string{"triangle.png"}, [][]byte{page.Bytes()}, &result); w.Code != http.StatusOK {
+		t.Fatalf("analyze = %d %s", w.Code, w.Body.String())
+	}
+
+	// 第二轮对话：原图附在历史中的第一条用户消息上
+	env.fake.Enqueue(aitest.Reply{Content: "箭头指向斜边。"})
+	body := `{"message":"图中的箭头是什么意思？","knowledgePointTitle":"勾股定理","knowledgePointDesc":"直角三角形三边关系",` +
+		`"conversationHistory":[{"id":"m1","sender":"user","content":"讲讲这个定理","timestamp":"2024-01-01T00:00:00Z"},{"id":"m2","sender":"ai","content":"好的","timestamp":"2024-01-01T00:00:01Z"}],` +
+		`"analysisId":"` + result.ID + `","imageContext":"crop"}`
+	var reply schema.DialogueResponse
+	if w, _ := env.postJSON("/api/knowledge-points/kp-001/dialogue", body, &reply); w.Code != http.StatusOK || reply.ImageContext != "crop" {
+		t.Fatalf("dialogue = %d %s", w.Code, w.Body.String())
+	}
+	messages := env.fake.LastRequest().Messages
+	if first, _ := json.Marshal(messages[1].Content); !strings.Contains(string(first), "data:image/png;base64,") || !strings.Contains(string(first), "讲讲这个定理") {
+		t.Errorf("first user message = %s, want text and image", first)
+	}
+	if _, ok := messages[len(messages)-1].Content.(string); !ok {
+		t.Errorf("current message should stay text-only: %+v", messages[len(messages)-1])
+	}
+	if system := aitest.ContentText(messages[0]); !strings.Contains(system, "原图") {
+		t.Errorf("system prompt does not mention the image: %s", system)
+	}
+
+	// 后续轮次省略 imageContext 和 analysisId 时沿用会话的设置，传入 none 关闭
+	env.fake.Enqueue(aitest.Reply{Content: "斜边最长。"})
+	body = `{"message":"哪条边最长？","knowledgePointTitle":"勾股定理","knowledgePointDesc":"直角三角形三边关系","conversationId":"` + reply.ConversationID + `"}`
+	if w, _ := env.postJSON("/api/knowledge-points/kp-001/dialogue", body, &reply); w.Code != http.StatusOK || reply.ImageContext != "crop" {
+		t.Fatalf("later turn = %d %s, want imageContext kept", w.Code, w.Body.String())
+	}
+	if first, _ := json.Marshal(env.fake.LastRequest().Messages[1].Content); !strings.Contains(string(first), "data:image/png;base64,") {
+		t.Errorf("later turn lost the image: %s", first)
+	}
+	env.fake.Enqueue(aitest.Reply{Content: "好的。"})
+	body = `{"message":"不用图了","knowledgePointTitle":"勾股定理","knowledgePointDesc":"直角三角形三边关系","conversationId":"` + reply.ConversationID + `","imageContext":"none"}`
+	reply = schema.DialogueResponse{}
+	if w, _ := env.postJSON("/api/knowledge-points/kp-001/dialogue", body, &reply); w.Code != http.StatusOK || reply.ImageContext != "" {
+		t.Fatalf("image turned off = %d %s", w.Code, w.Body.String())
+	}
+	if first, _ := json.Marshal(env.fake.LastRequest().Messages[1].Content); strings.Contains(string(first), "data:image/png;base64,") {
+		t.Errorf("image still attached after none: %s", first)
+	}
+
+	for body, want := range map[string]string{
+		`{"message":"m","knowledgePointTitle":"t","knowledgePointDesc":"d","imageContext":"page"}`:                        "INVALID_PARAMS",
+		`{"message":"m","knowledgePointTitle":"t","knowledgePointDesc":"d","imageContext":"full","analysisId":"x"}`:       "INVALID_PARAMS",
+		`{"message":"m","knowledgePointTitle":"t","knowledgePointDesc":"d","imageContext":"page","analysisId":"missing"}`: "NOT_FOUND",
+	} {
+		w, resp := env.postJSON("/api/knowledge-points/kp-001/dialogue", body, nil)
+		if resp.Error == nil || resp.Error.Reason != want {
+			t.Errorf("dialogue %s = %d %s, want %s", body, w.Code, w.Body.String(), want)
+		}
+	}
+}
+
+func TestDialogueTutoringStrategy(t *testing.T) {
+	env := newTestEnv(t)
+	send := func(body string) schema.DialogueResponse {
+		t.Helper()
+		var reply schema.DialogueResponse
+		if w, _ := env.postJSON("/api/knowledge-points/kp_1/dialogue", body, &reply); w.Code != http.StatusOK {
+			t.Fatalf("dialogue = %d %s", w.Code, w.Body.String())
+		}
+		return reply
+	}
+
+	// 首轮选定苏格拉底式提问，进度标记从回复中去掉
+	env.fake.Enqueue(aitest.Reply{Content: "直角对着的是哪条边？\n<<progress:progress>>"})
+	reply := send(`{"message":"为什么 a²+b²=c²？","knowledgePointTitle":"勾股定理","knowledgePointDesc":"直角三角形三边关系","strategy":"socratic"}`)
+	if reply.Message != "直角对着的是哪条边？" || reply.Strategy != schema.StrategySocratic ||
+		reply.Tutoring == nil || reply.Tutoring.State != schema.TutoringInProgress || reply.Tutoring.Turns != 1 {
+		t.Fatalf("first turn = %+v", reply)
+	}
+	if system := aitest.ContentText(env.fake.LastRequest().Messages[0]); !strings.Contains(system, "绝不直接给出最终答案") || !strings.Contains(system, "<<progress:reached>>") {
+		t.Errorf("system prompt missing socratic instructions: %s", system)
+	}
+
+	// 后续轮次省略 strategy 时沿用会话的策略；连续两轮没有进展后要求给出更具体的提示
+	next := `{"message":"不知道","knowledgePointTitle":"勾股定理","knowledgePointDesc":"直角三角形三边关系","conversationId":"` + reply.ConversationID + `"}`
+	for i := 1; i <= 2; i++ {
+		env.fake.Enqueue(aitest.Reply{Content: "换个角度想想。<<progress:stuck>>"})
+		reply = send(next)
+		if reply.Strategy != schema.StrategySocratic || reply.Tutoring == nil || reply.Tutoring.State != schema.TutoringStruggling || reply.Tutoring.StuckTurns != i {
+			t.Fatalf("stuck turn %d = %+v %+v", i, reply, reply.Tutoring)
+		}
+	}
+	env.fake.Enqueue(aitest.Reply{Content: "对，就是斜边！\n<<progress:reached>>"})
+	reply = send(next)
+	if system := aitest.ContentText(env.fake.LastRequest().Messages[0]); !strings.Contains(system, "更具体的提示") {
+		t.Errorf("system prompt should escalate hints after stuck turns: %s", system)
+	}
+	if reply.Tutoring.State != schema.TutoringGoalReached || reply.Tutoring.Turns != 4 {
+		t.Fatalf("reached turn = %+v", reply.Tutoring)
+	}
+
+	// 达成目标后不再回退，提示词转为总结
+	env.fake.Enqueue(aitest.Reply{Content: "总结一下。<<progress:stuck>>"})
+	reply = send(next)
+	if reply.Message != "总结一下。" || reply.Tutoring.State != schema.TutoringGoalReached {
+		t.Errorf("after goal = %+v %+v", reply, reply.Tutoring)
+	}
+	if system := aitest.ContentText(env.fake.LastRequest().Messages[0]); !strings.Contains(system, "已经达成本次目标") {
+		t.Errorf("system prompt should wrap up after goal reached: %s", system)
+	}
+
+	// 直接讲解：不要求进度标记，也不报告进度
+	env.fake.Enqueue(aitest.Reply{Content: "斜边的平方等于两直角边的平方和。"})
+	reply = send(`{"message":"m","knowledgePointTitle":"勾股定理","knowledgePointDesc":"d","conversationId":"` + reply.ConversationID + `","strategy":"direct"}`)
+	if reply.Strategy != schema.StrategyDirect || reply.Tutoring != nil {
+		t.Errorf("direct reply = %+v", reply)
+	}
+	if system := aitest.ContentText(env.fake.LastRequest().Messages[0]); strings.Contains(system, "<<progress:") {
+		t.Errorf("direct prompt should not ask for progress markers: %s", system)
+	}
+
+	w, resp := env.postJSON("/api/knowledge-points/kp_1/dialogue", `{"message":"m","knowledgePointTitle":"t","knowledgePointDesc":"d","strategy":"lecture"}`, nil)
+	if w.Code != http.StatusBadRequest || resp.Error == nil || len(resp.Error.Fields) != 1 || resp.Error.Fields[0].Field != "strategy" {
+		t.Errorf("invalid strategy = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDialogueValidation(t *testing.T) {
+	env := newTestEnv(t)
+	w, resp := env.postJSON("/api/knowledge-points/kp_1/dialogue", `{"knowledgePointTitle":"t"}`, nil)
+	if w.Code != http.StatusBadRequest || resp.Error == nil || len(resp.Error.Fields) == 0 {
+		t.Errorf("invalid dialogue = %d %s", w.Code, w.Body.String())
+	}
+
+	w, resp = env.postJSON("/api/knowledge-points/kp_1/dialogue", `{"message":"hi","knowledgePointDesc":"d"}`, nil)
+	if w.Code != http.StatusBadRequest || resp.Error == nil || len(resp.Error.Fields) != 1 || resp.Error.Fields[0].Field != "knowledgePointTitle" {
+		t.Errorf("missing title = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestChatValidation(t *testing.T) {
+	env := newTestEnv(t)
+	cases := []struct {
+		name, body, field string
+	}{
+		{"role", `{"messages":[{"role":"tool","content":"hi"}]}`, "messages[0].role"},
+		{"temperature", `{"messages":[{"role":"user","content":"hi"}],"temperature":2.5}`, "temperature"},
+		{"content part type", `{"messages":[{"role":"user","content":[{"type":"audio"}]}]}`, "messages[0].content[0].type"},
+		{"content type", `{"messages":[{"role":"user","content":42}]}`, "messages[0].content"},
+		{"no messages", `{"messages":[]}`, "messages"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, resp := env.postJSON("/api/v1/chat", tc.body, nil)
+			if w.Code != http.StatusBadRequest || resp.Error == nil {
+				t.Fatalf("chat = %d %s", w.Code, w.Body.String())
+			}
+			fields := make([]string, 0, len(resp.Error.Fields))
+			for _, f := range resp.Error.Fields {
+				fields = append(fields, f.Field)
+			}
+			if len(fields) != 1 || fields[0] != tc.field {
+				t.Errorf("fields = %v, want [%s]", fields, tc.field)
+			}
+		})
+	}
+	if env.fake.LastRequest() != nil {
+		t.Error("invalid request reached the AI service")
+	}
+}
+
+func TestResponseValidationCatchesDrift(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.Use(validationMiddleware(doc, global.Static(&global.AppConfig{Validation: global.ValidationConfig{Responses: true}})))
+	engine.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": 1})
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), `"status":1`) {
+		t.Errorf("drifted response = %d %s, want 500", w.Code, w.Body.String())
+	}
+}
+
+func TestSimpleChatUsesPromptTemplate(t *testing.T) {
+	env := newTestEnv(t)
+	var chat schema.ChatResponse
+	w, _ := env.postJSON("/api/v1/chat/simple", `{"message":"hi","model":"other-model"}`, &chat)
+	if w.Code != http.StatusOK || chat.Choices[0].Message.Content != aitest.DefaultContent {
+		t.Fatalf("simple chat = %d %s", w.Code, w.Body.String())
+	}
+	req := env.fake.LastRequest()
+	if req.Model != "other-model" || req.Messages[0].Role != "system" || aitest.ContentText(req.Messages[0]) == "" {
+		t.Errorf("unexpected upstream request: %+v", req)
+	}
+}
+
+func TestLearnerProfile(t *testing.T) {
+	env := newTestEnv(t)
+	send := func(method, path, body, user string, data any) (*httptest.ResponseRecorder, schema.Response) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "zh-CN")
+		if user != "" {
+			req.Header.Set(identity.HeaderUserID, user)
+		}
+		return env.do(req, data)
+	}
+
+	if w, _ := send(http.MethodPut, "/api/profile", `{"gradeLevel":"primary"}`, "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT without user = %d, want 400", w.Code)
+	}
+	if w, _ := send(http.MethodGet, "/api/profile", "", "kid", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET before saving = %d, want 404", w.Code)
+	}
+	if w, _ := send(http.MethodPut, "/api/profile", `{"gradeLevel":"toddler"}`, "kid", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT invalid grade = %d, want 400", w.Code)
+	}
+	send(http.MethodPut, "/api/profile", `{"gradeLevel":"middle"}`, "kid", nil)
+	var saved schema.LearnerProfile
+	w, _ := send(http.MethodPut, "/api/profile", `{"gradeLevel":"primary","strengths":["数学"],"language":"en","style":"examples"}`, "kid", &saved)
+	if w.Code != http.StatusOK || saved.Version != 2 || saved.GradeLevel != schema.GradePrimary {
+		t.Fatalf("PUT profile = %d %s", w.Code, w.Body.String())
+	}
+	var got schema.LearnerProfile
+	if w, _ := send(http.MethodGet, "/api/profile", "", "kid", &got); w.Code != http.StatusOK || !reflect.DeepEqual(got, saved) {
+		t.Errorf("GET profile = %d %s", w.Code, w.Body.String())
+	}
+
+	// 画像注入分析提示词，未指定 lang 时按画像的偏好语言输出
+	const text = `{"text":"直角三角形两直角边的平方和等于斜边的平方。"}`
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()})
+	var result schema.KnowledgeAnalysisResponse
+	if w, _ := send(http.MethodPost, "/api/analyze/text", text, "kid", &result); w.Code != http.StatusOK {
+		t.Fatalf("analyze = %d %s", w.Code, w.Body.String())
+	}
+	system := aitest.ContentText(env.fake.LastRequest().Messages[0])
+	if result.ProfileVersion != "v2" || result.Language != "en" || !strings.Contains(system, "primary school student") || !strings.Contains(system, "everyday examples") {
+		t.Errorf("profileVersion = %q, language = %q, system prompt = %s", result.ProfileVersion, result.Language, system)
+	}
+
+	// 单次请求覆盖画像，结果不复用其他画像的缓存
+	env.fake.Enqueue(aitest.Reply{Content: analysisReply()})
+	result = schema.KnowledgeAnalysisResponse{}
+	if w, _ := send(http.MethodPost, "/api/analyze/text?lang=zh-CN&gradeLevel=university", text, "kid", &result); w.Code != http.StatusOK {
+		t.Fatalf("analyze with override = %d %s", w.Code, w.Body.String())
+	}
+	system = aitest.ContentText(env.fake.LastRequest().Messages[0])
+	if result.ProfileVersion != "v2+override" || result.Language != "zh-CN" || !strings.Contains(system, "年级：大学生") || !strings.Contains(system, "擅长的科目：数学") {
+		t.Errorf("profileVersion = %q, language = %q, system prompt = %s", result.ProfileVersion, result.Language, system)
+	}
+	if w, _ := send(http.MethodPost, "/api/analyze/text?style=verbose", text, "kid", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid style override = %d, want 400", w.Code)
+	}
+
+	// 对话同样注入画像；没有画像的用户不受影响
+	env.fake.Enqueue(aitest.Reply{Content: "Imagine a ladder against a wall."})
+	var reply schema.DialogueResponse
+	dialogue := `{"message":"Why?","knowledgePointTitle":"勾股定理","knowledgePointDesc":"直角三角形三边关系"}`
+	if w, _ := send(http.MethodPost, "/api/knowledge-points/kp_1/dialogue", dialogue, "kid", &reply); w.Code != http.StatusOK || reply.ProfileVersion != "v2" {
+		t.Errorf("dialogue = %d %s", w.Code, w.Body.String())
+	}
+	if system := aitest.ContentText(env.fake.LastRequest().Messages[0]); !strings.Contains(system, "About the learner") {
+		t.Errorf("dialogue system prompt = %s", system)
+	}
+	env.fake.Enqueue(aitest.Reply{Content: "因为……"})
+	reply = schema.DialogueResponse{}
+	if w, _ := send(http.MethodPost, "/api/knowledge-points/kp_1/dialogue", dialogue, "adult", &reply); w.Code != http.StatusOK || reply.ProfileVersion != "" || reply.Language != "zh-CN" {
+		t.Errorf("dialogue without profile = %d %s", w.Code, w.Body.String())
+	}
+	if system := aitest.ContentText(env.fake.LastRequest().Messages[0]); strings.Contains(system, "学习者情况") {
+		t.Errorf("dialogue without profile mentions a learner: %s", system)
+	}
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	env := newTestEnv(t)
+	w, _ := env.do(httptest.NewRequest(http.MethodGet, "/api/admin/prompts", nil), nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("admin without token = %d, want 401", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/prompts", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	var prompts []schema.PromptTemplateInfo
+	w, _ = env.do(req, &prompts)
+	if w.Code != http.StatusOK || len(prompts) == 0 {
+		t.Errorf("admin with token = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminValidatesAfterAuth(t *testing.T) {
+	env := newTestEnv(t)
+	const path = "/api/admin/prompts/dialogue/preview"
+	const body = `{"variant": 1}`
+
+	// 未鉴权的请求不能通过字段错误探测管理接口的请求结构
+	w, resp := env.postJSON(path, body, nil)
+	if w.Code != http.StatusUnauthorized || resp.Error == nil || len(resp.Error.Fields) != 0 {
+		t.Errorf("unauthenticated bad body = %d %s, want 401 without fields", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w, resp = env.do(req, nil)
+	if w.Code != http.StatusBadRequest || resp.Error == nil || len(resp.Error.Fields) != 1 || resp.Error.Fields[0].Field != "variant" {
+		t.Errorf("authenticated bad body = %d %s, want 400 on variant", w.Code, w.Body.String())
+	}
+}
+
+func TestUnknownRoute(t *testing.T) {
+	env := newTestEnv(t)
+	w, resp := env.do(httptest.NewRequest(http.MethodGet, "/api/nope", nil), nil)
+	if w.Code != http.StatusNotFound || resp.Error == nil || resp.Error.Reason != "NOT_FOUND" {
+		t.Errorf("unknown route = %d %s", w.Code, w.Body.String())
+	}
+}
+
+// TestOpenAPICoversAllRoutes 注册的路由和 OpenAPI 文档中的接口一一对应
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	env := newTestEnv(t)
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("openapi.Load: %v", err)
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range env.engine.Routes() {
+		segments := strings.Split(route.Path, "/")
+		for i, s := range segments {
+			if len(s) > 1 && (s[0] == ':' || s[0] == '*') {
+				segments[i] = "{" + s[1:] + "}"
+			}
+		}
+		path, method := strings.Join(segments, "/"), strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+		if doc.Paths[path][method] == nil {
+			t.Errorf("%s %s is not in openapi.json, add swag annotations to %s and run go generate ./internal/application/openapi", route.Method, path, route.Handler)
+		}
+	}
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if !registered[method+" "+path] {
+				t.Errorf("openapi.json documents %s %s, which is not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestDocsEndpoints(t *testing.T) {
+	env := newTestEnv(t)
+	w, _ := env.do(httptest.NewRequest(http.MethodGet, "/openapi.json", nil), nil)
+	var doc openapi.Document
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &doc) != nil || doc.OpenAPI != openapi.Version {
+		t.Errorf("GET /openapi.json = %d %.100s", w.Code, w.Body.String())
+	}
+
+	w, _ = env.do(httptest.NewRequest(http.MethodGet, "/docs", nil), nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Errorf("GET /docs = %d", w.Code)
+	}
+	if page := w.Body.String(); !strings.Contains(page, "swagger-ui-dist@"+openapi.SwaggerUIVersion+"/swagger-ui-bundle.js") {
+		t.Errorf("GET /docs should load a pinned Swagger UI version: %s", page)
+	}
+}
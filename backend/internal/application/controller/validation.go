@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"ai-note-service/internal/application/common"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/openapi"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validationMiddleware 按 OpenAPI 文档校验请求体，不符合时返回字段级错误
+// 开启 validation.responses 时同时校验响应，发现与文档不一致时记录错误并返回 500
+// 声明了 security 的接口跳过，由路由组在鉴权之后挂载 securedValidationMiddleware 校验，避免未鉴权请求探测接口结构
+func validationMiddleware(doc *openapi.Document, config global.Provider) gin.HandlerFunc {
+	return newValidationMiddleware(doc, config, false)
+}
+
+// securedValidationMiddleware 只校验声明了 security 的接口，需挂在鉴权中间件之后
+func securedValidationMiddleware(doc *openapi.Document, config global.Provider) gin.HandlerFunc {
+	return newValidationMiddleware(doc, config, true)
+}
+
+func newValidationMiddleware(doc *openapi.Document, config global.Provider, secured bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := doc.Operation(c.Request.Method, c.FullPath())
+		if op == nil || (len(op.Security) > 0) != secured {
+			c.Next()
+			return
+		}
+
+		if fields, err := validateRequest(c, doc, op); err != nil {
+			common.InternalErrorResponse(c, err)
+			c.Abort()
+			return
+		} else if len(fields) > 0 {
+			common.HandleError(c, &errcode.Error{Code: errcode.InvalidParams, Fields: fields})
+			c.Abort()
+			return
+		}
+
+		if !config().Validation.Responses {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		buffered := &bufferedResponseWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buffered
+		defer func() { c.Writer = original }() // 处理器 panic 时恢复中间件需要写原始响应
+		c.Next()
+		c.Writer = original
+
+		if err := validateResponse(doc, op, buffered); err != nil {
+			common.InternalErrorResponse(c, fmt.Errorf("%s %s: %w", c.Request.Method, c.FullPath(), err))
+			return
+		}
+		original.WriteHeader(buffered.status)
+		original.Write(buffered.body.Bytes())
+	}
+}
+
+// validateRequest 校验 JSON 请求体，请求体为空或格式错误时交给处理器绑定时报告
+// 读取后的请求体会重新放回请求，处理器仍可正常绑定
+func validateRequest(c *gin.Context, doc *openapi.Document, op *openapi.Operation) ([]errcode.FieldError, error) {
+	if !openapi.IsJSON(c.ContentType()) || c.Request.Body == nil {
+		return nil, nil
+	}
+	schema := op.RequestSchema(c.ContentType())
+	if schema == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	violations, err := doc.ValidateJSON(schema, body)
+	if err != nil {
+		return nil, nil
+	}
+	return violationFields(violations, i18n.FromContext(c.Request.Context())), nil
+}
+
+// validateResponse 校验缓冲的 JSON 响应，非 JSON 响应和文档未声明结构的响应不校验
+func validateResponse(doc *openapi.Document, op *openapi.Operation, w *bufferedResponseWriter) error {
+	contentType := w.Header().Get("Content-Type")
+	if !openapi.IsJSON(contentType) {
+		return nil
+	}
+	schema := doc.ResponseSchema(op, w.status, contentType)
+	if schema == nil {
+		return nil
+	}
+	violations, err := doc.ValidateJSON(schema, w.body.Bytes())
+	if err != nil {
+		return fmt.Errorf("response %d is not valid JSON: %w", w.status, err)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("response %d does not match the OpenAPI spec: %v", w.status, violationFields(violations, i18n.En))
+	}
+	return nil
+}
+
+// violationFields 将文档校验结果转换为字段级错误，说明与参数绑定失败时一致
+func violationFields(violations []openapi.Violation, lang i18n.Language) []errcode.FieldError {
+	fields := make([]errcode.FieldError, 0, len(violations))
+	for _, v := range violations {
+		var message string
+		if v.Param == "" {
+			message = i18n.T(lang, "validation."+v.Rule)
+		} else {
+			message = i18n.T(lang, "validation."+v.Rule, v.Param)
+		}
+		field := v.Field
+		if field == "" {
+			field = "body"
+		}
+		fields = append(fields, errcode.FieldError{Field: field, Message: message})
+	}
+	return fields
+}
+
+// bufferedResponseWriter 缓冲处理器写出的响应，校验通过后再写给客户端
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	written bool
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedResponseWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedResponseWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedResponseWriter) Written() bool {
+	return w.written
+}
+
+// Flush 缓冲期间不向客户端刷新
+func (w *bufferedResponseWriter) Flush() {}
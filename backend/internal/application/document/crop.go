@@ -0,0 +1,53 @@
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+
+	// 注册上传图片支持的格式
+	_ "image/gif"
+	_ "image/jpeg"
+
+	_ "golang.org/x/image/webp"
+)
+
+// cropPadding 截取区域四周额外保留的比例（相对图片宽高），避免紧贴内容边缘
+const cropPadding = 0.02
+
+// ErrInvalidImage 图片无法解码
+var ErrInvalidImage = errors.New("document: invalid image")
+
+// Crop 按归一化区域（以左上角为原点，取值 0-1）截取图片，四周略微外扩，返回 PNG
+func Crop(data []byte, x, y, width, height float64) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	rect := image.Rect(
+		bounds.Min.X+int((x-cropPadding)*w),
+		bounds.Min.Y+int((y-cropPadding)*h),
+		bounds.Min.X+int((x+width+cropPadding)*w+0.5),
+		bounds.Min.Y+int((y+height+cropPadding)*h+0.5),
+	).Intersect(bounds)
+	if rect.Empty() {
+		return nil, fmt.Errorf("region %v is outside the image bounds %v", rect, bounds)
+	}
+
+	sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: %T does not support cropping", ErrInvalidImage, img)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sub.SubImage(rect)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+// Package document 将待分析的材料（图片、PDF、网页、文本）转换为按页组织的分析输入
+package document
+
+import (
+	"context"
+	"errors"
+)
+
+// Page 待分析的一页材料，Image 和 Text 至少有一个不为空
+type Page struct {
+	Number int    // 页码，从 1 开始
+	Image  []byte // 页面图片（上传的图片或 PDF 渲染结果）
+	Text   string // 页面文字（PDF 文字层、网页正文或文本），不为空时不再发送图片
+}
+
+// Source 待分析的材料来源，Load 读取并转换为按页组织的内容
+type Source interface {
+	Load(ctx context.Context) (*Extraction, error)
+}
+
+// Extraction 材料转换结果
+type Extraction struct {
+	TotalPages int
+	Pages      []Page // 按页码顺序，有文字的页面只有 Text，其余为图片
+	Skipped    []int  // 没有文字层且无法渲染而跳过的页码
+}
+
+// Renderer 将 PDF 的一页渲染为图片，用于没有文字层的页面（扫描件、纯图片幻灯片）
+type Renderer interface {
+	Render(ctx context.Context, pdf []byte, page int) ([]byte, error)
+}
+
+// RendererFunc 函数形式的 Renderer
+type RendererFunc func(ctx context.Context, pdf []byte, page int) ([]byte, error)
+
+// Render 调用 f
+func (f RendererFunc) Render(ctx context.Context, pdf []byte, page int) ([]byte, error) {
+	return f(ctx, pdf, page)
+}
+
+var (
+	// ErrInvalidPDF 文件不是可解析的 PDF（包括加密文件）
+	ErrInvalidPDF = errors.New("document: invalid PDF")
+	// ErrNoContent 选中的页面既没有文字层也无法渲染
+	ErrNoContent = errors.New("document: no analyzable pages")
+	// ErrNoRenderer 未配置页面渲染器
+	ErrNoRenderer = errors.New("document: no page renderer configured")
+)
+
+// PageRangeError 页码范围不合法
+type PageRangeError struct {
+	Spec  string // 请求的页码范围
+	Total int    // 文档总页数
+}
+
+func (e *PageRangeError) Error() string {
+	return "document: invalid page range " + e.Spec
+}
+
+// TooManyPagesError 选中的页数超过上限
+type TooManyPagesError struct {
+	Max int
+}
+
+func (e *TooManyPagesError) Error() string {
+	return "document: too many pages selected"
+}
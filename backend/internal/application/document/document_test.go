@@ -0,0 +1,200 @@
+package document
+
+import (
+	"ai-note-service/internal/application/document/pdftest"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	introText = "Chapter 1 Introduction to right triangles\nThe Pythagorean theorem relates the three sides."
+	proofText = "Chapter 2 Proof by rearrangement of four copies"
+)
+
+func TestParsePageRanges(t *testing.T) {
+	cases := []struct {
+		spec string
+		want []int
+	}{
+		{"", []int{1, 2, 3, 4, 5}},
+		{"2", []int{2}},
+		{"1-2, 4", []int{1, 2, 4}},
+		{"4-", []int{4, 5}},
+		{"3,1-3,3", []int{1, 2, 3}},
+	}
+	for _, tc := range cases {
+		got, err := ParsePageRanges(tc.spec, 5)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParsePageRanges(%q) = %v, %v; want %v", tc.spec, got, err, tc.want)
+		}
+	}
+
+	for _, spec := range []string{"0", "6", "3-2", "a", "1-x", ",", "2-7"} {
+		var rangeErr *PageRangeError
+		if _, err := ParsePageRanges(spec, 5); !errors.As(err, &rangeErr) {
+			t.Errorf("ParsePageRanges(%q) error = %v, want *PageRangeError", spec, err)
+		}
+	}
+}
+
+func TestExtractPDFText(t *testing.T) {
+	data := pdftest.Build(introText, "", proofText)
+
+	result, err := ExtractPDF(context.Background(), data, PDFOptions{})
+	if err != nil {
+		t.Fatalf("ExtractPDF: %v", err)
+	}
+	if result.TotalPages != 3 {
+		t.Errorf("total pages = %d, want 3", result.TotalPages)
+	}
+	// 第 2 页没有文字层且未配置渲染器，被跳过
+	if !reflect.DeepEqual(result.Skipped, []int{2}) {
+		t.Errorf("skipped = %v, want [2]", result.Skipped)
+	}
+	if len(result.Pages) != 2 || result.Pages[0].Number != 1 || result.Pages[1].Number != 3 {
+		t.Fatalf("pages = %+v", result.Pages)
+	}
+	if got := result.Pages[0].Text; !strings.Contains(got, "Introduction to right triangles") || !strings.Contains(got, "\n") {
+		t.Errorf("page 1 text = %q, want both lines", got)
+	}
+	if result.Pages[0].Image != nil {
+		t.Error("text page should not be rendered")
+	}
+}
+
+func TestExtractPDFRendersPagesWithoutText(t *testing.T) {
+	data := pdftest.Build(introText, "", "Page 3")
+
+	var rendered []int
+	renderer := RendererFunc(func(_ context.Context, pdf []byte, page int) ([]byte, error) {
+		rendered = append(rendered, page)
+		return []byte("png"), nil
+	})
+	result, err := ExtractPDF(context.Background(), data, PDFOptions{Pages: "2-", Renderer: renderer})
+	if err != nil {
+		t.Fatalf("ExtractPDF: %v", err)
+	}
+	// 第 3 页的文字过短（如只有页码），同样按图片处理
+	if !reflect.DeepEqual(rendered, []int{2, 3}) {
+		t.Errorf("rendered = %v, want [2 3]", rendered)
+	}
+	if len(result.Pages) != 2 || string(result.Pages[0].Image) != "png" || result.Skipped != nil {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestExtractPDFErrors(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := ExtractPDF(ctx, []byte("not a pdf"), PDFOptions{}); !errors.Is(err, ErrInvalidPDF) {
+		t.Errorf("invalid PDF error = %v, want ErrInvalidPDF", err)
+	}
+
+	data := pdftest.Build(introText, proofText, "")
+	var tooMany *TooManyPagesError
+	if _, err := ExtractPDF(ctx, data, PDFOptions{MaxPages: 2}); !errors.As(err, &tooMany) || tooMany.Max != 2 {
+		t.Errorf("too many pages error = %v", err)
+	}
+	if _, err := ExtractPDF(ctx, data, PDFOptions{Pages: "1-2", MaxPages: 2}); err != nil {
+		t.Errorf("selected pages within the limit: %v", err)
+	}
+
+	failing := RendererFunc(func(context.Context, []byte, int) ([]byte, error) {
+		return nil, errors.New("render failed")
+	})
+	if _, err := ExtractPDF(ctx, data, PDFOptions{Pages: "3", Renderer: failing}); !errors.Is(err, ErrNoContent) {
+		t.Errorf("no content error = %v, want ErrNoContent", err)
+	}
+
+	var rangeErr *PageRangeError
+	if _, err := ExtractPDF(ctx, data, PDFOptions{Pages: "4"}); !errors.As(err, &rangeErr) || rangeErr.Total != 3 {
+		t.Errorf("page range error = %v", err)
+	}
+}
+
+func TestTextSource(t *testing.T) {
+	if _, err := TextSource(" \n\t ").Load(context.Background()); !errors.Is(err, ErrNoContent) {
+		t.Errorf("blank text error = %v, want ErrNoContent", err)
+	}
+
+	result, err := TextSource("# Notes\r\n\r\nShort paragraph.").Load(context.Background())
+	if err != nil || len(result.Pages) != 1 || result.Pages[0].Text != "# Notes\n\nShort paragraph." {
+		t.Fatalf("short text = %+v, %v", result, err)
+	}
+
+	// 在段落边界分页，超长段落硬切分
+	long := strings.Repeat("a", maxPageTextRunes-100)
+	huge := strings.Repeat("b", maxPageTextRunes+10)
+	result, err = TextSource(long + "\n\n" + "second paragraph" + "\n\n" + huge).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var lengths []int
+	for i, page := range result.Pages {
+		if page.Number != i+1 {
+			t.Errorf("page %d numbered %d", i+1, page.Number)
+		}
+		lengths = append(lengths, len(page.Text))
+	}
+	if want := []int{maxPageTextRunes - 100 + 2 + 16, maxPageTextRunes, 10}; !reflect.DeepEqual(lengths, want) {
+		t.Errorf("page lengths = %v, want %v", lengths, want)
+	}
+}
+
+func TestHTMLText(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><title>Right Triangles</title><style>p{color:red}</style></head>
+<body><nav><a href="/">Home</a></nav>
+<h1>Right Triangles</h1>
+<p>The <b>Pythagorean</b> theorem: a&sup2; + b&sup2; = c&sup2;.</p>
+<script>track("view")</script>
+<ul><li>legs</li><li>hypotenuse</li></ul>
+<table><tr><td>a</td><td>b</td></tr></table>
+<footer>Copyright</footer></body></html>`
+	want := "# Right Triangles\nThe Pythagorean theorem: a² + b² = c².\n- legs\n- hypotenuse\na b"
+	if got := HTMLText([]byte(page), ""); got != want {
+		t.Errorf("HTMLText =\n%s\nwant\n%s", got, want)
+	}
+
+	// 标题不在正文中时补在开头；按 <meta> 声明的编码解码
+	gbk := []byte("<html><head><meta charset=\"gbk\"><title>\xb9\xb4\xb9\xc9\xb6\xa8\xc0\xed</title></head><body><p>\xd6\xb1\xbd\xc7\xc8\xfd\xbd\xc7\xd0\xce</p></body></html>")
+	if got := HTMLText(gbk, ""); got != "# 勾股定理\n直角三角形" {
+		t.Errorf("GBK page = %q", got)
+	}
+	if got := DecodeText([]byte("\xd6\xb1\xbd\xc7"), "gb2312"); got != "直角" {
+		t.Errorf("DecodeText = %q", got)
+	}
+}
+
+func TestCrop(t *testing.T) {
+	var src bytes.Buffer
+	png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 400, 300)))
+
+	data, err := Crop(src.Bytes(), 0.5, 0.5, 0.25, 0.1)
+	if err != nil {
+		t.Fatalf("Crop: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// 区域 100x30，左右各外扩 8 像素，上下各外扩 6 像素
+	if size := img.Bounds().Size(); size.X != 116 || size.Y != 42 {
+		t.Errorf("crop size = %v, want 116x42", size)
+	}
+
+	// 靠近边缘的区域外扩后截断在图片范围内
+	data, _ = Crop(src.Bytes(), 0, 0.9, 1, 0.1)
+	if img, _ := png.Decode(bytes.NewReader(data)); img == nil || img.Bounds().Dx() != 400 || img.Bounds().Dy() != 36 {
+		t.Errorf("edge crop = %v", img)
+	}
+
+	if _, err := Crop([]byte("not an image"), 0, 0, 1, 1); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("invalid image error = %v, want ErrInvalidImage", err)
+	}
+}
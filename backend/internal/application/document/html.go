@@ -0,0 +1,137 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// HTMLSource 网页，提取正文文字后按 TextSource 处理
+type HTMLSource struct {
+	Data    []byte
+	Charset string // 响应头给出的编码，为空时按 BOM、<meta> 和内容推断
+}
+
+// Load 提取网页标题和正文
+func (s HTMLSource) Load(ctx context.Context) (*Extraction, error) {
+	return TextSource(HTMLText(s.Data, s.Charset)).Load(ctx)
+}
+
+// DecodeText 按 contentCharset 将文本转换为 UTF-8，未给出或无法识别时按内容推断
+func DecodeText(data []byte, contentCharset string) string {
+	if contentCharset != "" {
+		if r, err := charset.NewReaderLabel(contentCharset, bytes.NewReader(data)); err == nil {
+			if decoded, err := io.ReadAll(r); err == nil {
+				return string(decoded)
+			}
+		}
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	enc, _, _ := charset.DetermineEncoding(data, "text/plain")
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+// skippedElements 不属于正文的元素，其中的内容全部忽略
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+	"iframe": true, "nav": true, "header": true, "footer": true, "aside": true, "form": true,
+}
+
+// blockElements 块级元素，前后换行
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "br": true, "hr": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true, "table": true, "tr": true,
+	"pre": true, "blockquote": true, "figure": true, "figcaption": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// HTMLText 提取网页的标题和正文文字，标题按 Markdown 格式标注层级，列表项以 "- " 开头
+func HTMLText(data []byte, contentCharset string) string {
+	contentType := "text/html"
+	if contentCharset != "" {
+		contentType += "; charset=" + contentCharset
+	}
+	var r io.Reader = bytes.NewReader(data)
+	if decoded, err := charset.NewReader(r, contentType); err == nil {
+		r = decoded
+	}
+
+	var title string
+	var lines []string
+	var line strings.Builder
+	flush := func() {
+		if s := strings.Join(strings.Fields(line.String()), " "); s != "" {
+			lines = append(lines, s)
+		}
+		line.Reset()
+	}
+
+	z := html.NewTokenizer(r)
+	skipDepth := 0 // 位于忽略元素内的层数
+	inTitle := false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		name, _ := z.TagName()
+		tag := string(name)
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if skippedElements[tag] {
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if tag == "title" {
+				inTitle = true
+			}
+			if skipDepth > 0 || !blockElements[tag] {
+				continue
+			}
+			flush()
+			if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+				line.WriteString(strings.Repeat("#", int(tag[1]-'0')) + " ")
+			} else if tag == "li" {
+				line.WriteString("- ")
+			}
+		case html.EndTagToken:
+			switch {
+			case skippedElements[tag]:
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			case tag == "title":
+				inTitle = false
+			case skipDepth == 0 && blockElements[tag]:
+				flush()
+			case tag == "td" || tag == "th":
+				line.WriteByte(' ')
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			} else if skipDepth == 0 {
+				line.Write(z.Text())
+			}
+		}
+	}
+	flush()
+
+	if title = strings.Join(strings.Fields(title), " "); title != "" && (len(lines) == 0 || !strings.Contains(lines[0], title)) {
+		lines = append([]string{"# " + title}, lines...)
+	}
+	return strings.Join(lines, "\n")
+}
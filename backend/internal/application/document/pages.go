@@ -0,0 +1,54 @@
+package document
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParsePageRanges 解析页码范围，如 "1-3,5,8-"（"8-" 表示第 8 页到最后一页），结果按页码升序去重
+// spec 为空时选中全部页面；页码超出 [1, total] 或格式错误时返回 *PageRangeError
+func ParsePageRanges(spec string, total int) ([]int, error) {
+	selected := make([]bool, total+1)
+	if strings.TrimSpace(spec) == "" {
+		for i := 1; i <= total; i++ {
+			selected[i] = true
+		}
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, &PageRangeError{Spec: spec, Total: total}
+		}
+		last := first
+		if isRange {
+			last = total
+			if to = strings.TrimSpace(to); to != "" {
+				if last, err = strconv.Atoi(to); err != nil {
+					return nil, &PageRangeError{Spec: spec, Total: total}
+				}
+			}
+		}
+		if first < 1 || last > total || first > last {
+			return nil, &PageRangeError{Spec: spec, Total: total}
+		}
+		for i := first; i <= last; i++ {
+			selected[i] = true
+		}
+	}
+
+	var pages []int
+	for i := 1; i <= total; i++ {
+		if selected[i] {
+			pages = append(pages, i)
+		}
+	}
+	if len(pages) == 0 {
+		return nil, &PageRangeError{Spec: spec, Total: total}
+	}
+	return pages, nil
+}
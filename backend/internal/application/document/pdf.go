@@ -0,0 +1,160 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// minTextRunes 文字层少于该字数（如只有页眉页码）的页面按无文字处理
+	minTextRunes = 20
+	// maxPageTextRunes 单页文字上限，超出部分截断，避免个别页面占满模型上下文
+	maxPageTextRunes = 8000
+)
+
+// PDFOptions PDF 提取选项
+type PDFOptions struct {
+	Pages    string   // 页码范围，如 "1-3,5"，为空时选中全部页面
+	MaxPages int      // 最多选中的页数，0 表示不限制
+	Renderer Renderer // 渲染没有文字层的页面，为 nil 时跳过这些页面
+}
+
+// ExtractPDF 按页提取 PDF：有文字层的页面提取文字，其余页面交给 Renderer 渲染为图片
+func ExtractPDF(ctx context.Context, data []byte, opts PDFOptions) (*Extraction, error) {
+	reader, total, err := openPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := ParsePageRanges(opts.Pages, total)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxPages > 0 && len(numbers) > opts.MaxPages {
+		return nil, &TooManyPagesError{Max: opts.MaxPages}
+	}
+
+	result := &Extraction{TotalPages: total}
+	for _, n := range numbers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if text := pageText(reader, n); utf8.RuneCountInString(text) >= minTextRunes {
+			result.Pages = append(result.Pages, Page{Number: n, Text: truncateRunes(text, maxPageTextRunes)})
+			continue
+		}
+
+		image, err := renderPage(ctx, opts.Renderer, data, n)
+		if err != nil {
+			if !errors.Is(err, ErrNoRenderer) {
+				slog.WarnContext(ctx, "render PDF page failed", "page", n, "error", err)
+			}
+			result.Skipped = append(result.Skipped, n)
+			continue
+		}
+		result.Pages = append(result.Pages, Page{Number: n, Image: image})
+	}
+
+	if len(result.Pages) == 0 {
+		return nil, ErrNoContent
+	}
+	return result, nil
+}
+
+// openPDF 打开 PDF 并读取总页数；解析库遇到格式错误时会 panic，统一转换为 ErrInvalidPDF
+func openPDF(data []byte) (reader *pdf.Reader, total int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			reader, total, err = nil, 0, fmt.Errorf("%w: %v", ErrInvalidPDF, r)
+		}
+	}()
+
+	reader, err = pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidPDF, err)
+	}
+	if total = reader.NumPage(); total <= 0 {
+		return nil, 0, fmt.Errorf("%w: no pages", ErrInvalidPDF)
+	}
+	return reader, total, nil
+}
+
+// pageText 按行提取页面文字，无法解析时返回空字符串
+// 按字形坐标重新分行：同一基线的字形归为一行，行内按横坐标排序，间距明显大于字距处补空格
+func pageText(reader *pdf.Reader, n int) (text string) {
+	defer func() {
+		if recover() != nil {
+			text = ""
+		}
+	}()
+
+	page := reader.Page(n)
+	if page.V.IsNull() {
+		return ""
+	}
+	glyphs := page.Content().Text
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		if !sameLine(glyphs[i], glyphs[j]) {
+			return glyphs[i].Y > glyphs[j].Y
+		}
+		return glyphs[i].X < glyphs[j].X
+	})
+
+	var lines []string
+	var line strings.Builder
+	flush := func() {
+		if s := strings.Join(strings.Fields(line.String()), " "); s != "" {
+			lines = append(lines, s)
+		}
+		line.Reset()
+	}
+	for i, g := range glyphs {
+		if i > 0 {
+			prev := glyphs[i-1]
+			if !sameLine(prev, g) {
+				flush()
+			} else if g.X-(prev.X+prev.W) > 0.15*g.FontSize {
+				line.WriteByte(' ')
+			}
+		}
+		line.WriteString(g.S)
+	}
+	flush()
+	return strings.Join(lines, "\n")
+}
+
+// sameLine 两个字形的基线相差不到半个字号时视为同一行
+func sameLine(a, b pdf.Text) bool {
+	return math.Abs(a.Y-b.Y) < math.Max(a.FontSize, b.FontSize)/2
+}
+
+// renderPage 渲染一页，未配置渲染器时返回 ErrNoRenderer
+func renderPage(ctx context.Context, renderer Renderer, data []byte, n int) ([]byte, error) {
+	if renderer == nil {
+		return nil, ErrNoRenderer
+	}
+	image, err := renderer.Render(ctx, data, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(image) == 0 {
+		return nil, fmt.Errorf("renderer returned an empty image for page %d", n)
+	}
+	return image, nil
+}
+
+// truncateRunes 截断到最多 n 个字符
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,59 @@
+// Package pdftest 生成测试用的最小 PDF 文档，使测试无需准备二进制样例文件
+package pdftest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Build 生成每个元素对应一页的 PDF，页面文字按行（\n 分隔）写入文字层；空字符串生成没有文字层的页面
+// 只支持 ASCII 文字（Helvetica 标准字体）
+func Build(pages ...string) []byte {
+	n := len(pages)
+	// 对象编号：1 Catalog，2 Pages，3 Font，之后每页依次为 Page、内容流
+	objects := make([]string, 3+2*n)
+	kids := make([]string, n)
+	for i, text := range pages {
+		pageID, contentID := 4+2*i, 5+2*i
+		kids[i] = fmt.Sprintf("%d 0 R", pageID)
+		objects[pageID-1] = fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", contentID)
+		stream := contentStream(text)
+		objects[contentID-1] = fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream)
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n)
+	objects[2] = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// contentStream 每行文字从页面顶部向下排列
+func contentStream(text string) string {
+	if text == "" {
+		return ""
+	}
+	var b strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(&b, "BT /F1 12 Tf 72 %d Td (%s) Tj ET\n", 720-18*i, escape(line))
+	}
+	return b.String()
+}
+
+// escape 转义 PDF 字符串中的特殊字符
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
@@ -0,0 +1,62 @@
+package document
+
+import (
+	"ai-note-service/internal/application/global"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultRenderDPI 未配置 pdf.render_dpi 时的渲染分辨率
+const defaultRenderDPI = 150
+
+// CommandRenderer 调用 pdftoppm（poppler-utils）或参数兼容的命令将页面渲染为 PNG
+type CommandRenderer struct {
+	Command string // 可执行文件，如 pdftoppm 或其绝对路径
+	DPI     int    // 渲染分辨率，0 时使用默认值
+}
+
+// Render 将 PDF 写入临时目录后渲染第 page 页，ctx 取消时终止命令
+func (r CommandRenderer) Render(ctx context.Context, data []byte, page int) ([]byte, error) {
+	dpi := r.DPI
+	if dpi <= 0 {
+		dpi = defaultRenderDPI
+	}
+
+	dir, err := os.MkdirTemp("", "ai-note-pdf-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return nil, err
+	}
+	output := filepath.Join(dir, "page")
+	n := strconv.Itoa(page)
+	cmd := exec.CommandContext(ctx, r.Command, "-png", "-r", strconv.Itoa(dpi), "-f", n, "-l", n, "-singlefile", input, output)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s page %d: %w: %s", r.Command, page, err, strings.TrimSpace(stderr.String()))
+	}
+	return os.ReadFile(output + ".png")
+}
+
+// ConfiguredRenderer 按当前配置的 pdf.render_command 渲染页面，未配置时返回 ErrNoRenderer
+// 每次渲染时读取配置，支持热加载
+func ConfiguredRenderer(config global.Provider) Renderer {
+	return RendererFunc(func(ctx context.Context, data []byte, page int) ([]byte, error) {
+		cfg := config().PDF
+		if cfg.RenderCommand == "" {
+			return nil, ErrNoRenderer
+		}
+		return CommandRenderer{Command: cfg.RenderCommand, DPI: cfg.RenderDPI}.Render(ctx, data, page)
+	})
+}
@@ -0,0 +1,88 @@
+package document
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTextRunes 文本材料的总字数上限，超出部分截断
+const maxTextRunes = 8 * maxPageTextRunes
+
+// ImageSource 按顺序排列的图片，依次编号为第 1、2… 页
+type ImageSource [][]byte
+
+// Load 每张图片作为一页
+func (s ImageSource) Load(context.Context) (*Extraction, error) {
+	if len(s) == 0 {
+		return nil, ErrNoContent
+	}
+	pages := make([]Page, len(s))
+	for i, image := range s {
+		pages[i] = Page{Number: i + 1, Image: image}
+	}
+	return &Extraction{TotalPages: len(pages), Pages: pages}, nil
+}
+
+// PDFSource PDF 文档，按 Options 选择和渲染页面
+type PDFSource struct {
+	Data    []byte
+	Options PDFOptions
+}
+
+// Load 调用 ExtractPDF
+func (s PDFSource) Load(ctx context.Context) (*Extraction, error) {
+	return ExtractPDF(ctx, s.Data, s.Options)
+}
+
+// TextSource 纯文本或 Markdown 笔记
+type TextSource string
+
+// Load 按段落切分为每页不超过 maxPageTextRunes 字的若干页，总字数超过 maxTextRunes 时截断
+func (s TextSource) Load(context.Context) (*Extraction, error) {
+	text := strings.TrimSpace(strings.ReplaceAll(string(s), "\r\n", "\n"))
+	if text == "" {
+		return nil, ErrNoContent
+	}
+	chunks := splitText(truncateRunes(text, maxTextRunes), maxPageTextRunes)
+	pages := make([]Page, len(chunks))
+	for i, chunk := range chunks {
+		pages[i] = Page{Number: i + 1, Text: chunk}
+	}
+	return &Extraction{TotalPages: len(pages), Pages: pages}, nil
+}
+
+// splitText 在段落边界切分文本，每段不超过 limit 字；单个段落超长时按字数硬切分
+func splitText(text string, limit int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentRunes := 0
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+		currentRunes = 0
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		n := utf8.RuneCountInString(paragraph)
+		if currentRunes > 0 && currentRunes+2+n > limit {
+			flush()
+		}
+		for n > limit {
+			runes := []rune(paragraph)
+			chunks = append(chunks, strings.TrimSpace(string(runes[:limit])))
+			paragraph = string(runes[limit:])
+			n -= limit
+		}
+		if currentRunes > 0 {
+			current.WriteString("\n\n")
+			currentRunes += 2
+		}
+		current.WriteString(paragraph)
+		currentRunes += n
+	}
+	flush()
+	return chunks
+}
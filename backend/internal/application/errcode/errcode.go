@@ -1,11 +1,18 @@
 package errcode
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 // ErrCode 错误码
 type ErrCode struct {
-	Code    int
-	Message string
+	Code       int
+	Message    string
+	HTTPStatus int    // 对应的 HTTP 状态码
+	Reason     string // 稳定的字符串标识，供客户端和监控使用
+	Retryable  bool   // 客户端是否可以重试
 }
 
 func (e *ErrCode) Error() string {
@@ -14,16 +21,98 @@ func (e *ErrCode) Error() string {
 
 // 定义错误码
 var (
-	Success            = &ErrCode{Code: 0, Message: "success"}
-	InternalError      = &ErrCode{Code: 10001, Message: "internal server error"}
-	InvalidParams      = &ErrCode{Code: 10002, Message: "invalid parameters"}
-	AIServiceError     = &ErrCode{Code: 20001, Message: "AI service error"}
-	AIServiceTimeout   = &ErrCode{Code: 20002, Message: "AI service timeout"}
-	ConfigLoadError    = &ErrCode{Code: 30001, Message: "config load error"}
+	Success              = &ErrCode{Code: 0, Message: "success", HTTPStatus: http.StatusOK, Reason: "OK"}
+	InternalError        = &ErrCode{Code: 10001, Message: "internal server error", HTTPStatus: http.StatusInternalServerError, Reason: "INTERNAL_ERROR"}
+	InvalidParams        = &ErrCode{Code: 10002, Message: "invalid parameters", HTTPStatus: http.StatusBadRequest, Reason: "INVALID_PARAMS"}
+	NotFound             = &ErrCode{Code: 10003, Message: "resource not found", HTTPStatus: http.StatusNotFound, Reason: "NOT_FOUND"}
+	PayloadTooLarge      = &ErrCode{Code: 10004, Message: "payload too large", HTTPStatus: http.StatusRequestEntityTooLarge, Reason: "PAYLOAD_TOO_LARGE"}
+	UnsupportedMediaType = &ErrCode{Code: 10005, Message: "unsupported media type", HTTPStatus: http.StatusUnsupportedMediaType, Reason: "UNSUPPORTED_MEDIA_TYPE"}
+	MethodNotAllowed     = &ErrCode{Code: 10006, Message: "method not allowed", HTTPStatus: http.StatusMethodNotAllowed, Reason: "METHOD_NOT_ALLOWED"}
+	Unauthorized         = &ErrCode{Code: 10007, Message: "unauthorized", HTTPStatus: http.StatusUnauthorized, Reason: "UNAUTHORIZED"}
+
+	AIServiceError     = &ErrCode{Code: 20001, Message: "AI service error", HTTPStatus: http.StatusBadGateway, Reason: "AI_SERVICE_ERROR", Retryable: true}
+	AIServiceTimeout   = &ErrCode{Code: 20002, Message: "AI service timeout", HTTPStatus: http.StatusGatewayTimeout, Reason: "AI_SERVICE_TIMEOUT", Retryable: true}
+	AIServiceBusy      = &ErrCode{Code: 20003, Message: "AI service is busy", HTTPStatus: http.StatusServiceUnavailable, Reason: "AI_SERVICE_BUSY", Retryable: true}
+	AIServiceRejected  = &ErrCode{Code: 20004, Message: "AI service rejected the request", HTTPStatus: http.StatusBadGateway, Reason: "AI_SERVICE_REJECTED"}
+	AIResponseInvalid  = &ErrCode{Code: 20005, Message: "AI response could not be parsed", HTTPStatus: http.StatusBadGateway, Reason: "AI_RESPONSE_INVALID", Retryable: true}
+	AIServiceCancelled = &ErrCode{Code: 20006, Message: "request cancelled", HTTPStatus: 499, Reason: "REQUEST_CANCELLED"}
+
+	ConfigLoadError = &ErrCode{Code: 30001, Message: "config load error", HTTPStatus: http.StatusInternalServerError, Reason: "CONFIG_LOAD_ERROR"}
+
+	SourceFetchFailed  = &ErrCode{Code: 40001, Message: "failed to fetch source", HTTPStatus: http.StatusBadGateway, Reason: "SOURCE_FETCH_FAILED", Retryable: true}
+	SourceFetchTimeout = &ErrCode{Code: 40002, Message: "source fetch timeout", HTTPStatus: http.StatusGatewayTimeout, Reason: "SOURCE_FETCH_TIMEOUT", Retryable: true}
 )
 
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error 携带错误码的业务错误
+// Detail（或 DetailKey 对应的本地化文案）和 Fields 会返回给客户端；Cause 只用于日志，不会返回给客户端
+type Error struct {
+	Code       *ErrCode
+	Detail     string
+	DetailKey  string        // 文案目录中的 key，返回时按请求语言本地化，优先于 Detail
+	DetailArgs []interface{} // DetailKey 的格式化参数
+	Fields     []FieldError
+	Cause      error
+}
+
+func (e *Error) Error() string {
+	msg := e.Code.Message
+	switch {
+	case e.Detail != "":
+		msg = msg + ": " + e.Detail
+	case e.DetailKey != "":
+		msg = msg + ": " + e.DetailKey + fmt.Sprint(e.DetailArgs...)
+	}
+	if e.Cause != nil {
+		msg = msg + ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+// Unwrap 返回底层错误
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
 // NewError 创建新的错误
 func NewError(errCode *ErrCode, detail string) error {
-	return fmt.Errorf("%s: %s", errCode.Message, detail)
+	return &Error{Code: errCode, Detail: detail}
+}
+
+// Wrap 使用错误码包装底层错误，detail 为可以返回给客户端的说明
+func Wrap(errCode *ErrCode, cause error, detail string) error {
+	return &Error{Code: errCode, Detail: detail, Cause: cause}
+}
+
+// NewLocalizedError 创建 detail 需要本地化的错误，key 为文案目录中的 key
+func NewLocalizedError(errCode *ErrCode, key string, args ...interface{}) error {
+	return &Error{Code: errCode, DetailKey: key, DetailArgs: args}
 }
 
+// WrapLocalized 使用错误码包装底层错误，detail 需要本地化
+func WrapLocalized(errCode *ErrCode, cause error, key string, args ...interface{}) error {
+	return &Error{Code: errCode, DetailKey: key, DetailArgs: args, Cause: cause}
+}
+
+// NewValidationError 创建字段级校验错误
+func NewValidationError(fields ...FieldError) error {
+	return &Error{Code: InvalidParams, Fields: fields}
+}
+
+// From 从错误链中提取业务错误，未识别的错误统一视为内部错误
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var code *ErrCode
+	if errors.As(err, &code) {
+		return &Error{Code: code}
+	}
+	return &Error{Code: InternalError, Cause: err}
+}
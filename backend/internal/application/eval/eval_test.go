@@ -0,0 +1,144 @@
+package eval
+
+import (
+	"ai-note-service/internal/application/app"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/schema"
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixturesDir 仓库中的示例用例及录制响应
+var fixturesDir = filepath.Join("..", "..", "..", "testdata", "eval")
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"勾股定理", "勾股定理", 1, 1},
+		{"Pythagorean Theorem", "pythagorean theorem!", 1, 1},
+		{"直角三角形", "直角三角形的性质", 0.8, 1},
+		{"Photosynthesis", "photosynthesis process", 0.8, 1},
+		{"勾股数", "勾股定理的证明", 0, 0.3},
+		{"光合作用", "呼吸作用", 0, 0.6},
+		{"", "勾股定理", 0, 0},
+	}
+	for _, tt := range tests {
+		got := Similarity(tt.a, tt.b)
+		if got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q) = %.2f, want [%.2f, %.2f]", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestMatchKeyPointsIsOneToOne(t *testing.T) {
+	expected := []ExpectedKeyPoint{
+		{Title: "勾股定理", Category: "数学"},
+		{Title: "勾股定理的应用"},
+		{Title: "相似三角形", Category: "数学"},
+	}
+	actual := []schema.KnowledgePoint{
+		{Title: "勾股定理的应用", Category: "数学"},
+		{Title: "勾股定理", Category: "几何"},
+	}
+
+	matches := MatchKeyPoints(expected, actual, DefaultThreshold)
+	if matches[0].Actual != "勾股定理" || matches[1].Actual != "勾股定理的应用" {
+		t.Fatalf("exact titles should match each other first: %+v", matches)
+	}
+	if c := matches[0].CategoryCorrect; c == nil || *c {
+		t.Errorf("category 数学 vs 几何 should be incorrect: %+v", matches[0])
+	}
+	if matches[1].CategoryCorrect != nil {
+		t.Errorf("category is not checked without an expected category: %+v", matches[1])
+	}
+	if matches[2].Actual != "" {
+		t.Errorf("相似三角形 should be unmatched: %+v", matches[2])
+	}
+}
+
+func TestRunReplayFixtures(t *testing.T) {
+	cases, err := LoadCases(fixturesDir)
+	if err != nil {
+		t.Fatalf("LoadCases: %v", err)
+	}
+	upstream, err := NewUpstream(ModeReplay, "", filepath.Join(fixturesDir, "recordings"), cases)
+	if err != nil {
+		t.Fatalf("NewUpstream: %v", err)
+	}
+	baseURL, err := upstream.Start()
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer upstream.Close()
+
+	c, err := app.New(global.Static(&global.AppConfig{
+		AI: global.AIConfig{BaseURL: baseURL, DefaultModel: "test-model", Timeout: 5},
+	}), app.Options{Feedback: feedback.NewMemoryStore()})
+	if err != nil {
+		t.Fatalf("app.New: %v", err)
+	}
+
+	report := Run(context.Background(), c.ImageAnalysis, upstream, cases, Options{
+		Mode:     ModeReplay,
+		Model:    "test-model",
+		Language: i18n.ZhCN,
+	})
+
+	s := report.Summary
+	if s.Cases != 3 || s.Valid != 2 || s.Responded != 3 || s.Errors != 0 {
+		t.Errorf("cases/valid/responded/errors = %d/%d/%d/%d, want 3/2/3/0", s.Cases, s.Valid, s.Responded, s.Errors)
+	}
+	if s.ExpectedKeyPoints != 6 || s.MatchedKeyPoints != 4 {
+		t.Errorf("matched %d of %d key points, want 4 of 6", s.MatchedKeyPoints, s.ExpectedKeyPoints)
+	}
+	if math.Abs(s.CategoryAccuracy-1) > 1e-9 {
+		t.Errorf("category accuracy = %.2f, want 1", s.CategoryAccuracy)
+	}
+	if s.Tokens.Total != 6002 {
+		t.Errorf("total tokens = %d, want 6002", s.Tokens.Total)
+	}
+	if len(report.PromptVersions) != 1 || !strings.HasPrefix(report.PromptVersions[0], "analysis/zh-CN@") {
+		t.Errorf("prompt versions = %v", report.PromptVersions)
+	}
+	if c := report.Cases[2]; c.Name != "truncated" || c.Valid || c.ErrorReason != "AI_RESPONSE_INVALID" {
+		t.Errorf("truncated case = %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("report JSON is invalid: %v", err)
+	}
+	buf.Reset()
+	if err := report.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown: %v", err)
+	}
+	if !strings.Contains(buf.String(), "| Key-point recall | 66.7% (4/6) |") {
+		t.Errorf("markdown summary missing recall row:\n%s", buf.String())
+	}
+}
+
+func TestReplayMissingRecording(t *testing.T) {
+	cases := []*Case{{Name: "unknown", Image: []byte("not recorded")}}
+	upstream, err := NewUpstream(ModeReplay, "", t.TempDir(), cases)
+	if err != nil {
+		t.Fatalf("NewUpstream: %v", err)
+	}
+	status, _, err := upstream.replay("unknown")
+	if err != nil || status != 404 {
+		t.Errorf("replay of missing recording = %d, %v; want 404", status, err)
+	}
+}
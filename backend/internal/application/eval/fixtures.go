@@ -0,0 +1,114 @@
+package eval
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// imageExts 支持的图片扩展名，与上传接口一致
+var imageExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
+
+// ExpectedKeyPoint 期望识别出的重点知识点
+type ExpectedKeyPoint struct {
+	Title    string `yaml:"title" json:"title"`
+	Category string `yaml:"category,omitempty" json:"category,omitempty"` // 为空时不检查分类
+}
+
+// Expected 用例的期望结果，保存在与图片同名的 .yaml 文件中
+type Expected struct {
+	KeyPoints []ExpectedKeyPoint `yaml:"keyPoints"`
+}
+
+// Case 评估用例
+type Case struct {
+	Name     string    // 图片文件名（不含扩展名）
+	Path     string    // 图片路径
+	Image    []byte    // 图片内容
+	Expected *Expected // 没有期望结果文件时为 nil，只统计结构合法率、延迟和 token 用量
+}
+
+// ImageSum 返回图片内容的 sha256，用于将上游请求对应到用例
+func (c *Case) ImageSum() string {
+	sum := sha256.Sum256(c.Image)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadCases 加载目录下的所有图片用例，按名称排序
+// 目录结构示例：
+//
+//	fixtures/
+//	  pythagorean.png
+//	  pythagorean.yaml   # keyPoints: [{title: 勾股定理, category: 数学}]
+func LoadCases(dir string) ([]*Case, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []*Case
+	names := make(map[string]string)
+	var errs []error
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !imageExts[ext] {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if other, ok := names[name]; ok {
+			errs = append(errs, fmt.Errorf("%s: duplicate case name, also used by %s", entry.Name(), other))
+			continue
+		}
+		names[name] = entry.Name()
+
+		c := &Case{Name: name, Path: filepath.Join(dir, entry.Name())}
+		if c.Image, err = os.ReadFile(c.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if c.Expected, err = loadExpected(filepath.Join(dir, name+".yaml")); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		cases = append(cases, c)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no images found in %s", dir)
+	}
+
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// loadExpected 读取期望结果文件，文件不存在时返回 nil
+func loadExpected(path string) (*Expected, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var expected Expected
+	if err := yaml.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+	}
+	for i, kp := range expected.KeyPoints {
+		if strings.TrimSpace(kp.Title) == "" {
+			return nil, fmt.Errorf("%s: keyPoints[%d].title is required", filepath.Base(path), i)
+		}
+	}
+	return &expected, nil
+}
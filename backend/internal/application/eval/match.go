@@ -0,0 +1,111 @@
+package eval
+
+import (
+	"ai-note-service/internal/application/schema"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold 默认的标题相似度阈值
+const DefaultThreshold = 0.6
+
+// Match 期望知识点与实际知识点的匹配结果
+type Match struct {
+	Expected         string  `json:"expected"`
+	Actual           string  `json:"actual,omitempty"` // 未匹配时为空
+	Similarity       float64 `json:"similarity,omitempty"`
+	ExpectedCategory string  `json:"expectedCategory,omitempty"`
+	ActualCategory   string  `json:"actualCategory,omitempty"`
+	CategoryCorrect  *bool   `json:"categoryCorrect,omitempty"` // 未匹配或未指定期望分类时为 nil
+}
+
+// MatchKeyPoints 按标题相似度将期望知识点与实际知识点一一匹配
+// 相似度从高到低贪心匹配，低于 threshold 的视为未匹配；返回结果与 expected 顺序一致
+func MatchKeyPoints(expected []ExpectedKeyPoint, actual []schema.KnowledgePoint, threshold float64) []Match {
+	type pair struct {
+		e, a int
+		sim  float64
+	}
+	var pairs []pair
+	for i, e := range expected {
+		for j, a := range actual {
+			if sim := Similarity(e.Title, a.Title); sim >= threshold {
+				pairs = append(pairs, pair{e: i, a: j, sim: sim})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].sim > pairs[j].sim })
+
+	matches := make([]Match, len(expected))
+	for i, e := range expected {
+		matches[i] = Match{Expected: e.Title, ExpectedCategory: e.Category}
+	}
+	usedExpected := make([]bool, len(expected))
+	usedActual := make([]bool, len(actual))
+	for _, p := range pairs {
+		if usedExpected[p.e] || usedActual[p.a] {
+			continue
+		}
+		usedExpected[p.e], usedActual[p.a] = true, true
+
+		m := &matches[p.e]
+		m.Actual = actual[p.a].Title
+		m.Similarity = p.sim
+		m.ActualCategory = actual[p.a].Category
+		if m.ExpectedCategory != "" {
+			correct := normalize(m.ExpectedCategory) == normalize(m.ActualCategory)
+			m.CategoryCorrect = &correct
+		}
+	}
+	return matches
+}
+
+// Similarity 计算两个标题的相似度（0-1）：归一化后按字符二元组计算 Dice 系数，
+// 对中文（按字）和英文（按字母）都适用；一方包含另一方时至少为 0.8
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(normalize(a)), []rune(normalize(b))
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	if string(ra) == string(rb) {
+		return 1
+	}
+
+	var contained float64
+	if strings.Contains(string(ra), string(rb)) || strings.Contains(string(rb), string(ra)) {
+		contained = 0.8
+	}
+
+	if len(ra) < 2 || len(rb) < 2 {
+		return contained
+	}
+	grams := make(map[string]int)
+	for i := 0; i+1 < len(ra); i++ {
+		grams[string(ra[i:i+2])]++
+	}
+	var common int
+	for i := 0; i+1 < len(rb); i++ {
+		g := string(rb[i : i+2])
+		if grams[g] > 0 {
+			grams[g]--
+			common++
+		}
+	}
+	dice := 2 * float64(common) / float64(len(ra)-1+len(rb)-1)
+	if dice < contained {
+		return contained
+	}
+	return dice
+}
+
+// normalize 转为小写并去掉空白和标点
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
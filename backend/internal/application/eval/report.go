@@ -0,0 +1,352 @@
+package eval
+
+import (
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/schema"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Analyzer 图片分析接口，由 service.ImageAnalysisService 实现
+type Analyzer interface {
+	AnalyzeImageData(ctx context.Context, imageData []byte, lang i18n.Language) (*schema.KnowledgeAnalysisResponse, error)
+}
+
+// UsageSource 查询用例的 token 用量
+type UsageSource interface {
+	Usage(name string) (schema.Usage, bool)
+}
+
+// Options 评估参数
+type Options struct {
+	Mode      string
+	Model     string
+	Variant   string
+	Language  i18n.Language
+	Threshold float64
+}
+
+// Report 评估报告，字段顺序固定、不含时间戳，便于在提示词版本之间 diff
+type Report struct {
+	Mode           string       `json:"mode"`
+	Model          string       `json:"model"`
+	Variant        string       `json:"variant,omitempty"`
+	Language       string       `json:"language"`
+	Threshold      float64      `json:"threshold"`
+	PromptVersions []string     `json:"promptVersions"`
+	Summary        Summary      `json:"summary"`
+	Cases          []CaseResult `json:"cases"`
+}
+
+// Summary 汇总指标
+type Summary struct {
+	Cases              int     `json:"cases"`
+	Responded          int     `json:"responded"` // 模型有返回的用例数（不含调用失败）
+	Valid              int     `json:"valid"`     // 响应结构合法的用例数
+	Errors             int     `json:"errors"`    // 调用失败的用例数
+	SchemaValidityRate float64 `json:"schemaValidityRate"`
+
+	ExpectedKeyPoints int     `json:"expectedKeyPoints"`
+	MatchedKeyPoints  int     `json:"matchedKeyPoints"`
+	KeyPointRecall    float64 `json:"keyPointRecall"`
+
+	CategoryChecked  int     `json:"categoryChecked"`
+	CategoryCorrect  int     `json:"categoryCorrect"`
+	CategoryAccuracy float64 `json:"categoryAccuracy"`
+
+	Latency LatencyStats `json:"latency"`
+	Tokens  TokenStats   `json:"tokens"`
+}
+
+// LatencyStats 延迟统计（毫秒）
+type LatencyStats struct {
+	Avg float64 `json:"avgMs"`
+	P50 float64 `json:"p50Ms"`
+	P95 float64 `json:"p95Ms"`
+	Max float64 `json:"maxMs"`
+}
+
+// TokenStats token 用量统计
+type TokenStats struct {
+	Prompt        int     `json:"prompt"`
+	Completion    int     `json:"completion"`
+	Total         int     `json:"total"`
+	AvgPrompt     float64 `json:"avgPrompt"`
+	AvgCompletion float64 `json:"avgCompletion"`
+}
+
+// CaseResult 单个用例的结果
+type CaseResult struct {
+	Name             string   `json:"name"`
+	Valid            bool     `json:"valid"`
+	Error            string   `json:"error,omitempty"`
+	ErrorReason      string   `json:"errorReason,omitempty"`
+	PromptVersion    string   `json:"promptVersion,omitempty"`
+	LatencyMs        float64  `json:"latencyMs"`
+	PromptTokens     int      `json:"promptTokens"`
+	CompletionTokens int      `json:"completionTokens"`
+	KeyPoints        []string `json:"keyPoints,omitempty"` // 实际识别出的重点知识点标题
+	Matches          []Match  `json:"matches,omitempty"`
+	Recall           *float64 `json:"recall,omitempty"` // 没有期望结果时为 nil
+}
+
+// Run 依次分析每个用例并生成报告；用例之间串行执行，保证延迟数据可比
+func Run(ctx context.Context, analyzer Analyzer, usage UsageSource, cases []*Case, opts Options) *Report {
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	report := &Report{
+		Mode:      opts.Mode,
+		Model:     opts.Model,
+		Variant:   opts.Variant,
+		Language:  string(opts.Language),
+		Threshold: opts.Threshold,
+	}
+
+	for _, c := range cases {
+		start := time.Now()
+		result, err := analyzer.AnalyzeImageData(ctx, c.Image, opts.Language)
+		latency := time.Since(start)
+
+		cr := CaseResult{Name: c.Name, LatencyMs: milliseconds(latency)}
+		if u, ok := usage.Usage(c.Name); ok {
+			cr.PromptTokens = u.PromptTokens
+			cr.CompletionTokens = u.CompletionTokens
+		}
+		if err != nil {
+			cr.Error = err.Error()
+			cr.ErrorReason = errcode.From(err).Code.Reason
+		} else {
+			cr.Valid = true
+			cr.PromptVersion = result.PromptVersion
+			for _, kp := range result.KeyPoints {
+				cr.KeyPoints = append(cr.KeyPoints, kp.Title)
+			}
+		}
+		if c.Expected != nil && len(c.Expected.KeyPoints) > 0 {
+			var actual []schema.KnowledgePoint
+			if result != nil {
+				actual = result.KeyPoints
+			}
+			cr.Matches = MatchKeyPoints(c.Expected.KeyPoints, actual, opts.Threshold)
+			recall := float64(matched(cr.Matches)) / float64(len(cr.Matches))
+			cr.Recall = &recall
+		}
+		report.Cases = append(report.Cases, cr)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	report.summarize()
+	return report
+}
+
+// summarize 计算汇总指标
+func (r *Report) summarize() {
+	s := &r.Summary
+	versions := make(map[string]bool)
+	var latencies []float64
+	var tokenCases int
+	for _, c := range r.Cases {
+		s.Cases++
+		latencies = append(latencies, c.LatencyMs)
+		switch {
+		case c.Valid:
+			s.Responded++
+			s.Valid++
+			versions[c.PromptVersion] = true
+		case c.ErrorReason == errcode.AIResponseInvalid.Reason:
+			s.Responded++
+		default:
+			s.Errors++
+		}
+
+		if c.PromptTokens > 0 || c.CompletionTokens > 0 {
+			tokenCases++
+			s.Tokens.Prompt += c.PromptTokens
+			s.Tokens.Completion += c.CompletionTokens
+		}
+
+		for _, m := range c.Matches {
+			s.ExpectedKeyPoints++
+			if m.Actual != "" {
+				s.MatchedKeyPoints++
+			}
+			if m.CategoryCorrect != nil {
+				s.CategoryChecked++
+				if *m.CategoryCorrect {
+					s.CategoryCorrect++
+				}
+			}
+		}
+	}
+
+	s.SchemaValidityRate = ratio(s.Valid, s.Responded)
+	s.KeyPointRecall = ratio(s.MatchedKeyPoints, s.ExpectedKeyPoints)
+	s.CategoryAccuracy = ratio(s.CategoryCorrect, s.CategoryChecked)
+	s.Tokens.Total = s.Tokens.Prompt + s.Tokens.Completion
+	if tokenCases > 0 {
+		s.Tokens.AvgPrompt = float64(s.Tokens.Prompt) / float64(tokenCases)
+		s.Tokens.AvgCompletion = float64(s.Tokens.Completion) / float64(tokenCases)
+	}
+
+	if len(latencies) > 0 {
+		sort.Float64s(latencies)
+		var total float64
+		for _, l := range latencies {
+			total += l
+		}
+		s.Latency = LatencyStats{
+			Avg: round(total / float64(len(latencies))),
+			P50: percentile(latencies, 0.50),
+			P95: percentile(latencies, 0.95),
+			Max: latencies[len(latencies)-1],
+		}
+	}
+
+	r.PromptVersions = make([]string, 0, len(versions))
+	for v := range versions {
+		r.PromptVersions = append(r.PromptVersions, v)
+	}
+	sort.Strings(r.PromptVersions)
+}
+
+// WriteJSON 以 JSON 格式输出报告
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown 以 Markdown 格式输出报告
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	s := r.Summary
+
+	b.WriteString("# Image analysis evaluation\n\n")
+	fmt.Fprintf(&b, "- Mode: %s\n", r.Mode)
+	fmt.Fprintf(&b, "- Model: %s\n", r.Model)
+	if r.Variant != "" {
+		fmt.Fprintf(&b, "- Prompt variant: %s\n", r.Variant)
+	}
+	fmt.Fprintf(&b, "- Language: %s\n", r.Language)
+	fmt.Fprintf(&b, "- Prompt versions: %s\n", strings.Join(backquote(r.PromptVersions), ", "))
+	fmt.Fprintf(&b, "- Match threshold: %.2f\n\n", r.Threshold)
+
+	b.WriteString("## Summary\n\n| Metric | Value |\n| --- | --- |\n")
+	fmt.Fprintf(&b, "| Cases | %d |\n", s.Cases)
+	fmt.Fprintf(&b, "| Call errors | %d |\n", s.Errors)
+	fmt.Fprintf(&b, "| Schema validity | %s (%d/%d) |\n", percent(s.SchemaValidityRate), s.Valid, s.Responded)
+	fmt.Fprintf(&b, "| Key-point recall | %s (%d/%d) |\n", percent(s.KeyPointRecall), s.MatchedKeyPoints, s.ExpectedKeyPoints)
+	fmt.Fprintf(&b, "| Category accuracy | %s (%d/%d) |\n", percent(s.CategoryAccuracy), s.CategoryCorrect, s.CategoryChecked)
+	fmt.Fprintf(&b, "| Latency avg / p50 / p95 / max | %.0f / %.0f / %.0f / %.0f ms |\n", s.Latency.Avg, s.Latency.P50, s.Latency.P95, s.Latency.Max)
+	fmt.Fprintf(&b, "| Tokens prompt / completion (avg) | %.0f / %.0f |\n", s.Tokens.AvgPrompt, s.Tokens.AvgCompletion)
+	fmt.Fprintf(&b, "| Tokens total | %d |\n\n", s.Tokens.Total)
+
+	b.WriteString("## Cases\n\n| Case | Valid | Recall | Latency (ms) | Tokens | Missing key points | Error |\n| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, c := range r.Cases {
+		recall := "-"
+		if c.Recall != nil {
+			recall = percent(*c.Recall)
+		}
+		var missing []string
+		for _, m := range c.Matches {
+			if m.Actual == "" {
+				missing = append(missing, m.Expected)
+			}
+		}
+		valid := "yes"
+		if !c.Valid {
+			valid = "no"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %.0f | %d | %s | %s |\n",
+			escapeCell(c.Name), valid, recall, c.LatencyMs, c.PromptTokens+c.CompletionTokens,
+			escapeCell(strings.Join(missing, ", ")), escapeCell(c.ErrorReason))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Write 按格式（json 或 md）输出报告
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		return r.WriteJSON(w)
+	case "md", "markdown":
+		return r.WriteMarkdown(w)
+	default:
+		return errors.New("unknown report format " + format)
+	}
+}
+
+// matched 返回已匹配的数量
+func matched(matches []Match) int {
+	var n int
+	for _, m := range matches {
+		if m.Actual != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// ratio 计算比例，分母为 0 时返回 0
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// percentile 返回已排序样本的分位数（最近秩法）
+func percentile(sorted []float64, p float64) float64 {
+	idx := int(p*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// milliseconds 将时间间隔转换为毫秒，保留一位小数
+func milliseconds(d time.Duration) float64 {
+	return round(float64(d) / float64(time.Millisecond))
+}
+
+// round 保留一位小数，减少报告 diff 中的噪音
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// percent 格式化百分比
+func percent(v float64) string {
+	return fmt.Sprintf("%.1f%%", v*100)
+}
+
+// backquote 为每一项加上反引号
+func backquote(items []string) []string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = "`" + item + "`"
+	}
+	return quoted
+}
+
+// escapeCell 转义 Markdown 表格单元格中的竖线和换行
+func escapeCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
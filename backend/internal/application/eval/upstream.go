@@ -0,0 +1,224 @@
+package eval
+
+import (
+	"ai-note-service/internal/application/schema"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 上游模式
+const (
+	ModeLive   = "live"   // 转发到真实模型服务
+	ModeReplay = "replay" // 从录制文件返回响应，不访问网络
+)
+
+// dataURIPattern 请求中的 base64 图片
+var dataURIPattern = regexp.MustCompile(`data:image/[a-z+.-]+;base64,([A-Za-z0-9+/=]+)`)
+
+// Upstream 评估时使用的本地上游服务，服务配置的 ai.base_url 指向它
+// replay 模式从 <dir>/<用例名>.json 返回录制的 chat completions 响应；
+// live 模式转发到真实模型服务，dir 不为空时同时录制响应。两种模式都会记录每个用例的 token 用量
+type Upstream struct {
+	mode     string
+	target   string // live 模式的真实服务地址
+	dir      string
+	cases    map[string]string // 图片 sha256 -> 用例名
+	client   *http.Client
+	server   *http.Server
+	listener net.Listener
+
+	mu    sync.Mutex
+	usage map[string]schema.Usage
+}
+
+// NewUpstream 创建本地上游服务
+func NewUpstream(mode, target, dir string, cases []*Case) (*Upstream, error) {
+	switch mode {
+	case ModeReplay:
+		if dir == "" {
+			return nil, errors.New("replay mode requires a recordings directory")
+		}
+	case ModeLive:
+		if target == "" {
+			return nil, errors.New("live mode requires ai.base_url")
+		}
+		if dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q, expected %s or %s", mode, ModeLive, ModeReplay)
+	}
+
+	u := &Upstream{
+		mode:   mode,
+		target: strings.TrimSuffix(target, "/"),
+		dir:    dir,
+		cases:  make(map[string]string, len(cases)),
+		client: &http.Client{},
+		usage:  make(map[string]schema.Usage),
+	}
+	for _, c := range cases {
+		u.cases[c.ImageSum()] = c.Name
+	}
+	return u, nil
+}
+
+// Start 在本机随机端口启动服务，返回可用作 ai.base_url 的地址
+func (u *Upstream) Start() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	u.listener = listener
+	u.server = &http.Server{Handler: u}
+	go u.server.Serve(listener)
+	return "http://" + listener.Addr().String(), nil
+}
+
+// Close 关闭服务
+func (u *Upstream) Close() error {
+	if u.server == nil {
+		return nil
+	}
+	return u.server.Close()
+}
+
+// Usage 返回用例最近一次请求的 token 用量
+func (u *Upstream) Usage(name string) (schema.Usage, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	usage, ok := u.usage[name]
+	return usage, ok
+}
+
+// ServeHTTP 处理 chat completions 请求
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		writeUpstreamError(w, http.StatusNotFound, "only POST /chat/completions is supported")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeUpstreamError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	name, ok := u.caseFor(body)
+	if !ok {
+		writeUpstreamError(w, http.StatusBadRequest, "request image does not belong to any fixture")
+		return
+	}
+
+	var status int
+	var respBody []byte
+	if u.mode == ModeReplay {
+		status, respBody, err = u.replay(name)
+	} else {
+		status, respBody, err = u.forward(r, body, name)
+	}
+	if err != nil {
+		writeUpstreamError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	if status == http.StatusOK {
+		var resp schema.ChatResponse
+		if json.Unmarshal(respBody, &resp) == nil {
+			u.mu.Lock()
+			u.usage[name] = resp.Usage
+			u.mu.Unlock()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(respBody)
+}
+
+// caseFor 根据请求中的图片找到对应的用例
+func (u *Upstream) caseFor(body []byte) (string, bool) {
+	for _, m := range dataURIPattern.FindAllSubmatch(body, -1) {
+		data, err := base64.StdEncoding.DecodeString(string(m[1]))
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if name, ok := u.cases[hex.EncodeToString(sum[:])]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// replay 返回录制的响应
+func (u *Upstream) replay(name string) (int, []byte, error) {
+	data, err := os.ReadFile(u.recordingPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return http.StatusNotFound, upstreamError("no recording for fixture " + name), nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, data, nil
+}
+
+// forward 转发到真实模型服务，成功时录制响应
+func (u *Upstream) forward(r *http.Request, body []byte, name string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, u.target+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header = r.Header.Clone()
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if resp.StatusCode == http.StatusOK && u.dir != "" {
+		var indented bytes.Buffer
+		if json.Indent(&indented, respBody, "", "  ") == nil {
+			respBody = indented.Bytes()
+		}
+		if err := os.WriteFile(u.recordingPath(name), respBody, 0o644); err != nil {
+			return 0, nil, fmt.Errorf("save recording: %w", err)
+		}
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// recordingPath 用例录制文件路径
+func (u *Upstream) recordingPath(name string) string {
+	return filepath.Join(u.dir, name+".json")
+}
+
+// writeUpstreamError 以 OpenAI 兼容格式返回错误
+func writeUpstreamError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(upstreamError(message))
+}
+
+// upstreamError OpenAI 兼容的错误响应体
+func upstreamError(message string) []byte {
+	data, _ := json.Marshal(map[string]any{"error": map[string]string{"message": message, "type": "eval_upstream_error"}})
+	return data
+}
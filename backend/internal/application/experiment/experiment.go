@@ -0,0 +1,87 @@
+package experiment
+
+import (
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/identity"
+	"ai-note-service/internal/application/logger"
+	"ai-note-service/internal/application/schema"
+	"context"
+	"hash/fnv"
+)
+
+// 实验作用的对象
+const (
+	TargetAnalysis = "analysis" // 图片分析
+	TargetDialogue = "dialogue" // 知识点对话
+)
+
+// Assignment 请求被分配到的实验变体
+type Assignment struct {
+	Experiment    string
+	Variant       string
+	PromptVariant string
+	Model         string
+}
+
+// Info 返回写入结果中的实验信息，未参与实验时返回 nil
+func (a *Assignment) Info() *schema.ExperimentAssignment {
+	if a == nil {
+		return nil
+	}
+	return &schema.ExperimentAssignment{Name: a.Experiment, Variant: a.Variant}
+}
+
+// Assign 为请求分配实验变体，target 没有启用的实验时返回 nil
+// 分组单位为用户标识（X-User-ID），同一用户始终分到同一变体；未传入用户标识时按请求ID分组
+func Assign(ctx context.Context, experiments []global.ExperimentConfig, target string) *Assignment {
+	unit := identity.UserIDFrom(ctx)
+	if unit == "" {
+		unit = logger.RequestIDFrom(ctx)
+	}
+
+	for _, exp := range experiments {
+		if !exp.Enabled || exp.Target != target {
+			continue
+		}
+		variant, ok := pick(exp, unit)
+		if !ok {
+			return nil
+		}
+		return &Assignment{
+			Experiment:    exp.Name,
+			Variant:       variant.Name,
+			PromptVariant: variant.PromptVariant,
+			Model:         variant.Model,
+		}
+	}
+	return nil
+}
+
+// pick 按权重选择变体，对 实验名:分组单位 取哈希，保证同一单位分组稳定
+// 调整权重只会移动部分用户，实验名不同的实验之间分组相互独立
+func pick(exp global.ExperimentConfig, unit string) (global.VariantConfig, bool) {
+	total := 0
+	for _, v := range exp.Variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		return global.VariantConfig{}, false
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(exp.Name + ":" + unit))
+	bucket := int(h.Sum64() % uint64(total))
+
+	for _, v := range exp.Variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if bucket < v.Weight {
+			return v, true
+		}
+		bucket -= v.Weight
+	}
+	return global.VariantConfig{}, false
+}
@@ -0,0 +1,129 @@
+package experiment
+
+import (
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/identity"
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+var testExperiments = []global.ExperimentConfig{
+	{
+		Name:    "analysis-prompt",
+		Target:  TargetAnalysis,
+		Enabled: true,
+		Variants: []global.VariantConfig{
+			{Name: "control", Weight: 80},
+			{Name: "concise", Weight: 20, PromptVariant: "concise", Model: "model-b"},
+		},
+	},
+	{
+		Name:     "dialogue-old",
+		Target:   TargetDialogue,
+		Enabled:  false,
+		Variants: []global.VariantConfig{{Name: "a", Weight: 1}},
+	},
+}
+
+func TestAssignIsStickyPerUser(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), "user-42")
+	first := Assign(ctx, testExperiments, TargetAnalysis)
+	if first == nil {
+		t.Fatal("expected an assignment")
+	}
+	for i := 0; i < 20; i++ {
+		if got := Assign(ctx, testExperiments, TargetAnalysis); got.Variant != first.Variant {
+			t.Fatalf("assignment changed from %s to %s", first.Variant, got.Variant)
+		}
+	}
+}
+
+func TestAssignFollowsWeights(t *testing.T) {
+	counts := map[string]int{}
+	const users = 10000
+	for i := 0; i < users; i++ {
+		ctx := identity.WithUserID(context.Background(), fmt.Sprintf("user-%d", i))
+		counts[Assign(ctx, testExperiments, TargetAnalysis).Variant]++
+	}
+	if ratio := float64(counts["concise"]) / users; math.Abs(ratio-0.2) > 0.03 {
+		t.Errorf("concise ratio = %.3f, want about 0.2 (%v)", ratio, counts)
+	}
+}
+
+func TestAssignSkipsDisabledExperiments(t *testing.T) {
+	if a := Assign(context.Background(), testExperiments, TargetDialogue); a != nil {
+		t.Errorf("expected no assignment for disabled experiment, got %+v", a)
+	}
+}
+
+func TestTrackerReport(t *testing.T) {
+	tracker := NewTracker(100)
+	control := &Assignment{Experiment: "analysis-prompt", Variant: "control"}
+	concise := &Assignment{Experiment: "analysis-prompt", Variant: "concise"}
+
+	tracker.Record(control, Outcome{ResultID: "r1", Status: StatusSuccess, Latency: 100 * time.Millisecond, PromptTokens: 1000, CompletionTokens: 500})
+	tracker.Record(control, Outcome{Status: StatusParseError, Latency: 300 * time.Millisecond, PromptTokens: 1000, CompletionTokens: 700})
+	tracker.Record(control, Outcome{ResultID: "r2", Status: StatusCacheHit})
+	tracker.Record(concise, Outcome{Status: StatusError, Latency: time.Second})
+	tracker.Record(nil, Outcome{ResultID: "r3", Status: StatusSuccess})
+
+	if err := tracker.Rate("r1", 4); err != nil {
+		t.Fatalf("Rate(r1): %v", err)
+	}
+	if err := tracker.Rate("r3", 5); err != nil {
+		t.Fatalf("Rate(r3) for result outside experiments: %v", err)
+	}
+	if err := tracker.Rate("missing", 3); err != ErrUnknownResult {
+		t.Errorf("Rate(missing) = %v, want ErrUnknownResult", err)
+	}
+
+	reports := tracker.Report(testExperiments[:1])
+	if len(reports) != 1 || len(reports[0].Variants) != 2 {
+		t.Fatalf("unexpected report shape: %+v", reports)
+	}
+	c := reports[0].Variants[0]
+	if c.Name != "control" || c.Weight != 80 {
+		t.Errorf("variant metadata = %+v", c)
+	}
+	if c.Requests != 3 || c.CacheHits != 1 || c.Successes != 1 || c.ParseErrors != 1 {
+		t.Errorf("counts = %+v", c)
+	}
+	if c.SuccessRate != 0.5 || c.ParseSuccessRate != 0.5 {
+		t.Errorf("rates = %v, %v", c.SuccessRate, c.ParseSuccessRate)
+	}
+	if c.Latency.Avg != 200 || c.AvgPromptTokens != 1000 || c.AvgCompletionTokens != 600 {
+		t.Errorf("latency/tokens = %+v, %v, %v", c.Latency, c.AvgPromptTokens, c.AvgCompletionTokens)
+	}
+	if c.Ratings != 1 || c.AvgRating != 4 {
+		t.Errorf("ratings = %d, %v", c.Ratings, c.AvgRating)
+	}
+	if e := reports[0].Variants[1]; e.Errors != 1 || e.SuccessRate != 0 {
+		t.Errorf("concise = %+v", e)
+	}
+}
+
+func TestTrackerReportKeepsRemovedExperiments(t *testing.T) {
+	tracker := NewTracker(100)
+	tracker.Record(&Assignment{Experiment: "old", Variant: "a"}, Outcome{Status: StatusSuccess})
+
+	reports := tracker.Report(nil)
+	if len(reports) != 1 || reports[0].Name != "old" || reports[0].Enabled || reports[0].Variants[0].Requests != 1 {
+		t.Errorf("removed experiment not reported: %+v", reports)
+	}
+}
+
+func TestTrackerEvictsOldResults(t *testing.T) {
+	tracker := NewTracker(2)
+	for _, id := range []string{"r1", "r2", "r3"} {
+		tracker.Record(nil, Outcome{ResultID: id, Status: StatusSuccess})
+	}
+	if err := tracker.Rate("r1", 5); err != ErrUnknownResult {
+		t.Errorf("Rate(r1) = %v, want ErrUnknownResult after eviction", err)
+	}
+	if err := tracker.Rate("r3", 5); err != nil {
+		t.Errorf("Rate(r3): %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package experiment
+
+import (
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/schema"
+	"container/list"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status 单次请求的结果
+type Status int
+
+const (
+	StatusError      Status = iota // 调用失败（超时、上游错误等）
+	StatusParseError               // AI 返回内容无法解析或不完整
+	StatusSuccess
+	StatusCacheHit
+)
+
+const (
+	// DefaultMaxResults 默认保留的结果ID数量，用于将评分关联到变体
+	DefaultMaxResults = 10000
+	// latencySamples 每个变体保留的最近延迟样本数，用于计算分位数
+	latencySamples = 1024
+)
+
+// ErrUnknownResult 结果ID不存在或已过期
+var ErrUnknownResult = errors.New("unknown result")
+
+// Outcome 单次请求的结果信号
+type Outcome struct {
+	ResultID         string // 成功时的结果ID（分析ID或对话消息ID）
+	Status           Status
+	Latency          time.Duration
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// variantKey 实验变体索引
+type variantKey struct {
+	experiment string
+	variant    string
+}
+
+// variantStats 变体指标
+type variantStats struct {
+	requests, cacheHits, successes, parseErrors, errors int64
+
+	latencyTotal time.Duration
+	latencyCount int64
+	latencies    []time.Duration // 最近的延迟样本（环形缓冲）
+	next         int
+
+	promptTokens, completionTokens, tokenSamples int64
+
+	ratings, ratingTotal int64
+}
+
+// Tracker 实验结果统计，数据保存在内存中，重启后清空
+type Tracker struct {
+	mu         sync.Mutex
+	stats      map[variantKey]*variantStats
+	results    map[string]*list.Element // 结果ID -> 所属变体，用于关联评分
+	order      *list.List
+	maxResults int
+}
+
+// resultEntry 结果ID记录
+type resultEntry struct {
+	id  string
+	key *variantKey // 未参与实验时为 nil
+}
+
+// NewTracker 创建实验统计，maxResults 为保留的结果ID数量，超过后淘汰最早的记录
+func NewTracker(maxResults int) *Tracker {
+	return &Tracker{
+		stats:      make(map[variantKey]*variantStats),
+		results:    make(map[string]*list.Element),
+		order:      list.New(),
+		maxResults: maxResults,
+	}
+}
+
+// Record 记录一次请求的结果；未参与实验（a 为 nil）时只登记结果ID，以便后续评分
+func (t *Tracker) Record(a *Assignment, o Outcome) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var key *variantKey
+	if a != nil {
+		key = &variantKey{experiment: a.Experiment, variant: a.Variant}
+		t.variant(*key).add(o)
+	}
+
+	if o.ResultID != "" {
+		t.results[o.ResultID] = t.order.PushBack(&resultEntry{id: o.ResultID, key: key})
+		for t.order.Len() > t.maxResults {
+			oldest := t.order.Front()
+			t.order.Remove(oldest)
+			delete(t.results, oldest.Value.(*resultEntry).id)
+		}
+	}
+}
+
+// Rate 记录用户对结果的评分（1-5），结果ID不存在时返回 ErrUnknownResult
+func (t *Tracker) Rate(resultID string, score int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elem, ok := t.results[resultID]
+	if !ok {
+		return ErrUnknownResult
+	}
+	if key := elem.Value.(*resultEntry).key; key != nil {
+		stats := t.variant(*key)
+		stats.ratings++
+		stats.ratingTotal += int64(score)
+	}
+	return nil
+}
+
+// Report 汇总各实验变体的指标；配置中的实验按配置顺序在前，已移除但仍有数据的实验在后
+func (t *Tracker) Report(experiments []global.ExperimentConfig) []schema.ExperimentReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	index := make(map[string]int, len(experiments)) // 实验名 -> reports 下标
+	reported := make(map[variantKey]bool)
+	reports := make([]schema.ExperimentReport, 0, len(experiments))
+	for _, exp := range experiments {
+		report := schema.ExperimentReport{Name: exp.Name, Target: exp.Target, Enabled: exp.Enabled}
+		for _, v := range exp.Variants {
+			key := variantKey{experiment: exp.Name, variant: v.Name}
+			reported[key] = true
+
+			vr := t.variant(key).report()
+			vr.Name = v.Name
+			vr.Weight = v.Weight
+			vr.PromptVariant = v.PromptVariant
+			vr.Model = v.Model
+			report.Variants = append(report.Variants, vr)
+		}
+		index[exp.Name] = len(reports)
+		reports = append(reports, report)
+	}
+
+	// 已从配置中移除的实验或变体
+	var stale []variantKey
+	for key := range t.stats {
+		if !reported[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		if stale[i].experiment != stale[j].experiment {
+			return stale[i].experiment < stale[j].experiment
+		}
+		return stale[i].variant < stale[j].variant
+	})
+	for _, key := range stale {
+		idx, ok := index[key.experiment]
+		if !ok {
+			idx = len(reports)
+			index[key.experiment] = idx
+			reports = append(reports, schema.ExperimentReport{Name: key.experiment})
+		}
+		vr := t.stats[key].report()
+		vr.Name = key.variant
+		reports[idx].Variants = append(reports[idx].Variants, vr)
+	}
+
+	return reports
+}
+
+// variant 返回变体指标，不存在时创建；调用方需持有锁
+func (t *Tracker) variant(key variantKey) *variantStats {
+	stats, ok := t.stats[key]
+	if !ok {
+		stats = &variantStats{}
+		t.stats[key] = stats
+	}
+	return stats
+}
+
+// add 累加一次请求的结果
+func (s *variantStats) add(o Outcome) {
+	s.requests++
+	switch o.Status {
+	case StatusCacheHit:
+		s.cacheHits++
+		return
+	case StatusSuccess:
+		s.successes++
+	case StatusParseError:
+		s.parseErrors++
+	default:
+		s.errors++
+	}
+
+	s.latencyTotal += o.Latency
+	s.latencyCount++
+	if len(s.latencies) < latencySamples {
+		s.latencies = append(s.latencies, o.Latency)
+	} else {
+		s.latencies[s.next] = o.Latency
+		s.next = (s.next + 1) % latencySamples
+	}
+
+	if o.PromptTokens > 0 || o.CompletionTokens > 0 {
+		s.promptTokens += int64(o.PromptTokens)
+		s.completionTokens += int64(o.CompletionTokens)
+		s.tokenSamples++
+	}
+}
+
+// report 计算变体指标
+func (s *variantStats) report() schema.VariantReport {
+	r := schema.VariantReport{
+		Requests:    s.requests,
+		CacheHits:   s.cacheHits,
+		Successes:   s.successes,
+		ParseErrors: s.parseErrors,
+		Errors:      s.errors,
+		Ratings:     s.ratings,
+	}
+	if calls := s.requests - s.cacheHits; calls > 0 {
+		r.SuccessRate = float64(s.successes) / float64(calls)
+	}
+	if parsed := s.successes + s.parseErrors; parsed > 0 {
+		r.ParseSuccessRate = float64(s.successes) / float64(parsed)
+	}
+	if s.latencyCount > 0 {
+		r.Latency.Avg = milliseconds(s.latencyTotal / time.Duration(s.latencyCount))
+		sorted := append([]time.Duration(nil), s.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		r.Latency.P50 = milliseconds(percentile(sorted, 0.50))
+		r.Latency.P95 = milliseconds(percentile(sorted, 0.95))
+	}
+	if s.tokenSamples > 0 {
+		r.AvgPromptTokens = float64(s.promptTokens) / float64(s.tokenSamples)
+		r.AvgCompletionTokens = float64(s.completionTokens) / float64(s.tokenSamples)
+	}
+	if s.ratings > 0 {
+		r.AvgRating = float64(s.ratingTotal) / float64(s.ratings)
+	}
+	return r
+}
+
+// percentile 返回已排序样本的分位数（最近秩法）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// milliseconds 将时间间隔转换为毫秒
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
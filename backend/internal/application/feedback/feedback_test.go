@@ -0,0 +1,158 @@
+package feedback
+
+import (
+	"ai-note-service/internal/application/schema"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	testStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	treatment = &schema.ExperimentAssignment{Name: "analysis-prompt", Variant: "concise"}
+)
+
+func testRecords() []*Record {
+	return []*Record{
+		{ID: "f1", CreatedAt: testStart, Kind: KindAnalysis, Rating: "up", PromptVersion: "analysis/zh-CN@v1:aaa"},
+		{ID: "f2", CreatedAt: testStart.Add(time.Hour), Kind: KindAnalysis, Rating: "down", Reasons: []string{"incorrect", "unclear"}, PromptVersion: "analysis/zh-CN@v1:aaa"},
+		{ID: "f3", CreatedAt: testStart.Add(2 * time.Hour), Kind: KindAnalysis, Rating: "down", Reasons: []string{"incorrect"}, PromptVersion: "analysis+concise/zh-CN@v1:bbb", Experiment: treatment},
+		{ID: "f4", CreatedAt: testStart.Add(3 * time.Hour), Kind: KindMessage, Rating: "up", PromptVersion: "dialogue/en@v1:ccc", Reply: "hello"},
+	}
+}
+
+func addAll(t *testing.T, s *Store) {
+	t.Helper()
+	for _, r := range testRecords() {
+		if err := s.Add(r); err != nil {
+			t.Fatalf("Add(%s): %v", r.ID, err)
+		}
+	}
+}
+
+func checkSummary(t *testing.T, summary []schema.FeedbackSummary) {
+	t.Helper()
+	if len(summary) != 3 {
+		t.Fatalf("got %d summary rows, want 3: %+v", len(summary), summary)
+	}
+	control := summary[1]
+	if control.Kind != KindAnalysis || control.PromptVersion != "analysis/zh-CN@v1:aaa" {
+		t.Fatalf("unexpected row order: %+v", summary)
+	}
+	if control.Total != 2 || control.Up != 1 || control.Down != 1 || control.Reasons["incorrect"] != 1 || control.Reasons["unclear"] != 1 {
+		t.Errorf("control summary = %+v", control)
+	}
+	concise := summary[0]
+	if concise.Experiment == nil || concise.Experiment.Variant != "concise" || concise.Down != 1 {
+		t.Errorf("concise summary = %+v", concise)
+	}
+	if summary[2].Kind != KindMessage || summary[2].Up != 1 {
+		t.Errorf("message summary = %+v", summary[2])
+	}
+}
+
+func exportIDs(t *testing.T, s *Store, filter Filter) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := s.Export(&buf, filter); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var ids []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid JSONL line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestMemoryStoreSummaryAndExport(t *testing.T) {
+	s := NewMemoryStore()
+	addAll(t, s)
+	checkSummary(t, s.Summary())
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"f1", "f2", "f3", "f4"}},
+		{"kind", Filter{Kind: KindMessage}, []string{"f4"}},
+		{"rating", Filter{Rating: "down"}, []string{"f2", "f3"}},
+		{"since", Filter{Since: testStart.Add(2 * time.Hour)}, []string{"f3", "f4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exportIDs(t, s, tt.filter)
+			if len(got) != len(tt.want) {
+				t.Fatalf("exported %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("exported %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreRebuildsSummaryOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "feedback.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	addAll(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	checkSummary(t, reopened.Summary())
+
+	records := exportIDs(t, reopened, Filter{Kind: KindMessage})
+	if len(records) != 1 || records[0] != "f4" {
+		t.Errorf("exported %v, want [f4]", records)
+	}
+}
+
+func TestSummaryReturnsCopies(t *testing.T) {
+	s := NewMemoryStore()
+	addAll(t, s)
+	s.Summary()[0].Reasons["incorrect"] = 100
+	if got := s.Summary()[0].Reasons["incorrect"]; got != 1 {
+		t.Errorf("summary was modified through returned copy: %d", got)
+	}
+}
+
+func TestResultsLookupAndEviction(t *testing.T) {
+	r := NewResults(2)
+	r.Remember(&Snapshot{Kind: KindAnalysis, ID: "a1"})
+	r.Remember(&Snapshot{Kind: KindMessage, ID: "m1", ConversationID: "c1"})
+
+	if _, ok := r.Lookup(KindMessage, "a1"); ok {
+		t.Error("lookup with wrong kind should fail")
+	}
+	if _, ok := r.Lookup(KindAnalysis, "a1"); !ok {
+		t.Error("a1 should be present")
+	}
+
+	// a1 刚被访问过，但 Lookup 不影响淘汰顺序，最早保存的 a1 被淘汰
+	r.Remember(&Snapshot{Kind: KindAnalysis, ID: "a2"})
+	if _, ok := r.Lookup(KindAnalysis, "a1"); ok {
+		t.Error("a1 should have been evicted")
+	}
+	if s, ok := r.Lookup(KindMessage, "m1"); !ok || s.ConversationID != "c1" {
+		t.Errorf("m1 lookup = %+v, %v", s, ok)
+	}
+}
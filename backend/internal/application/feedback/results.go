@@ -0,0 +1,87 @@
+package feedback
+
+import (
+	"ai-note-service/internal/application/schema"
+	"container/list"
+	"sync"
+	"time"
+)
+
+// 反馈对象类型
+const (
+	KindAnalysis = "analysis" // 图片分析结果
+	KindMessage  = "message"  // 对话回复
+)
+
+// DefaultMaxResults 默认保留的结果快照数量
+const DefaultMaxResults = 5000
+
+// Snapshot 生成结果的快照，反馈时与反馈一起保存，供离线评估使用
+type Snapshot struct {
+	Kind             string
+	ID               string // 分析ID或对话消息ID
+	ConversationID   string // 仅对话回复
+	KnowledgePointID string // 仅对话回复
+	UserID           string
+	Language         string
+	PromptVersion    string
+	ProfileVersion   string
+	Experiment       *schema.ExperimentAssignment
+	CreatedAt        time.Time
+
+	Analysis            *schema.KnowledgeAnalysisResponse // 仅分析结果
+	KnowledgePointTitle string                            // 仅对话回复
+	UserMessage         string                            // 仅对话回复
+	Reply               string                            // 仅对话回复
+}
+
+// Results 最近生成结果的快照（内存，超过容量时淘汰最早保存的快照），重启后清空
+type Results struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// NewResults 创建结果快照，size 为最大保留数量
+func NewResults(size int) *Results {
+	return &Results{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Remember 保存结果快照
+func (r *Results) Remember(s *Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[s.ID]; ok {
+		elem.Value = s
+		r.order.MoveToFront(elem)
+		return
+	}
+	r.entries[s.ID] = r.order.PushFront(s)
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*Snapshot).ID)
+	}
+}
+
+// Lookup 按ID和类型查找结果快照
+func (r *Results) Lookup(kind, id string) (*Snapshot, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[id]
+	if !ok {
+		return nil, false
+	}
+	s := elem.Value.(*Snapshot)
+	if s.Kind != kind {
+		return nil, false
+	}
+	return s, true
+}
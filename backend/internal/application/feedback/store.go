@@ -0,0 +1,259 @@
+package feedback
+
+import (
+	"ai-note-service/internal/application/schema"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxRecordSize 单条反馈记录的最大字节数（读取文件时使用）
+const maxRecordSize = 4 << 20
+
+// Record 反馈记录，以 JSONL 格式保存，每行一条
+type Record struct {
+	ID             string    `json:"id"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Kind           string    `json:"kind"` // analysis | message
+	AnalysisID     string    `json:"analysisId,omitempty"`
+	ConversationID string    `json:"conversationId,omitempty"`
+	MessageID      string    `json:"messageId,omitempty"`
+	KeyPointID     string    `json:"keyPointId,omitempty"`
+	UserID         string    `json:"userId,omitempty"`
+
+	Rating  string   `json:"rating"` // up | down
+	Reasons []string `json:"reasons,omitempty"`
+	Comment string   `json:"comment,omitempty"`
+
+	// 被评价结果的上下文，用于离线评估
+	Language            string                            `json:"language,omitempty"`
+	PromptVersion       string                            `json:"promptVersion,omitempty"`
+	ProfileVersion      string                            `json:"profileVersion,omitempty"`
+	Experiment          *schema.ExperimentAssignment      `json:"experiment,omitempty"`
+	Analysis            *schema.KnowledgeAnalysisResponse `json:"analysis,omitempty"`
+	KnowledgePointID    string                            `json:"knowledgePointId,omitempty"`
+	KnowledgePointTitle string                            `json:"knowledgePointTitle,omitempty"`
+	UserMessage         string                            `json:"userMessage,omitempty"`
+	Reply               string                            `json:"reply,omitempty"`
+}
+
+// Filter 导出过滤条件，零值表示不过滤
+type Filter struct {
+	Kind   string
+	Rating string
+	Since  time.Time
+}
+
+// match 判断记录是否满足过滤条件
+func (f Filter) match(r *Record) bool {
+	return (f.Kind == "" || r.Kind == f.Kind) &&
+		(f.Rating == "" || r.Rating == f.Rating) &&
+		(f.Since.IsZero() || !r.CreatedAt.Before(f.Since))
+}
+
+// summaryKey 汇总维度
+type summaryKey struct {
+	kind          string
+	promptVersion string
+	experiment    string
+	variant       string
+}
+
+// Store 反馈存储
+// path 不为空时追加写入 JSONL 文件，启动时从文件恢复汇总数据；为空时只保存在内存中
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	records []*Record // 仅内存模式
+	summary map[summaryKey]*schema.FeedbackSummary
+}
+
+// NewMemoryStore 创建内存反馈存储
+func NewMemoryStore() *Store {
+	return &Store{summary: make(map[summaryKey]*schema.FeedbackSummary)}
+}
+
+// Open 打开文件反馈存储，文件不存在时创建
+func Open(path string) (*Store, error) {
+	if path == "" {
+		return NewMemoryStore(), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create feedback directory: %w", err)
+	}
+
+	s := &Store{path: path, summary: make(map[summaryKey]*schema.FeedbackSummary)}
+	if err := s.scan(Filter{}, func(r *Record) error {
+		s.aggregate(r)
+		return nil
+	}); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("load feedback: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open feedback file: %w", err)
+	}
+	s.file = file
+	return s, nil
+}
+
+// Add 保存反馈
+func (s *Store) Add(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != nil {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if _, err := s.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("write feedback: %w", err)
+		}
+	} else {
+		s.records = append(s.records, r)
+	}
+
+	s.aggregate(r)
+	return nil
+}
+
+// Summary 返回按对象类型、提示词版本和实验变体汇总的反馈
+func (s *Store) Summary() []schema.FeedbackSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]schema.FeedbackSummary, 0, len(s.summary))
+	for _, item := range s.summary {
+		copied := *item
+		copied.Reasons = make(map[string]int64, len(item.Reasons))
+		for k, v := range item.Reasons {
+			copied.Reasons[k] = v
+		}
+		list = append(list, copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.PromptVersion != b.PromptVersion {
+			return a.PromptVersion < b.PromptVersion
+		}
+		return experimentName(a.Experiment) < experimentName(b.Experiment)
+	})
+	return list
+}
+
+// Export 将满足条件的反馈以 JSONL 格式写入 w
+func (s *Store) Export(w io.Writer, filter Filter) error {
+	encoder := json.NewEncoder(w)
+	write := func(r *Record) error {
+		return encoder.Encode(r)
+	}
+
+	if s.path != "" {
+		err := s.scan(filter, write)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	records := append([]*Record(nil), s.records...)
+	s.mu.Unlock()
+	for _, r := range records {
+		if filter.match(r) {
+			if err := write(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close 关闭反馈文件
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// scan 逐行读取反馈文件，无法解析的行会被跳过
+func (s *Store) scan(filter Filter, fn func(*Record) error) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if filter.match(&r) {
+			if err := fn(&r); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// aggregate 累加汇总数据；调用方需持有锁（Open 时除外）
+func (s *Store) aggregate(r *Record) {
+	key := summaryKey{kind: r.Kind, promptVersion: r.PromptVersion}
+	if r.Experiment != nil {
+		key.experiment = r.Experiment.Name
+		key.variant = r.Experiment.Variant
+	}
+
+	item, ok := s.summary[key]
+	if !ok {
+		item = &schema.FeedbackSummary{
+			Kind:          r.Kind,
+			PromptVersion: r.PromptVersion,
+			Experiment:    r.Experiment,
+			Reasons:       make(map[string]int64),
+		}
+		s.summary[key] = item
+	}
+
+	item.Total++
+	switch r.Rating {
+	case "up":
+		item.Up++
+	case "down":
+		item.Down++
+	}
+	for _, reason := range r.Reasons {
+		item.Reasons[reason]++
+	}
+}
+
+// experimentName 返回排序用的实验名
+func experimentName(e *schema.ExperimentAssignment) string {
+	if e == nil {
+		return ""
+	}
+	return e.Name + "/" + e.Variant
+}
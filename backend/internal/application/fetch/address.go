@@ -0,0 +1,47 @@
+package fetch
+
+import (
+	"net"
+	"net/netip"
+)
+
+// reservedPrefixes 除 netip 已能识别的私有、回环、链路本地和组播地址外，其他不应从公网访问的地址段
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),   // 运营商级 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF 协议分配
+	netip.MustParsePrefix("192.0.2.0/24"),    // 文档示例 TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // 基准测试
+	netip.MustParsePrefix("198.51.100.0/24"), // 文档示例 TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // 文档示例 TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // 保留及广播地址
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64，可映射到内网 IPv4
+	netip.MustParsePrefix("2001:db8::/32"),   // 文档示例
+}
+
+// checkAddress 检查拨号地址（ip:port）是否为公网地址，否则返回 ErrForbiddenAddress
+func checkAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrForbiddenAddress
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !isPublic(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// isPublic 判断 IP 是否为可从公网访问的单播地址，IPv4 映射的 IPv6 地址按 IPv4 判断
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,201 @@
+// Package fetch 抓取用户提交的链接内容：只允许 http(s)，限制超时、大小和内容类型，并拒绝连接内网等保留地址（SSRF 防护）
+package fetch
+
+import (
+	"ai-note-service/internal/application/global"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultTimeout   = 15 // 秒
+	defaultMaxSizeMB = 20
+	maxRedirects     = 5
+	userAgent        = "ai-note-service/1.0"
+)
+
+var (
+	// ErrInvalidURL 链接格式错误或不是 http(s) 链接
+	ErrInvalidURL = errors.New("invalid URL")
+	// ErrForbiddenAddress 链接（含重定向）解析到内网、回环等保留地址
+	ErrForbiddenAddress = errors.New("address is not allowed")
+	// ErrTimeout 抓取超时
+	ErrTimeout = errors.New("fetch timeout")
+)
+
+// TooLargeError 内容超过大小上限
+type TooLargeError struct {
+	MaxSizeMB int
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("content exceeds %dMB", e.MaxSizeMB)
+}
+
+// UnsupportedTypeError 内容类型不在允许的范围内
+type UnsupportedTypeError struct {
+	ContentType string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported content type %q", e.ContentType)
+}
+
+// StatusError 链接返回非 2xx 状态码
+type StatusError struct {
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.Status)
+}
+
+// Resource 抓取到的内容
+type Resource struct {
+	URL         string // 重定向后的最终地址
+	ContentType string // 小写的媒体类型，不含参数，如 text/html
+	Charset     string // Content-Type 中的 charset 参数，未给出时为空
+	Data        []byte
+}
+
+// Fetcher 抓取链接内容，每次抓取时读取 fetch.* 配置，支持热加载
+type Fetcher struct {
+	config global.Provider
+	client *http.Client
+}
+
+// NewFetcher 创建抓取器；连接在拨号时按实际解析出的 IP 检查，防止 DNS 重绑定绕过
+func NewFetcher(config global.Provider) *Fetcher {
+	f := &Fetcher{config: config}
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			if config().Fetch.AllowPrivate {
+				return nil
+			}
+			return checkAddress(address)
+		},
+	}
+	f.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil, // 经代理访问时无法检查目标地址
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrInvalidURL
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// MaxSizeMB 内容大小上限
+func (f *Fetcher) MaxSizeMB() int {
+	if n := f.config().Fetch.MaxSizeMB; n > 0 {
+		return n
+	}
+	return defaultMaxSizeMB
+}
+
+// Fetch 抓取 rawURL，内容类型须在 accept 中（如 image/png）；响应头未给出类型时按内容推断
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string, accept []string) (*Resource, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return nil, ErrInvalidURL
+	}
+
+	timeout := time.Duration(f.config().Fetch.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", strings.Join(accept, ", "))
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, f.classify(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{Status: resp.StatusCode}
+	}
+	maxSizeMB := f.MaxSizeMB()
+	maxBytes := int64(maxSizeMB) << 20
+	if resp.ContentLength > maxBytes {
+		return nil, &TooLargeError{MaxSizeMB: maxSizeMB}
+	}
+
+	contentType, charset := mediaType(resp.Header.Get("Content-Type"))
+	if contentType != "" && contentType != "application/octet-stream" && !slices.Contains(accept, contentType) {
+		return nil, &UnsupportedTypeError{ContentType: contentType}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, f.classify(ctx, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, &TooLargeError{MaxSizeMB: maxSizeMB}
+	}
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType, _ = mediaType(http.DetectContentType(data))
+		if !slices.Contains(accept, contentType) {
+			return nil, &UnsupportedTypeError{ContentType: contentType}
+		}
+	}
+
+	return &Resource{URL: resp.Request.URL.String(), ContentType: contentType, Charset: charset, Data: data}, nil
+}
+
+// classify 将网络错误归类为 ErrForbiddenAddress、ErrInvalidURL 或 ErrTimeout，其余原样返回
+func (f *Fetcher) classify(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrForbiddenAddress):
+		return ErrForbiddenAddress
+	case errors.Is(err, ErrInvalidURL):
+		return ErrInvalidURL
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	return err
+}
+
+// mediaType 解析 Content-Type 中的媒体类型和 charset，失败时返回空字符串
+func mediaType(contentType string) (string, string) {
+	t, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ""
+	}
+	return strings.ToLower(t), params["charset"]
+}
@@ -1,32 +1,179 @@
 package global
 
 import (
-	"sync"
+	"sync/atomic"
 )
 
-// Config 全局配置
-var (
-	Config     *AppConfig
-	configOnce sync.Once
-)
+// current 当前生效的配置快照，热加载时整体替换
+var current atomic.Pointer[AppConfig]
+
+// Load 返回当前配置快照
+// 快照在替换后不会再被修改，调用方不应修改返回值；一次请求内应只读取一次，保证前后一致
+func Load() *AppConfig {
+	return current.Load()
+}
+
+// Store 替换当前配置快照
+func Store(cfg *AppConfig) {
+	current.Store(cfg)
+}
+
+// Provider 配置快照来源，服务在每次请求时调用，使热加载后的配置对后续请求生效
+// 服务进程中使用 Load，测试和命令行工具可用 Static 注入固定配置
+type Provider func() *AppConfig
+
+// Static 返回始终提供同一配置快照的 Provider
+func Static(cfg *AppConfig) Provider {
+	return func() *AppConfig {
+		return cfg
+	}
+}
 
 // AppConfig 应用配置结构
 type AppConfig struct {
-	Server ServerConfig `yaml:"server"`
-	AI     AIConfig     `yaml:"ai"`
+	Server     ServerConfig     `yaml:"server"`
+	AI         AIConfig         `yaml:"ai"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Log        LogConfig        `yaml:"log"`
+	Reload     ReloadConfig     `yaml:"reload"`
+	I18n       I18nConfig       `yaml:"i18n"`
+	Prompt     PromptConfig     `yaml:"prompt"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Analysis   AnalysisConfig   `yaml:"analysis"`
+	PDF        PDFConfig        `yaml:"pdf"`
+	Fetch      FetchConfig      `yaml:"fetch"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Feedback   FeedbackConfig   `yaml:"feedback"`
+	Profile    ProfileConfig    `yaml:"profile"`
+	Validation ValidationConfig `yaml:"validation"`
+
+	Experiments []ExperimentConfig `yaml:"experiments"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port int    `yaml:"port"`
-	Host string `yaml:"host"`
+	Port              int    `yaml:"port"`
+	Host              string `yaml:"host"`
+	ReadHeaderTimeout int    `yaml:"read_header_timeout"` // 读取请求头超时时间（秒）
+	ReadTimeout       int    `yaml:"read_timeout"`        // 读取完整请求超时时间（秒）
+	WriteTimeout      int    `yaml:"write_timeout"`       // 写响应超时时间（秒），需大于 AI 调用超时
+	IdleTimeout       int    `yaml:"idle_timeout"`        // keep-alive 空闲连接超时时间（秒）
+	MaxHeaderBytes    int    `yaml:"max_header_bytes"`    // 请求头最大字节数
+	ShutdownTimeout   int    `yaml:"shutdown_timeout"`    // 优雅关闭等待时间（秒）
 }
 
 // AIConfig AI服务配置
 type AIConfig struct {
 	BaseURL      string `yaml:"base_url"`
 	APIKey       string `yaml:"api_key"`
+	APIKeyFile   string `yaml:"api_key_file"` // 从文件读取 API 密钥，优先于 api_key
 	DefaultModel string `yaml:"default_model"`
 	Timeout      int    `yaml:"timeout"` // 超时时间（秒）
 }
 
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Exporter    string            `yaml:"exporter"`     // "otlp" 或 "stdout"
+	Endpoint    string            `yaml:"endpoint"`     // OTLP HTTP 地址，如 localhost:4318
+	Insecure    bool              `yaml:"insecure"`     // OTLP 是否使用明文 HTTP
+	Headers     map[string]string `yaml:"headers"`      // OTLP 额外请求头（如鉴权）
+	ServiceName string            `yaml:"service_name"` // 上报的服务名
+	SampleRatio float64           `yaml:"sample_ratio"` // 采样比例 0-1，0 表示使用默认值 1
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level          string `yaml:"level"`            // debug | info | warn | error
+	Format         string `yaml:"format"`           // json | text
+	LogUserContent bool   `yaml:"log_user_content"` // 是否记录用户内容原文，默认只记录长度
+}
+
+// ReloadConfig 配置热加载
+// 除 SIGHUP 外，开启 watch_file 后会定期检查配置文件是否变化
+type ReloadConfig struct {
+	WatchFile bool `yaml:"watch_file"`
+	Interval  int  `yaml:"interval"` // 检查间隔（秒）
+}
+
+// I18nConfig 多语言配置
+type I18nConfig struct {
+	DefaultLanguage string `yaml:"default_language"` // 未通过 lang 参数或 Accept-Language 指定时使用，默认 zh-CN
+}
+
+// PromptConfig 提示词模板配置
+type PromptConfig struct {
+	Dir string `yaml:"dir"` // 模板目录，其中的模板覆盖内置的同名模板；为空时只使用内置模板
+}
+
+// CacheConfig 图片分析结果缓存配置
+type CacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Size    int  `yaml:"size"` // 最大缓存条目数
+	TTL     int  `yaml:"ttl"`  // 过期时间（秒），0 表示不过期
+}
+
+// AnalysisConfig 图片分析配置
+type AnalysisConfig struct {
+	MaxImages        int `yaml:"max_images"`         // 单次分析最多上传的图片（页）数，0 表示使用默认值
+	ImagesPerRequest int `yaml:"images_per_request"` // 每次模型请求最多包含的图片数，超过时分批请求后合并结果，0 表示使用默认值
+	PageStoreMB      int `yaml:"page_store_mb"`      // 保留最近分析的页面图片供截取知识点区域，总大小上限（MB），0 表示使用默认值；重启后生效
+
+	// 默认分析深度和数量，可被请求参数覆盖
+	Depth          string `yaml:"depth"`          // quick、standard 或 deep，为空时为 standard
+	KeyPoints      int    `yaml:"key_points"`     // 重点知识点数量上限，0 表示使用深度预设
+	Prerequisites  int    `yaml:"prerequisites"`  // 前置知识点数量，0 表示使用深度预设
+	Postrequisites int    `yaml:"postrequisites"` // 后置知识点数量，0 表示使用深度预设
+	FunExamples    int    `yaml:"fun_examples"`   // 每个重点知识点的趣味示例数量，0 表示使用深度预设
+}
+
+// PDFConfig PDF 分析配置
+type PDFConfig struct {
+	MaxPages      int    `yaml:"max_pages"`      // 单次分析最多选中的页数，0 表示使用默认值
+	RenderCommand string `yaml:"render_command"` // 渲染无文字层页面的 pdftoppm 兼容命令，为空时跳过这些页面
+	RenderDPI     int    `yaml:"render_dpi"`     // 渲染分辨率，0 表示使用默认值 150
+}
+
+// FetchConfig 链接分析时抓取内容的配置
+type FetchConfig struct {
+	Timeout      int  `yaml:"timeout"`       // 抓取超时时间（秒），包含重定向和读取内容，0 表示使用默认值
+	MaxSizeMB    int  `yaml:"max_size_mb"`   // 内容大小上限（MB），0 表示使用默认值
+	AllowPrivate bool `yaml:"allow_private"` // 允许访问内网、回环等保留地址，仅用于本地开发和测试
+}
+
+// AdminConfig 管理接口配置
+type AdminConfig struct {
+	Token string `yaml:"token"` // 管理接口的 Bearer token，为空时关闭管理接口
+}
+
+// FeedbackConfig 用户反馈配置
+type FeedbackConfig struct {
+	Path string `yaml:"path"` // 反馈 JSONL 文件路径，为空时只保存在内存中（重启后丢失）
+}
+
+// ProfileConfig 学习者画像配置
+type ProfileConfig struct {
+	Path string `yaml:"path"` // 画像 JSONL 文件路径，每次保存追加一行，为空时只保存在内存中（重启后丢失）；重启后生效
+}
+
+// ValidationConfig 接口校验配置，请求体始终按 OpenAPI 文档校验
+type ValidationConfig struct {
+	Responses bool `yaml:"responses"` // 校验响应是否符合文档，不符合时记录错误并返回 500；需要缓冲响应，用于测试和预发环境
+}
+
+// ExperimentConfig A/B 实验配置
+// 同一 target 同时只能有一个启用的实验，请求按用户标识加权分配到变体
+type ExperimentConfig struct {
+	Name     string          `yaml:"name"`
+	Target   string          `yaml:"target"` // analysis | dialogue
+	Enabled  bool            `yaml:"enabled"`
+	Variants []VariantConfig `yaml:"variants"`
+}
+
+// VariantConfig 实验变体配置
+type VariantConfig struct {
+	Name          string `yaml:"name"`
+	Weight        int    `yaml:"weight"`         // 流量权重，按所有变体权重之和计算比例
+	PromptVariant string `yaml:"prompt_variant"` // 提示词模板变体，为空时使用默认模板
+	Model         string `yaml:"model"`          // 模型，为空时使用 ai.default_model
+}
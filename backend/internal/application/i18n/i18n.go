@@ -0,0 +1,144 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Language 语言标签
+type Language string
+
+const (
+	// ZhCN 简体中文
+	ZhCN Language = "zh-CN"
+	// En 英文
+	En Language = "en"
+
+	// QueryParam 指定语言的查询参数名
+	QueryParam = "lang"
+)
+
+// defaultLanguage 未指定或无法识别时使用的语言，支持热加载
+var defaultLanguage atomic.Value
+
+func init() {
+	defaultLanguage.Store(ZhCN)
+}
+
+// Supported 返回支持的语言列表
+func Supported() []Language {
+	return []Language{ZhCN, En}
+}
+
+// SetDefault 设置默认语言，不支持的语言返回错误
+func SetDefault(lang string) error {
+	if lang == "" {
+		defaultLanguage.Store(ZhCN)
+		return nil
+	}
+	l, ok := Match(lang)
+	if !ok {
+		return fmt.Errorf("unsupported language: %s", lang)
+	}
+	defaultLanguage.Store(l)
+	return nil
+}
+
+// Default 返回默认语言
+func Default() Language {
+	return defaultLanguage.Load().(Language)
+}
+
+// Match 将语言标签匹配到支持的语言，如 zh、zh-Hans、zh-TW -> zh-CN，en-US -> en
+func Match(tag string) (Language, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "":
+		return "", false
+	case tag == "zh" || strings.HasPrefix(tag, "zh-") || strings.HasPrefix(tag, "zh_"):
+		return ZhCN, true
+	case tag == "en" || strings.HasPrefix(tag, "en-") || strings.HasPrefix(tag, "en_"):
+		return En, true
+	default:
+		return "", false
+	}
+}
+
+// Negotiate 按优先级选择语言：显式参数 > Accept-Language（按 q 值）> 默认语言
+func Negotiate(param, acceptLanguage string) Language {
+	if l, ok := Match(param); ok {
+		return l
+	}
+
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if tag != "" && q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if l, ok := Match(c.tag); ok {
+			return l
+		}
+	}
+	return Default()
+}
+
+type languageKey struct{}
+
+// WithLanguage 将语言写入 context
+func WithLanguage(ctx context.Context, lang Language) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// FromContext 从 context 读取语言，未设置时返回默认语言
+func FromContext(ctx context.Context) Language {
+	if ctx != nil {
+		if lang, ok := ctx.Value(languageKey{}).(Language); ok {
+			return lang
+		}
+	}
+	return Default()
+}
+
+// T 返回指定语言的文案，args 按 fmt 格式填充
+// 文案缺失时依次回退到默认语言、中文，最后返回 key 本身
+func T(lang Language, key string, args ...interface{}) string {
+	entry, ok := catalog[key]
+	if !ok {
+		return key
+	}
+	msg, ok := entry[lang]
+	if !ok {
+		if msg, ok = entry[Default()]; !ok {
+			msg = entry[ZhCN]
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Has 判断文案是否存在
+func Has(key string) bool {
+	_, ok := catalog[key]
+	return ok
+}
@@ -0,0 +1,64 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		param  string
+		accept string
+		want   Language
+	}{
+		{"default", "", "", ZhCN},
+		{"query param wins", "en", "zh-CN", En},
+		{"accept language", "", "en-US,en;q=0.9", En},
+		{"q value order", "", "fr;q=1.0, zh-TW;q=0.5, en;q=0.8", En},
+		{"q zero ignored", "", "en;q=0, zh;q=0.1", ZhCN},
+		{"unsupported param falls back to header", "fr", "en", En},
+		{"unsupported only", "", "fr, de", ZhCN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.param, tt.accept); got != tt.want {
+				t.Errorf("Negotiate(%q, %q) = %q, want %q", tt.param, tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	t.Cleanup(func() { _ = SetDefault("") })
+
+	if err := SetDefault("fr"); err == nil {
+		t.Fatal("SetDefault(fr) should fail")
+	}
+	if err := SetDefault("en-GB"); err != nil {
+		t.Fatalf("SetDefault(en-GB): %v", err)
+	}
+	if got := Negotiate("", ""); got != En {
+		t.Errorf("Negotiate with default en = %q", got)
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T(En, "image.too_large", 10); got != "image is too large, the maximum size is 10MB" {
+		t.Errorf("T(en) = %q", got)
+	}
+	if got := T(ZhCN, "image.too_large", 10); got != "图片文件过大，最大支持 10MB" {
+		t.Errorf("T(zh-CN) = %q", got)
+	}
+	if got := T(En, "missing.key"); got != "missing.key" {
+		t.Errorf("missing key = %q", got)
+	}
+}
+
+// TestCatalogComplete 确保每条文案都有所有支持语言的版本
+func TestCatalogComplete(t *testing.T) {
+	for key, entry := range catalog {
+		for _, lang := range Supported() {
+			if entry[lang] == "" {
+				t.Errorf("%s missing %s translation", key, lang)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package i18n
+
+// catalog 面向用户的文案目录：key -> 语言 -> 文案
+var catalog = map[string]map[Language]string{
+	// 错误码说明，key 为 error.<Reason>
+	"error.OK":                     {ZhCN: "成功", En: "success"},
+	"error.INTERNAL_ERROR":         {ZhCN: "服务内部错误", En: "internal server error"},
+	"error.INVALID_PARAMS":         {ZhCN: "参数错误", En: "invalid parameters"},
+	"error.NOT_FOUND":              {ZhCN: "资源不存在", En: "resource not found"},
+	"error.PAYLOAD_TOO_LARGE":      {ZhCN: "请求内容过大", En: "payload too large"},
+	"error.UNSUPPORTED_MEDIA_TYPE": {ZhCN: "不支持的文件类型", En: "unsupported media type"},
+	"error.METHOD_NOT_ALLOWED":     {ZhCN: "不支持的请求方法", En: "method not allowed"},
+	"error.UNAUTHORIZED":           {ZhCN: "未授权", En: "unauthorized"},
+	"error.AI_SERVICE_ERROR":       {ZhCN: "AI 服务异常", En: "AI service error"},
+	"error.AI_SERVICE_TIMEOUT":     {ZhCN: "AI 服务响应超时", En: "AI service timeout"},
+	"error.AI_SERVICE_BUSY":        {ZhCN: "AI 服务繁忙，请稍后重试", En: "AI service is busy"},
+	"error.AI_SERVICE_REJECTED":    {ZhCN: "AI 服务拒绝了请求", En: "AI service rejected the request"},
+	"error.AI_RESPONSE_INVALID":    {ZhCN: "AI 返回内容无法解析", En: "AI response could not be parsed"},
+	"error.REQUEST_CANCELLED":      {ZhCN: "请求已取消", En: "request cancelled"},
+	"error.CONFIG_LOAD_ERROR":      {ZhCN: "配置加载失败", En: "config load error"},
+	"error.SOURCE_FETCH_FAILED":    {ZhCN: "获取链接内容失败", En: "failed to fetch source"},
+	"error.SOURCE_FETCH_TIMEOUT":   {ZhCN: "获取链接内容超时", En: "source fetch timeout"},
+
+	// 字段校验
+	"validation.required":       {ZhCN: "不能为空", En: "is required"},
+	"validation.min":            {ZhCN: "长度或数量不能少于 %s", En: "must have at least %s items or characters"},
+	"validation.max":            {ZhCN: "长度或数量不能超过 %s", En: "must have at most %s items or characters"},
+	"validation.oneof":          {ZhCN: "必须是 [%s] 之一", En: "must be one of [%s]"},
+	"validation.gte":            {ZhCN: "必须大于或等于 %s", En: "must be greater than or equal to %s"},
+	"validation.lte":            {ZhCN: "必须小于或等于 %s", En: "must be less than or equal to %s"},
+	"validation.rule":           {ZhCN: "未通过 '%s' 校验", En: "failed on the '%s' rule"},
+	"validation.type":           {ZhCN: "必须是 JSON %s", En: "must be a JSON %s"},
+	"validation.malformed_json": {ZhCN: "JSON 格式错误（位置 %d）", En: "malformed JSON at offset %d"},
+	"validation.invalid_body":   {ZhCN: "请求体无效", En: "invalid request body"},
+
+	// 图片上传
+	"image.required":           {ZhCN: "请上传图片文件", En: "please upload an image file"},
+	"image.unsupported_format": {ZhCN: "不支持的图片格式，请上传 jpg, jpeg, png, gif 或 webp 格式的图片", En: "unsupported image format, please upload a jpg, jpeg, png, gif or webp image"},
+	"image.too_large":          {ZhCN: "图片文件过大，最大支持 %dMB", En: "image is too large, the maximum size is %dMB"},
+	"image.too_many":           {ZhCN: "图片数量过多，单次最多上传 %d 张", En: "too many images, at most %d can be uploaded at once"},
+	"analysis.invalid_mode":    {ZhCN: "不支持的分析模式 %s，可选 analyze 或 check", En: "unsupported analysis mode %s, expected analyze or check"},
+
+	// 知识点对话
+	"knowledge_point.id_required": {ZhCN: "知识点ID不能为空", En: "knowledge point ID is required"},
+	"dialogue.analysis_required":  {ZhCN: "附带原图时必须提供 analysisId", En: "analysisId is required when imageContext is set"},
+
+	// 提示词模板
+	"prompt.unknown_template":  {ZhCN: "提示词模板 %s 不存在", En: "prompt template %s does not exist"},
+	"prompt.invalid_variables": {ZhCN: "模板变量无效：%s", En: "invalid template variables: %s"},
+	"prompt.render_failed":     {ZhCN: "模板渲染失败：%s", En: "failed to render template: %s"},
+
+	// 实验
+	"experiment.unknown_result": {ZhCN: "结果 %s 不存在或已过期", En: "result %s does not exist or has expired"},
+
+	// 用户反馈
+	"feedback.unknown_analysis":     {ZhCN: "分析结果 %s 不存在或已过期", En: "analysis %s does not exist or has expired"},
+	"feedback.unknown_message":      {ZhCN: "会话 %s 中的消息 %s 不存在或已过期", En: "message %[2]s in conversation %[1]s does not exist or has expired"},
+	"feedback.unknown_keypoint":     {ZhCN: "知识点 %s 不在该分析结果中", En: "knowledge point %s is not part of this analysis"},
+	"feedback.keypoint_not_allowed": {ZhCN: "只有分析结果反馈可以指定知识点", En: "keyPointId is only allowed for analysis feedback"},
+	"feedback.invalid_since":        {ZhCN: "since 必须是 RFC 3339 格式的时间", En: "since must be an RFC 3339 timestamp"},
+
+	// PDF 分析
+	"pdf.required":           {ZhCN: "请上传 PDF 文件", En: "please upload a PDF file"},
+	"pdf.unsupported_format": {ZhCN: "请上传 .pdf 格式的文件", En: "please upload a .pdf file"},
+	"pdf.too_large":          {ZhCN: "PDF 文件过大，最大支持 %dMB", En: "PDF is too large, the maximum size is %dMB"},
+	"pdf.invalid":            {ZhCN: "无法解析 PDF 文件（文件损坏或已加密）", En: "the PDF could not be read (corrupted or encrypted)"},
+	"pdf.invalid_pages":      {ZhCN: "页码范围 %q 无效，文档共 %d 页", En: "invalid page range %q, the document has %d pages"},
+	"pdf.too_many_pages":     {ZhCN: "选中的页数过多，单次最多分析 %d 页", En: "too many pages selected, at most %d pages can be analyzed at once"},
+	"pdf.no_content":         {ZhCN: "选中的页面没有可提取的文字，且未配置页面渲染", En: "the selected pages have no text layer and page rendering is not configured"},
+
+	// 链接与文本分析
+	"url.invalid":          {ZhCN: "链接无效，只支持 http 或 https 链接", En: "invalid URL, only http and https URLs are supported"},
+	"url.forbidden":        {ZhCN: "不允许访问内网或保留地址", En: "URLs pointing to private or reserved addresses are not allowed"},
+	"url.too_large":        {ZhCN: "链接内容过大，最大支持 %dMB", En: "the linked content is too large, the maximum size is %dMB"},
+	"url.unsupported_type": {ZhCN: "不支持的内容类型 %s，只支持图片、PDF、网页和纯文本", En: "unsupported content type %s, only images, PDFs, web pages and plain text are supported"},
+	"url.status":           {ZhCN: "链接返回状态码 %d", En: "the URL returned status %d"},
+	"url.unreachable":      {ZhCN: "无法访问该链接", En: "the URL could not be reached"},
+	"text.no_content":      {ZhCN: "没有可分析的文字内容", En: "there is no text content to analyze"},
+
+	// 知识点区域截图
+	"crop.unknown_analysis": {ZhCN: "分析结果 %s 不存在或已过期", En: "analysis %s does not exist or has expired"},
+	"crop.unknown_keypoint": {ZhCN: "知识点 %s 不在该分析结果中", En: "knowledge point %s is not part of this analysis"},
+	"crop.no_region":        {ZhCN: "知识点 %s 没有对应的图片区域", En: "knowledge point %s has no image region"},
+	"crop.image_expired":    {ZhCN: "分析结果 %s 的原图已过期，请重新分析", En: "the original images of analysis %s have expired, please analyze again"},
+	"crop.invalid_source":   {ZhCN: "source 必须是非负整数", En: "source must be a non-negative integer"},
+
+	// 学习者画像
+	"profile.user_required": {ZhCN: "学习者画像需要通过 X-User-ID 请求头提供用户标识", En: "the X-User-ID header is required for learner profiles"},
+	"profile.not_found":     {ZhCN: "用户 %s 还没有保存学习者画像", En: "user %s has no saved learner profile"},
+
+	// 学习者画像在提示词中的描述（发送给模型）
+	"learner.grade.primary":    {ZhCN: "小学生（约 6-12 岁）", En: "primary school student (about 6-12 years old)"},
+	"learner.grade.middle":     {ZhCN: "初中生（约 12-15 岁）", En: "middle school student (about 12-15 years old)"},
+	"learner.grade.high":       {ZhCN: "高中生（约 15-18 岁）", En: "high school student (about 15-18 years old)"},
+	"learner.grade.university": {ZhCN: "大学生", En: "university student"},
+	"learner.grade.adult":      {ZhCN: "成人学习者（自学或职业学习）", En: "adult learner (self-study or professional development)"},
+	"learner.style.concise":    {ZhCN: "简洁，直接给出要点，少铺垫", En: "concise: get straight to the point with little preamble"},
+	"learner.style.detailed":   {ZhCN: "详细，给出完整的推导和解释", En: "detailed: give complete derivations and explanations"},
+	"learner.style.examples":   {ZhCN: "多举例，用生活中的例子和类比讲解", En: "example-driven: explain with everyday examples and analogies"},
+	"learner.separator":        {ZhCN: "、", En: ", "},
+
+	// 多页分析中每页前的页码标签（发送给模型）
+	"analysis.page_label": {ZhCN: "第 %d 页", En: "Page %d"},
+
+	// AI 响应
+	"ai.empty_response":       {ZhCN: "AI未返回任何响应", En: "the AI returned no response"},
+	"ai.invalid_content":      {ZhCN: "AI返回的内容格式不正确", En: "the AI returned content in an unexpected format"},
+	"ai.invalid_json":         {ZhCN: "JSON解析失败", En: "failed to parse JSON"},
+	"ai.prerequisite_count":   {ZhCN: "前置知识点数量不正确，期望%d个，实际%d个", En: "unexpected number of prerequisites: expected %d, got %d"},
+	"ai.postrequisite_count":  {ZhCN: "后置知识点数量不正确，期望%d个，实际%d个", En: "unexpected number of postrequisites: expected %d, got %d"},
+	"ai.no_steps":             {ZhCN: "批改结果中没有解答步骤", En: "the check result contains no solution steps"},
+	"ai.missing_mistake":      {ZhCN: "批改结果有错误步骤但没有错误分析", En: "the check result has an incorrect step but no mistake analysis"},
+	"ai.invalid_mistake_type": {ZhCN: "未知的错误类型 %q", En: "unknown mistake type %q"},
+	"ai.upstream_status":      {ZhCN: "上游服务返回状态码 %d", En: "upstream status %d"},
+}
@@ -0,0 +1,56 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// HeaderUserID 用户标识请求头，由前端或网关传入，用于实验分组等按用户保持一致的功能
+	HeaderUserID = "X-User-ID"
+
+	// maxUserIDLength 用户标识最大长度
+	maxUserIDLength = 128
+)
+
+type userIDKey struct{}
+
+// WithUserID 将用户标识写入 context
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFrom 从 context 中读取用户标识，未传入时返回空字符串
+func UserIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+// ValidUserID 校验用户标识，只允许可打印的安全字符
+func ValidUserID(userID string) bool {
+	if userID == "" || len(userID) > maxUserIDLength {
+		return false
+	}
+	for _, ch := range userID {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-' || ch == '_' || ch == '.' || ch == ':' || ch == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewID 生成随机 ID，用于分析结果、对话消息等
+func NewID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
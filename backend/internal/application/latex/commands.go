@@ -0,0 +1,77 @@
+package latex
+
+import "strings"
+
+// commands KaTeX 支持的常用命令 -> 必需参数个数
+// 不在表中的命令视为未知命令，模型编造或拼错的命令会导致前端渲染失败
+var commands = map[string]int{}
+
+// optionalArg 可以带 [...] 可选参数的命令
+var optionalArg = map[string]bool{"sqrt": true, "xleftarrow": true, "xrightarrow": true}
+
+func init() {
+	// 没有参数的符号和运算符
+	for _, names := range []string{
+		// 希腊字母
+		"alpha beta gamma delta epsilon varepsilon zeta eta theta vartheta iota kappa lambda mu nu xi pi varpi rho varrho sigma varsigma tau upsilon phi varphi chi psi omega",
+		"Gamma Delta Theta Lambda Xi Pi Sigma Upsilon Phi Psi Omega",
+		// 二元运算
+		"pm mp times div cdot ast star circ bullet oplus ominus otimes oslash odot cap cup sqcap sqcup vee wedge setminus wr amalg dagger ddagger",
+		// 关系
+		"leq le geq ge neq ne equiv approx cong sim simeq propto ll gg subset supset subseteq supseteq in notin ni mid parallel perp models prec succ preceq succeq asymp doteq vdash dashv",
+		"lt gt leqslant geqslant lesssim gtrsim nless ngtr nleq ngeq subsetneq supsetneq nparallel frown smile",
+		// 箭头
+		"to gets leftarrow rightarrow Leftarrow Rightarrow leftrightarrow Leftrightarrow longleftarrow longrightarrow Longleftarrow Longrightarrow longleftrightarrow Longleftrightarrow",
+		"uparrow downarrow Uparrow Downarrow updownarrow mapsto longmapsto implies impliedby iff nearrow searrow swarrow nwarrow rightleftharpoons leftharpoonup rightharpoonup hookrightarrow",
+		// 大型运算符
+		"sum prod coprod int iint iiint oint bigcup bigcap bigvee bigwedge bigoplus bigotimes bigodot biguplus",
+		// 函数名
+		"sin cos tan cot sec csc arcsin arccos arctan sinh cosh tanh coth log ln lg exp lim limsup liminf sup inf max min arg det dim gcd deg hom ker Pr",
+		// 其他符号
+		"infty partial nabla forall exists nexists emptyset varnothing neg lnot angle measuredangle sphericalangle triangle square prime hbar ell Re Im aleph wp top bot",
+		"ldots cdots vdots ddots dots dotsb dotsc therefore because bmod mod degree circledR checkmark",
+		"langle rangle lbrace rbrace lbrack rbrack lceil rceil lfloor rfloor lvert rvert lVert rVert vert Vert backslash",
+		// 间距、换行和尺寸
+		"quad qquad enspace thinspace medspace thickspace negthinspace space nobreakspace newline mathstrut displaystyle textstyle scriptstyle scriptscriptstyle",
+		"big Big bigg Bigg bigl bigr Bigl Bigr biggl biggr Biggl Biggr limits nolimits hline cr",
+	} {
+		for _, name := range strings.Fields(names) {
+			commands[name] = 0
+		}
+	}
+
+	// 带参数的命令
+	for _, names := range []string{
+		"sqrt text textbf textit textrm textsf texttt mathrm mathbf mathit mathsf mathtt mathcal mathbb mathfrak mathscr boldsymbol bm operatorname",
+		"hat widehat bar overline underline vec overrightarrow overleftarrow tilde widetilde dot ddot check breve acute grave mathring overbrace underbrace",
+		"boxed cancel bcancel sout phantom hphantom vphantom not pmod color xleftarrow xrightarrow",
+	} {
+		for _, name := range strings.Fields(names) {
+			commands[name] = 1
+		}
+	}
+	for _, name := range strings.Fields("frac dfrac tfrac cfrac binom dbinom tbinom overset underset stackrel textcolor") {
+		commands[name] = 2
+	}
+}
+
+// controlSymbols 反斜杠加单个非字母字符的控制符号
+var controlSymbols = map[string]bool{
+	`\`: true, ",": true, ";": true, ":": true, "!": true, " ": true, ">": true,
+	"{": true, "}": true, "|": true, "%": true, "$": true, "&": true, "#": true, "_": true,
+}
+
+// environments 支持的环境，环境内可以用 & 对齐
+var environments = map[string]bool{
+	"matrix": true, "pmatrix": true, "bmatrix": true, "Bmatrix": true, "vmatrix": true, "Vmatrix": true, "smallmatrix": true,
+	"cases": true, "rcases": true, "array": true, "aligned": true, "align": true, "align*": true, "gathered": true,
+	"gather": true, "gather*": true, "split": true, "equation": true, "equation*": true, "alignat": true, "alignedat": true,
+}
+
+// delimiters \left、\middle、\right 可用的命令形式定界符
+var delimiters = map[string]bool{
+	"{": true, "}": true, "|": true, "langle": true, "rangle": true, "lbrace": true, "rbrace": true,
+	"lbrack": true, "rbrack": true, "lceil": true, "rceil": true,
+	"lfloor": true, "rfloor": true, "lvert": true, "rvert": true, "lVert": true, "rVert": true,
+	"vert": true, "Vert": true, "backslash": true, "uparrow": true, "downarrow": true,
+}
@@ -0,0 +1,314 @@
+// Package latex 校验模型输出的 LaTeX 公式：只做轻量的语法检查（括号与环境配对、已知命令、参数个数），
+// 保证公式能被前端的 KaTeX 渲染，不做完整的 TeX 解析
+package latex
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError 公式无法渲染的原因，Pos 为出错位置（按字符计）
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("latex: %s at position %d", e.Msg, e.Pos)
+}
+
+// Normalize 去掉公式两端的空白和数学定界符（$$…$$、$…$、\[…\]、\(…\)）
+func Normalize(expr string) string {
+	expr = strings.TrimSpace(expr)
+	for _, d := range [][2]string{{"$$", "$$"}, {`\[`, `\]`}, {`\(`, `\)`}, {"$", "$"}} {
+		if len(expr) >= len(d[0])+len(d[1]) && strings.HasPrefix(expr, d[0]) && strings.HasSuffix(expr, d[1]) {
+			return strings.TrimSpace(expr[len(d[0]) : len(expr)-len(d[1])])
+		}
+	}
+	return expr
+}
+
+// frame 未闭合的分组：{…}、\left…\right 或 \begin…\end
+type frame struct {
+	kind string // "{"、"left" 或环境名
+	pos  int
+}
+
+// parser 按字符扫描公式
+type parser struct {
+	src   []rune
+	pos   int
+	stack []frame
+}
+
+// Validate 检查公式（不含定界符）能否被 KaTeX 渲染，返回 *SyntaxError
+func Validate(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return &SyntaxError{Msg: "empty formula"}
+	}
+	p := &parser{src: []rune(expr)}
+	if err := p.parse(); err != nil {
+		return err
+	}
+	if n := len(p.stack); n > 0 {
+		top := p.stack[n-1]
+		switch top.kind {
+		case "{":
+			return p.errorAt(top.pos, "unclosed {")
+		case "left":
+			return p.errorAt(top.pos, `\left without \right`)
+		default:
+			return p.errorAt(top.pos, fmt.Sprintf(`\begin{%s} without \end`, top.kind))
+		}
+	}
+	return nil
+}
+
+func (p *parser) errorAt(pos int, msg string) error {
+	return &SyntaxError{Pos: pos, Msg: msg}
+}
+
+func (p *parser) parse() error {
+	for p.pos < len(p.src) {
+		start := p.pos
+		switch ch := p.src[p.pos]; ch {
+		case '\\':
+			if err := p.command(); err != nil {
+				return err
+			}
+			continue
+		case '{':
+			p.stack = append(p.stack, frame{kind: "{", pos: start})
+		case '}':
+			if n := len(p.stack); n == 0 || p.stack[n-1].kind != "{" {
+				return p.errorAt(start, "unexpected }")
+			}
+			p.stack = p.stack[:len(p.stack)-1]
+		case '^', '_':
+			p.pos++
+			if _, ok := p.skipArg(); !ok {
+				return p.errorAt(start, fmt.Sprintf("missing argument for %c", ch))
+			}
+			p.pos = start + 1
+			continue
+		case '&':
+			if !p.inEnvironment() {
+				return p.errorAt(start, "& outside of an environment")
+			}
+		case '$':
+			return p.errorAt(start, "math delimiter $ inside formula")
+		case '%', '#':
+			return p.errorAt(start, fmt.Sprintf("unescaped %c", ch))
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// command 处理以 \ 开头的命令
+func (p *parser) command() error {
+	start := p.pos
+	name, ok := p.readCommand()
+	if !ok {
+		return p.errorAt(start, `trailing \`)
+	}
+	if len(name) == 1 && !unicode.IsLetter(rune(name[0])) {
+		if !controlSymbols[name] {
+			return p.errorAt(start, fmt.Sprintf(`unknown command \%s`, name))
+		}
+		return nil
+	}
+
+	switch name {
+	case "begin":
+		env, err := p.envName(start)
+		if err != nil {
+			return err
+		}
+		if !environments[env] {
+			return p.errorAt(start, fmt.Sprintf("unknown environment %s", env))
+		}
+		p.stack = append(p.stack, frame{kind: env, pos: start})
+		return nil
+	case "end":
+		env, err := p.envName(start)
+		if err != nil {
+			return err
+		}
+		if n := len(p.stack); n == 0 || p.stack[n-1].kind != env {
+			return p.errorAt(start, fmt.Sprintf(`\end{%s} without matching \begin`, env))
+		}
+		p.stack = p.stack[:len(p.stack)-1]
+		return nil
+	case "left":
+		if !p.delimiter() {
+			return p.errorAt(start, `missing delimiter after \left`)
+		}
+		p.stack = append(p.stack, frame{kind: "left", pos: start})
+		return nil
+	case "middle", "right":
+		if n := len(p.stack); n == 0 || p.stack[n-1].kind != "left" {
+			return p.errorAt(start, fmt.Sprintf(`\%s without \left`, name))
+		}
+		if !p.delimiter() {
+			return p.errorAt(start, fmt.Sprintf(`missing delimiter after \%s`, name))
+		}
+		if name == "right" {
+			p.stack = p.stack[:len(p.stack)-1]
+		}
+		return nil
+	}
+
+	arity, known := commands[name]
+	if !known {
+		return p.errorAt(start, fmt.Sprintf(`unknown command \%s`, name))
+	}
+	// 只检查参数存在，参数内容由主循环继续扫描
+	after := p.pos
+	if p.peekNonSpace() == '[' && optionalArg[name] {
+		if !p.skipOptional() {
+			return p.errorAt(start, fmt.Sprintf(`unclosed [ after \%s`, name))
+		}
+	}
+	for range arity {
+		if _, ok := p.skipArg(); !ok {
+			return p.errorAt(start, fmt.Sprintf(`\%s expects %d argument(s)`, name, arity))
+		}
+	}
+	p.pos = after
+	return nil
+}
+
+// readCommand 读取 \ 之后的命令名：连续字母，或单个非字母字符（控制符号）
+func (p *parser) readCommand() (string, bool) {
+	p.pos++ // 跳过 \
+	if p.pos >= len(p.src) {
+		return "", false
+	}
+	start := p.pos
+	if !isLetter(p.src[p.pos]) {
+		p.pos++
+		return string(p.src[start:p.pos]), true
+	}
+	for p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos]), true
+}
+
+// envName 读取 \begin 和 \end 之后的 {环境名}
+func (p *parser) envName(start int) (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return "", p.errorAt(start, "missing environment name")
+	}
+	end := p.pos + 1
+	for end < len(p.src) && p.src[end] != '}' {
+		end++
+	}
+	if end >= len(p.src) {
+		return "", p.errorAt(start, "unclosed environment name")
+	}
+	name := strings.TrimSpace(string(p.src[p.pos+1 : end]))
+	p.pos = end + 1
+	return name, nil
+}
+
+// delimiter 读取 \left、\middle、\right 之后的定界符
+func (p *parser) delimiter() bool {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return false
+	}
+	if ch := p.src[p.pos]; ch != '\\' {
+		p.pos++
+		return strings.ContainsRune("()[]|./<>", ch)
+	}
+	name, ok := p.readCommand()
+	return ok && delimiters[name]
+}
+
+// skipArg 从当前位置跳过一个参数：{…} 分组、命令或单个字符，返回参数起始位置
+func (p *parser) skipArg() (int, bool) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return p.pos, false
+	}
+	start := p.pos
+	switch p.src[p.pos] {
+	case '}', '&', '^', '_', '$':
+		return start, false
+	case '{':
+		depth := 0
+		for ; p.pos < len(p.src); p.pos++ {
+			switch p.src[p.pos] {
+			case '\\':
+				p.pos++
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					p.pos++
+					return start, true
+				}
+			}
+		}
+		// 未闭合的分组由主循环报告
+		return start, true
+	case '\\':
+		_, ok := p.readCommand()
+		return start, ok
+	}
+	p.pos++
+	return start, true
+}
+
+// skipOptional 跳过 [...] 可选参数
+func (p *parser) skipOptional() bool {
+	p.skipSpace()
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *parser) peekNonSpace() rune {
+	for i := p.pos; i < len(p.src); i++ {
+		if !unicode.IsSpace(p.src[i]) {
+			return p.src[i]
+		}
+	}
+	return 0
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// inEnvironment 当前是否位于支持 & 对齐的环境中（可以嵌套在分组内）
+func (p *parser) inEnvironment() bool {
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if kind := p.stack[i].kind; kind != "{" && kind != "left" {
+			return true
+		}
+	}
+	return false
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,75 @@
+package latex
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		" $a^2 + b^2 = c^2$ ": "a^2 + b^2 = c^2",
+		"$$E = mc^2$$":        "E = mc^2",
+		`\[ F = ma \]`:        "F = ma",
+		`\(x\)`:               "x",
+		"x_1 + x_2":           "x_1 + x_2",
+		"$":                   "$",
+	}
+	for in, want := range cases {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{
+		"a^2 + b^2 = c^2",
+		`x = \frac{-b \pm \sqrt{b^2 - 4ac}}{2a}`,
+		`\sqrt[3]{x} \cdot \vec{F} = m\vec{a}`,
+		`\int_0^{\infty} e^{-x^2} \, dx = \frac{\sqrt{\pi}}{2}`,
+		`\left( \frac{a}{b} \right)^n \left\{ x \middle| x > 0 \right\}`,
+		`\begin{pmatrix} a & b \\ c & d \end{pmatrix}`,
+		`f(x) = \begin{cases} x^2, & x \geq 0 \\ -x, & x < 0 \end{cases}`,
+		`\mathrm{CO_2} + \text{H}_2\text{O} \rightleftharpoons \mathrm{H_2CO_3}`,
+		`\lim_{n \to \infty} \left(1 + \frac{1}{n}\right)^n = e`,
+		`\frac12 + \binom{n}{k}`,
+		`50\%`,
+		`0 \lt x \lt 1, \; y \gt 0`,
+		`A = \lbrace x \mid x \gt 0 \rbrace, \left\lbrace 1, 2 \right\rbrace`,
+		`\overset{\frown}{AB} = \frac{n\pi r}{180}`,
+		`a \nparallel b, \; \measuredangle ABC = 90^\circ`,
+	}
+	for _, expr := range valid {
+		if err := Validate(expr); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", expr, err)
+		}
+	}
+
+	invalid := map[string]int{ // 公式 -> 出错位置
+		"":                               0,
+		`\frac{a}{b`:                     8,
+		`a}`:                             1,
+		`\fracc{a}{b}`:                   0,
+		`\frac{a}`:                       0,
+		`x^`:                             1,
+		`\left( x`:                       0,
+		`x \right)`:                      2,
+		`\begin{pmatrix} a & b`:          0,
+		`\begin{matrix} a \end{pmatrix}`: 17,
+		`\begin{foo} a \end{foo}`:        0,
+		`a & b`:                          2,
+		`$x$ + y`:                        0,
+		`100%`:                           3,
+		`x \`:                            2,
+		`\left x \right)`:                0,
+		`\sqrt`:                          0,
+	}
+	for expr, pos := range invalid {
+		var syntaxErr *SyntaxError
+		if err := Validate(expr); !errors.As(err, &syntaxErr) {
+			t.Errorf("Validate(%q) = %v, want *SyntaxError", expr, err)
+		} else if syntaxErr.Pos != pos {
+			t.Errorf("Validate(%q) error at %d (%v), want %d", expr, syntaxErr.Pos, err, pos)
+		}
+	}
+}
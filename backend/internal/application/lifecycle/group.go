@@ -0,0 +1,82 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Group 后台任务组，用于在进程退出前通知并等待后台任务结束
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	running map[string]int
+}
+
+// NewGroup 创建后台任务组
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{
+		ctx:     ctx,
+		cancel:  cancel,
+		running: make(map[string]int),
+	}
+}
+
+// Go 启动一个后台任务
+// ctx 在开始关闭时取消：循环类任务（如监听、轮询）应据此退出，一次性任务可以继续执行到完成
+// 任务组关闭后不再接受新任务，返回 false
+func (g *Group) Go(name string, fn func(ctx context.Context)) bool {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		slog.Warn("background job rejected during shutdown", "job", name)
+		return false
+	}
+	g.running[name]++
+	g.wg.Add(1)
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			g.mu.Lock()
+			g.running[name]--
+			if g.running[name] == 0 {
+				delete(g.running, name)
+			}
+			g.mu.Unlock()
+			g.wg.Done()
+		}()
+		fn(g.ctx)
+	}()
+	return true
+}
+
+// Shutdown 停止接受新任务，取消任务 ctx 并等待所有任务结束
+// ctx 到期时返回错误，并列出仍未结束的任务
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return fmt.Errorf("background jobs did not finish before deadline: %v", g.running)
+	}
+}
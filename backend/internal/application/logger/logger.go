@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"ai-note-service/internal/application/global"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// FormatJSON JSON 格式日志
+	FormatJSON = "json"
+	// FormatText 文本格式日志（本地调试使用）
+	FormatText = "text"
+)
+
+var (
+	// logUserContent 是否在日志中输出用户内容（默认不输出）
+	logUserContent atomic.Bool
+	// level 当前日志级别，支持热加载
+	level slog.LevelVar
+)
+
+// Init 根据配置初始化全局 slog 日志
+func Init(cfg global.LogConfig) error {
+	if err := Apply(cfg); err != nil {
+		return err
+	}
+
+	handler, err := newHandler(os.Stdout, cfg.Format, &level)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Apply 应用可热加载的日志配置（级别和用户内容开关），格式变更需要重启
+func Apply(cfg global.LogConfig) error {
+	lvl, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	level.Set(lvl)
+	logUserContent.Store(cfg.LogUserContent)
+	return nil
+}
+
+// newHandler 创建带请求ID注入和脱敏的日志处理器
+func newHandler(w io.Writer, format string, level slog.Leveler) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON, "":
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s", format)
+	}
+
+	return &contextHandler{Handler: handler}, nil
+}
+
+// ParseLevel 解析日志级别，空字符串默认为 info
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unsupported log level: %s", level)
+	}
+}
+
+// contextHandler 从 context 中提取请求ID和 trace ID 写入每一条日志
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle 处理日志记录
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestID := RequestIDFrom(ctx); requestID != "" {
+		r.AddAttrs(slog.String(KeyRequestID, requestID))
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", spanCtx.TraceID().String()),
+			slog.String("span_id", spanCtx.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs 返回附加属性后的处理器
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup 返回分组后的处理器
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	input := `payload {"url":"data:image/jpeg;base64,/9j/4AAQSkZJRgABAQ=="} Authorization: Bearer sk-secret-123`
+	got := RedactString(input)
+
+	if strings.Contains(got, "/9j/4AAQ") {
+		t.Errorf("base64 image data not redacted: %s", got)
+	}
+	if strings.Contains(got, "sk-secret-123") {
+		t.Errorf("bearer token not redacted: %s", got)
+	}
+	if !strings.Contains(got, "data:image/jpeg;base64,[REDACTED") {
+		t.Errorf("expected data URI prefix to be kept, got %s", got)
+	}
+}
+
+func TestHandlerAddsRequestIDAndRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := newHandler(&buf, FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("newHandler failed: %v", err)
+	}
+	log := slog.New(handler)
+
+	logUserContent.Store(false)
+	ctx := WithRequestID(context.Background(), "req-123")
+	log.InfoContext(ctx, "test", "api_key", "secret", UserContent("message", "hello world"))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if entry[KeyRequestID] != "req-123" {
+		t.Errorf("expected request_id req-123, got %v", entry[KeyRequestID])
+	}
+	if entry["api_key"] != Redacted {
+		t.Errorf("expected api_key to be redacted, got %v", entry["api_key"])
+	}
+	if msg, _ := entry["message"].(string); strings.Contains(msg, "hello") {
+		t.Errorf("expected user content to be redacted, got %v", msg)
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	cases := map[string]bool{
+		"abc-123":                true,
+		"":                       false,
+		"bad id":                 false,
+		"line\nbreak":            false,
+		strings.Repeat("a", 200): false,
+	}
+	for id, want := range cases {
+		if got := ValidRequestID(id); got != want {
+			t.Errorf("ValidRequestID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted 脱敏后的占位内容
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 值需要整体脱敏的日志键名（小写）
+var sensitiveKeys = map[string]bool{
+	"api_key":       true,
+	"apikey":        true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+}
+
+var (
+	// base64DataURIPattern 匹配 data URI 中的 base64 数据
+	base64DataURIPattern = regexp.MustCompile(`(data:[\w/+.-]+;base64,)[A-Za-z0-9+/=]+`)
+	// bearerPattern 匹配 Authorization 头中的 Bearer token
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`)
+)
+
+// redactAttr slog ReplaceAttr 回调，对敏感键名和字符串值进行脱敏
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
+
+// RedactString 去除字符串中的 base64 图片数据和 Bearer token
+func RedactString(s string) string {
+	s = base64DataURIPattern.ReplaceAllStringFunc(s, func(match string) string {
+		prefix := base64DataURIPattern.FindStringSubmatch(match)[1]
+		return fmt.Sprintf("%s[REDACTED %d bytes]", prefix, len(match)-len(prefix))
+	})
+	return bearerPattern.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// Truncate 截断过长的字符串（按字符计），用于在日志或错误中保留片段
+func Truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + fmt.Sprintf("...(%d chars truncated)", len(runes)-max)
+}
+
+// userContent 用户内容，默认只输出长度
+type userContent string
+
+// LogValue 实现 slog.LogValuer
+func (u userContent) LogValue() slog.Value {
+	if logUserContent.Load() {
+		return slog.StringValue(string(u))
+	}
+	return slog.StringValue(fmt.Sprintf("%s len=%d", Redacted, len([]rune(string(u)))))
+}
+
+// UserContent 构造用户内容日志字段（学习者消息、知识点描述、模型回复等）
+// 除非配置开启 log_user_content，否则只记录长度
+func UserContent(key, value string) slog.Attr {
+	return slog.Any(key, userContent(value))
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// HeaderRequestID 请求ID请求头
+	HeaderRequestID = "X-Request-ID"
+	// KeyRequestID 日志和 gin.Context 中请求ID的键名
+	KeyRequestID = "request_id"
+
+	// maxRequestIDLength 允许透传的请求ID最大长度
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// WithRequestID 将请求ID写入 context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFrom 从 context 中读取请求ID
+func RequestIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewRequestID 生成新的请求ID
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+// ValidRequestID 校验客户端传入的请求ID，只允许可打印的安全字符，避免日志注入
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, ch := range requestID {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-' || ch == '_' || ch == '.' || ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
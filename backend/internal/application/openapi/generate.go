@@ -0,0 +1,407 @@
+package openapi
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 生成文档时扫描的目录和引用的类型（相对模块根目录）
+const (
+	controllerDir = "internal/application/controller"
+	schemaPkg     = "internal/application/schema"
+)
+
+// AdminSecurity 管理接口的鉴权方式名称，对应注释中的 @Security
+const AdminSecurity = "AdminToken"
+
+var (
+	// paramPattern @Param 名称 位置 类型 是否必填 "说明" [Enums(a, b)]
+	paramPattern = regexp.MustCompile(`^(\S+)\s+(path|query|header|body|formData)\s+(\S+)\s+(true|false)\s+"([^"]*)"\s*(.*)$`)
+	// responsePattern @Success/@Failure 状态码 {类别} 类型 ["说明"]
+	responsePattern = regexp.MustCompile(`^(\d{3})\s+\{(\w+)\}\s+(\S+)\s*(?:"([^"]*)")?$`)
+	// routerPattern @Router 路径 [方法]
+	routerPattern = regexp.MustCompile(`^(\S+)\s+\[(\w+)\]$`)
+	// enumsPattern 参数属性 Enums(a, b)
+	enumsPattern = regexp.MustCompile(`^Enums\(([^)]*)\)$`)
+	// pathParamPattern 路径中的 {参数}
+	pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+)
+
+// Generate 扫描模块 root 下控制器的注释生成 OpenAPI 文档，引用的 schema 类型从源码解析
+func Generate(root string) (*Document, error) {
+	module, err := modulePath(root)
+	if err != nil {
+		return nil, err
+	}
+	r := newTypeResolver(root, module)
+	files, err := r.parseDir(filepath.Join(root, filepath.FromSlash(controllerDir)))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       "AI Note Service API",
+			Description: "图片知识点分析与 AI 对话服务。错误响应使用统一响应结构，请求头 Accept: application/problem+json 时返回 RFC 7807 格式。",
+			Version:     "1.0.0",
+		},
+		Paths: make(map[string]map[string]*Operation),
+	}
+	operationIDs := make(map[string]string)
+	tags := make(map[string]bool)
+
+	// 注释中引用的类型按控制器包内所有文件的导入解析，与 swag 一致，不要求注释所在文件导入该包
+	imports := &ast.File{}
+	for _, f := range files {
+		imports.Imports = append(imports.Imports, f.Imports...)
+	}
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+			annotations := parseAnnotations(fn.Doc)
+			if len(annotations["Router"]) == 0 {
+				continue
+			}
+			name := funcName(fn)
+			op, path, method, err := buildOperation(r, imports, fn.Name.Name, annotations)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if other, ok := operationIDs[op.OperationID]; ok {
+				return nil, fmt.Errorf("%s: operationId %s is already used by %s", name, op.OperationID, other)
+			}
+			operationIDs[op.OperationID] = name
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]*Operation)
+			}
+			if _, ok := doc.Paths[path][method]; ok {
+				return nil, fmt.Errorf("%s: %s %s is documented twice", name, strings.ToUpper(method), path)
+			}
+			doc.Paths[path][method] = op
+			for _, tag := range op.Tags {
+				tags[tag] = true
+			}
+		}
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+
+	// 所有接口共用的错误响应
+	schemaPath := module + "/" + schemaPkg
+	errorSchema, err := r.named(schemaPath, "Response")
+	if err != nil {
+		return nil, err
+	}
+	problemSchema, err := r.named(schemaPath, "ProblemDetails")
+	if err != nil {
+		return nil, err
+	}
+	doc.Components = Components{
+		Schemas: r.schemas,
+		Responses: map[string]*Response{
+			"Error": {
+				Description: "错误响应，error.reason 为稳定的错误标识",
+				Content: map[string]*MediaType{
+					"application/json":         {Schema: errorSchema},
+					"application/problem+json": {Schema: problemSchema},
+				},
+			},
+		},
+		SecuritySchemes: map[string]*SecurityScheme{
+			AdminSecurity: {
+				Type:        "http",
+				Scheme:      "bearer",
+				Description: "配置项 admin.token",
+			},
+		},
+	}
+	return doc, nil
+}
+
+// modulePath 读取 go.mod 中的模块路径
+func modulePath(root string) (string, error) {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.TrimSpace(module), nil
+		}
+	}
+	return "", fmt.Errorf("module path not found in %s", f.Name())
+}
+
+// funcName 返回带接收者类型的函数名，用于错误信息
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// parseAnnotations 按名称收集 // @Name value 形式的注释
+func parseAnnotations(doc *ast.CommentGroup) map[string][]string {
+	annotations := make(map[string][]string)
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "@")
+		if !ok {
+			continue
+		}
+		name, value, _ := strings.Cut(rest, " ")
+		annotations[name] = append(annotations[name], strings.TrimSpace(value))
+	}
+	return annotations
+}
+
+// buildOperation 根据一个处理函数的注释构造操作
+func buildOperation(r *typeResolver, file *ast.File, funcName string, a map[string][]string) (op *Operation, path, method string, err error) {
+	if len(a["Router"]) != 1 {
+		return nil, "", "", fmt.Errorf("expected one @Router, got %d", len(a["Router"]))
+	}
+	m := routerPattern.FindStringSubmatch(a["Router"][0])
+	if m == nil {
+		return nil, "", "", fmt.Errorf("invalid @Router %q", a["Router"][0])
+	}
+	path, method = m[1], strings.ToLower(m[2])
+
+	op = &Operation{
+		Summary:     strings.Join(a["Summary"], " "),
+		Description: strings.Join(a["Description"], "\n"),
+		OperationID: lowerFirst(funcName),
+		Responses:   make(map[string]*Response),
+	}
+	for _, tags := range a["Tags"] {
+		for _, tag := range strings.Split(tags, ",") {
+			op.Tags = append(op.Tags, strings.TrimSpace(tag))
+		}
+	}
+	for _, name := range a["Security"] {
+		op.Security = append(op.Security, map[string][]string{name: {}})
+	}
+	accept := mimeType(first(a["Accept"], "json"))
+	produce := mimeType(first(a["Produce"], "json"))
+
+	var form *Schema
+	for _, line := range a["Param"] {
+		p := paramPattern.FindStringSubmatch(line)
+		if p == nil {
+			return nil, "", "", fmt.Errorf("invalid @Param %q", line)
+		}
+		name, in, typ, required, desc, attrs := p[1], p[2], p[3], p[4] == "true", p[5], p[6]
+
+		var s *Schema
+		if typ == "file" {
+			s = &Schema{Type: "string", Format: "binary"}
+		} else if typ == "[]file" {
+			s = &Schema{Type: "array", Items: &Schema{Type: "string", Format: "binary"}}
+		} else if s, err = r.resolveAnnotation(typ, file); err != nil {
+			return nil, "", "", fmt.Errorf("@Param %s: %w", name, err)
+		}
+		if attrs != "" {
+			e := enumsPattern.FindStringSubmatch(attrs)
+			if e == nil {
+				return nil, "", "", fmt.Errorf("@Param %s: unsupported attributes %q", name, attrs)
+			}
+			for _, v := range strings.Split(e[1], ",") {
+				s.Enum = append(s.Enum, strings.TrimSpace(v))
+			}
+		}
+
+		switch in {
+		case "body":
+			op.RequestBody = &RequestBody{
+				Description: desc,
+				Required:    required,
+				Content:     map[string]*MediaType{accept: {Schema: s}},
+			}
+		case "formData":
+			if form == nil {
+				form = &Schema{Type: "object", Properties: make(map[string]*Schema)}
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content:  map[string]*MediaType{"multipart/form-data": {Schema: form}},
+				}
+			}
+			s.Description = desc
+			form.Properties[name] = s
+			if required {
+				form.Required = append(form.Required, name)
+			}
+		default:
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:        name,
+				In:          in,
+				Description: desc,
+				Required:    required || in == "path",
+				Schema:      s,
+			})
+		}
+	}
+	if err := checkPathParams(path, op.Parameters); err != nil {
+		return nil, "", "", err
+	}
+
+	for _, annotation := range []string{"Success", "Failure"} {
+		for _, line := range a[annotation] {
+			resp, code, err := r.buildResponse(line, produce, file)
+			if err != nil {
+				return nil, "", "", fmt.Errorf("@%s: %w", annotation, err)
+			}
+			op.Responses[code] = resp
+		}
+	}
+	if len(op.Responses) == 0 {
+		return nil, "", "", fmt.Errorf("no @Success response")
+	}
+	op.Responses["default"] = &Response{Ref: "#/components/responses/Error"}
+	return op, path, method, nil
+}
+
+// buildResponse 解析 @Success/@Failure 注释
+func (r *typeResolver) buildResponse(line, produce string, file *ast.File) (*Response, string, error) {
+	m := responsePattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, "", fmt.Errorf("invalid response %q", line)
+	}
+	code, kind, typ, desc := m[1], m[2], m[3], m[4]
+	if desc == "" {
+		n, _ := strconv.Atoi(code)
+		desc = http.StatusText(n)
+	}
+
+	var s *Schema
+	var err error
+	switch kind {
+	case "file":
+		s = &Schema{Type: "string", Format: "binary"}
+	case "object", "array":
+		s, err = r.resolveAnnotation(typ, file)
+		if err == nil && kind == "array" {
+			s = &Schema{Type: "array", Items: s}
+		}
+	default:
+		s, err = r.resolveAnnotation(kind, file)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &Response{
+		Description: desc,
+		Content:     map[string]*MediaType{produce: {Schema: s}},
+	}, code, nil
+}
+
+// resolveAnnotation 解析注释中的类型，支持 swag 的字段覆盖写法 schema.Response{data=[]schema.Item}
+// 覆盖的字段可以是 | 分隔的多个候选类型，如 data=schema.A|schema.B，生成 oneOf
+func (r *typeResolver) resolveAnnotation(typ string, file *ast.File) (*Schema, error) {
+	base, overrides, hasOverrides := strings.Cut(typ, "{")
+	expr, err := parser.ParseExpr(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type %q: %w", typ, err)
+	}
+	s, err := r.resolve(expr, file, "")
+	if err != nil || !hasOverrides {
+		return s, err
+	}
+
+	props := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, override := range strings.Split(strings.TrimSuffix(overrides, "}"), ",") {
+		field, fieldType, ok := strings.Cut(override, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid field override %q in %q", override, typ)
+		}
+		if alternatives := strings.Split(fieldType, "|"); len(alternatives) > 1 {
+			prop := &Schema{}
+			for _, alt := range alternatives {
+				option, err := r.resolveAnnotation(alt, file)
+				if err != nil {
+					return nil, err
+				}
+				prop.OneOf = append(prop.OneOf, option)
+			}
+			props.Properties[field] = prop
+			continue
+		}
+		if props.Properties[field], err = r.resolveAnnotation(fieldType, file); err != nil {
+			return nil, err
+		}
+	}
+	return &Schema{AllOf: []*Schema{s, props}}, nil
+}
+
+// checkPathParams 路径中的每个 {参数} 都必须有对应的 @Param path 注释
+func checkPathParams(path string, params []*Parameter) error {
+	declared := make(map[string]bool)
+	for _, p := range params {
+		if p.In == "path" {
+			declared[p.Name] = true
+		}
+	}
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		if !declared[m[1]] {
+			return fmt.Errorf("path parameter %s is not documented", m[1])
+		}
+		delete(declared, m[1])
+	}
+	for name := range declared {
+		return fmt.Errorf("path parameter %s is not in %s", name, path)
+	}
+	return nil
+}
+
+// mimeType 将 swag 的简写转换为 MIME 类型
+func mimeType(v string) string {
+	switch v {
+	case "json":
+		return "application/json"
+	case "html":
+		return "text/html"
+	case "plain":
+		return "text/plain"
+	case "png":
+		return "image/png"
+	}
+	return v
+}
+
+func first(values []string, fallback string) string {
+	if len(values) == 0 {
+		return fallback
+	}
+	return values[0]
+}
+
+func lowerFirst(s string) string {
+	for i, c := range s {
+		return string(unicode.ToLower(c)) + s[i+len(string(c)):]
+	}
+	return s
+}
@@ -0,0 +1,167 @@
+// Package openapi 根据控制器上的 swag 风格注释和 schema 类型生成 OpenAPI 3 文档
+//
+// 生成的 openapi.json 随代码提交并编译进二进制，由 /openapi.json 提供，/docs 为对应的 Swagger UI 页面；修改接口注释或 schema 类型后需重新生成：
+//
+//	go generate ./internal/application/openapi
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+)
+
+//go:generate go run ../../../cmd/openapi -root ../../.. -o openapi.json
+
+// Version 生成文档使用的 OpenAPI 版本
+const Version = "3.0.3"
+
+//go:embed openapi.json
+var spec []byte
+
+// SwaggerUIVersion 文档页面从 CDN 加载的 swagger-ui-dist 固定版本
+const SwaggerUIVersion = "5.17.14"
+
+// docsTemplate 加载 /openapi.json 的 Swagger UI 页面，页面资源从 CDN 加载固定版本，离线时无法打开
+//
+//go:embed docs.html
+var docsTemplate string
+
+// docsPage 渲染后的文档页面
+var docsPage = renderDocsPage()
+
+// Spec 返回编译进二进制的 OpenAPI 文档
+func Spec() []byte {
+	return spec
+}
+
+// DocsPage 返回接口文档页面
+func DocsPage() []byte {
+	return docsPage
+}
+
+// renderDocsPage 将 swagger-ui-dist 的版本填入页面
+func renderDocsPage() []byte {
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("docs").Parse(docsTemplate))
+	if err := tmpl.Execute(&buf, struct{ Assets string }{"https://unpkg.com/swagger-ui-dist@" + SwaggerUIVersion}); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// Load 解析编译进二进制的 OpenAPI 文档
+func Load() (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Marshal 以固定格式序列化文档，生成结果与提交的 openapi.json 逐字节比较
+func Marshal(doc *Document) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Document OpenAPI 文档
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Tags       []Tag                            `json:"tags,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"` // 路径 -> 小写 HTTP 方法 -> 操作
+	Components Components                       `json:"components"`
+}
+
+// Info 文档基本信息
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Tag 接口分组
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Operation 一个路径上的一个 HTTP 方法
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter 路径、查询或请求头参数
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// Response 响应，Ref 不为空时引用 components.responses
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType 某种内容类型的结构
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema 数据结构（OpenAPI 3.0 Schema Object 的子集）
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Components 可复用的结构、响应和鉴权方式
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme 鉴权方式
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,144 @@
+package openapi
+
+import (
+	"bytes"
+	"go/ast"
+	"reflect"
+	"testing"
+)
+
+// moduleRoot 测试运行目录相对模块根目录的位置
+const moduleRoot = "../../.."
+
+func TestSpecUpToDate(t *testing.T) {
+	doc, err := Generate(moduleRoot)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	data, err := Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, Spec()) {
+		t.Error("openapi.json is out of date, run: go generate ./internal/application/openapi")
+	}
+}
+
+func TestApplyBinding(t *testing.T) {
+	tests := []struct {
+		binding  string
+		schema   *Schema
+		required bool
+		check    func(*Schema) bool
+	}{
+		{"required,min=1,max=5", &Schema{Type: "integer"}, true, func(s *Schema) bool {
+			return *s.Minimum == 1 && *s.Maximum == 5
+		}},
+		{"omitempty,max=64", &Schema{Type: "string"}, false, func(s *Schema) bool {
+			return *s.MaxLength == 64
+		}},
+		{"required,oneof=up down", &Schema{Type: "string"}, true, func(s *Schema) bool {
+			return len(s.Enum) == 2 && s.Enum[1] == "down"
+		}},
+		{"omitempty,max=8,dive,oneof=a b", &Schema{Type: "array", Items: &Schema{Type: "string"}}, false, func(s *Schema) bool {
+			return *s.MaxItems == 8 && len(s.Items.Enum) == 2 && s.Enum == nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.binding, func(t *testing.T) {
+			if got := applyBinding(tt.schema, tt.binding); got != tt.required {
+				t.Errorf("required = %v, want %v", got, tt.required)
+			}
+			if !tt.check(tt.schema) {
+				t.Errorf("unexpected schema: %+v", tt.schema)
+			}
+		})
+	}
+}
+
+func TestBuildOperationRequiresPathParams(t *testing.T) {
+	r := newTypeResolver(moduleRoot, "ai-note-service")
+	_, _, _, err := buildOperation(r, &ast.File{}, "Get", map[string][]string{
+		"Router":  {"/api/items/{id} [get]"},
+		"Success": {`200 {string} string "ok"`},
+	})
+	if err == nil {
+		t.Fatal("expected error for undocumented path parameter")
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	doc, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := doc.Operation("POST", "/api/v1/chat").RequestSchema("application/json; charset=utf-8")
+	if chat == nil {
+		t.Fatal("chat request schema not found")
+	}
+
+	tests := []struct {
+		body string
+		want []Violation
+	}{
+		{`{"messages":[{"role":"user","content":"hi"}],"temperature":1}`, nil},
+		{`{"messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`, nil},
+		{`{"messages":[{"role":"bot","content":""}]}`, []Violation{
+			{Field: "messages[0].content", Rule: "required"},
+			{Field: "messages[0].role", Rule: "oneof", Param: "system user assistant"},
+		}},
+		{`{"messages":[{"role":"user","content":"hi"}],"temperature":-1,"max_tokens":1.5}`, []Violation{
+			{Field: "max_tokens", Rule: "type", Param: "integer"},
+			{Field: "temperature", Rule: "gte", Param: "0"},
+		}},
+		{`{"messages":[{"role":"user","content":true}]}`, []Violation{
+			{Field: "messages[0].content", Rule: "type", Param: "string | array"},
+		}},
+		{`{}`, []Violation{{Field: "messages", Rule: "required"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			got, err := doc.ValidateJSON(chat, []byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := doc.ValidateJSON(chat, []byte(`{"messages":`)); err == nil {
+		t.Error("expected error for malformed JSON")
+	}
+}
+
+func TestValidateOneOfObjects(t *testing.T) {
+	doc := &Document{}
+	s := &Schema{OneOf: []*Schema{
+		{Type: "object", Required: []string{"a"}},
+		{Type: "object", Required: []string{"b"}},
+	}}
+	// 任意一个候选通过即可，都不通过时报告第一个候选的错误
+	if got := doc.Validate(s, map[string]any{"b": "x"}); got != nil {
+		t.Errorf("second option violations = %+v", got)
+	}
+	want := []Violation{{Field: "a", Rule: "required"}}
+	if got := doc.Validate(s, map[string]any{"c": "x"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %+v, want %+v", got, want)
+	}
+}
+
+func TestResponseSchemaFallsBackToDefault(t *testing.T) {
+	doc, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Operation("GET", "/api/admin/prompts")
+	if s := doc.ResponseSchema(op, 401, "application/problem+json"); s == nil || s.Ref != schemaRefPrefix+"ProblemDetails" {
+		t.Errorf("401 problem schema = %+v", s)
+	}
+	if s := doc.ResponseSchema(op, 200, "text/plain"); s != nil {
+		t.Errorf("undeclared content type schema = %+v", s)
+	}
+}
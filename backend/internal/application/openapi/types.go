@@ -0,0 +1,410 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// schemaRefPrefix 组件结构的引用前缀
+const schemaRefPrefix = "#/components/schemas/"
+
+// typeResolver 从源码解析 Go 类型并转换为 Schema，结构体注册为组件并以 $ref 引用
+type typeResolver struct {
+	root    string // 模块根目录
+	module  string // 模块路径
+	fset    *token.FileSet
+	pkgs    map[string]*sourcePackage // 导入路径 -> 已解析的包
+	schemas map[string]*Schema        // 组件名 -> 结构
+	origins map[string]string         // 组件名 -> 类型全名，用于检测不同包中的同名类型
+}
+
+// sourcePackage 已解析的包中声明的类型
+type sourcePackage struct {
+	path  string
+	types map[string]*typeDecl
+}
+
+// typeDecl 类型声明及其所在文件（解析字段类型时需要文件的导入）
+type typeDecl struct {
+	spec *ast.TypeSpec
+	doc  string
+	file *ast.File
+}
+
+func newTypeResolver(root, module string) *typeResolver {
+	return &typeResolver{
+		root:    root,
+		module:  module,
+		fset:    token.NewFileSet(),
+		pkgs:    make(map[string]*sourcePackage),
+		schemas: make(map[string]*Schema),
+		origins: make(map[string]string),
+	}
+}
+
+// parseDir 解析目录中的非测试 Go 文件
+func (r *typeResolver) parseDir(dir string) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(r.fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// dir 返回模块内导入路径对应的目录
+func (r *typeResolver) dir(pkgPath string) (string, bool) {
+	rel, ok := strings.CutPrefix(pkgPath, r.module+"/")
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(r.root, filepath.FromSlash(rel)), true
+}
+
+// load 解析模块内的包
+func (r *typeResolver) load(pkgPath string) (*sourcePackage, error) {
+	if pkg, ok := r.pkgs[pkgPath]; ok {
+		return pkg, nil
+	}
+	dir, ok := r.dir(pkgPath)
+	if !ok {
+		return nil, fmt.Errorf("package %s is outside module %s", pkgPath, r.module)
+	}
+	files, err := r.parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &sourcePackage{path: pkgPath, types: make(map[string]*typeDecl)}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				pkg.types[ts.Name.Name] = &typeDecl{spec: ts, doc: doc.Text(), file: f}
+			}
+		}
+	}
+	r.pkgs[pkgPath] = pkg
+	return pkg, nil
+}
+
+// importPath 按文件的导入声明查找包名对应的导入路径
+func importPath(f *ast.File, name string) (string, bool) {
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		local := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			local = imp.Name.Name
+		}
+		if local == name {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// resolve 将 file（属于包 pkgPath）中的类型表达式转换为 Schema
+func (r *typeResolver) resolve(expr ast.Expr, file *ast.File, pkgPath string) (*Schema, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if s, ok := basicSchema(t.Name); ok {
+			return s, nil
+		}
+		return r.named(pkgPath, t.Name)
+	case *ast.SelectorExpr:
+		pkgName, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", types.ExprString(expr))
+		}
+		path, ok := importPath(file, pkgName.Name)
+		if !ok {
+			return nil, fmt.Errorf("unknown package %s", pkgName.Name)
+		}
+		switch path + "." + t.Sel.Name {
+		case "encoding/json.RawMessage":
+			return &Schema{}, nil // 任意 JSON
+		case "time.Time":
+			return &Schema{Type: "string", Format: "date-time"}, nil
+		}
+		return r.named(path, t.Sel.Name)
+	case *ast.StarExpr:
+		return r.resolve(t.X, file, pkgPath)
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := r.resolve(t.Elt, file, pkgPath)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		if key, ok := t.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, fmt.Errorf("unsupported map key in %s", types.ExprString(expr))
+		}
+		value, err := r.resolve(t.Value, file, pkgPath)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: value}, nil
+	case *ast.InterfaceType:
+		return &Schema{}, nil // 任意值
+	case *ast.StructType:
+		return r.structSchema(t, file, pkgPath)
+	}
+	return nil, fmt.Errorf("unsupported type %s", types.ExprString(expr))
+}
+
+// named 解析包中声明的类型：结构体注册为组件并返回引用，其他类型按底层类型展开
+func (r *typeResolver) named(pkgPath, name string) (*Schema, error) {
+	pkg, err := r.load(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	decl, ok := pkg.types[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s.%s not found", pkgPath, name)
+	}
+	if _, ok := decl.spec.Type.(*ast.StructType); !ok {
+		return r.resolve(decl.spec.Type, decl.file, pkgPath)
+	}
+
+	ref := &Schema{Ref: schemaRefPrefix + name}
+	fullName := pkgPath + "." + name
+	if origin, ok := r.origins[name]; ok {
+		if origin != fullName {
+			return nil, fmt.Errorf("schema name %s is used by both %s and %s", name, origin, fullName)
+		}
+		return ref, nil
+	}
+	// 先登记再展开，支持递归引用
+	r.origins[name] = fullName
+	s, err := r.resolve(decl.spec.Type, decl.file, pkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	s.Description = docDescription(decl.doc, name)
+	r.schemas[name] = s
+	return ref, nil
+}
+
+// structSchema 按 json 标签展开结构体字段，binding 标签转换为必填、长度、范围和枚举约束
+func (r *typeResolver) structSchema(st *ast.StructType, file *ast.File, pkgPath string) (*Schema, error) {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			value, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(value)
+		}
+
+		// 嵌入的结构体字段提升到外层
+		if len(field.Names) == 0 {
+			embedded, err := r.resolve(field.Type, file, pkgPath)
+			if err != nil {
+				return nil, err
+			}
+			if target := r.deref(embedded); target != nil {
+				for name, prop := range target.Properties {
+					s.Properties[name] = prop
+				}
+				s.Required = append(s.Required, target.Required...)
+			}
+			continue
+		}
+
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			name, jsonOpts, _ := strings.Cut(tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = ident.Name
+			}
+
+			prop, err := r.resolve(field.Type, file, pkgPath)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", ident.Name, err)
+			}
+			if alternatives, ok := strings.CutPrefix(tag.Get("openapi"), "oneOf="); ok {
+				if prop, err = r.oneOf(alternatives, file, pkgPath); err != nil {
+					return nil, fmt.Errorf("field %s: %w", ident.Name, err)
+				}
+			}
+			required := applyBinding(prop, tag.Get("binding"))
+			// 未设置 omitempty 的 nil 切片和 map 序列化为 null
+			if !required && (prop.Type == "array" || prop.AdditionalProperties != nil) && !strings.Contains(jsonOpts, "omitempty") {
+				prop.Nullable = true
+			}
+			if desc := fieldDescription(field); desc != "" {
+				if prop.Ref != "" {
+					// $ref 的同级字段会被忽略，带说明的引用包一层 allOf
+					prop = &Schema{AllOf: []*Schema{prop}}
+				}
+				prop.Description = desc
+			}
+			s.Properties[name] = prop
+			if required {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+	return s, nil
+}
+
+// oneOf 解析 openapi:"oneOf=类型,规则|类型,规则" 标签，用于取值可以是多种类型的 interface{} 字段
+// 每个候选类型后可跟与 binding 标签相同写法的规则，如 oneOf=string,max=100|[]Part,max=10
+func (r *typeResolver) oneOf(alternatives string, file *ast.File, pkgPath string) (*Schema, error) {
+	s := &Schema{}
+	for _, alt := range strings.Split(alternatives, "|") {
+		typ, rules, _ := strings.Cut(alt, ",")
+		expr, err := parser.ParseExpr(typ)
+		if err != nil {
+			return nil, fmt.Errorf("invalid oneOf type %q: %w", typ, err)
+		}
+		option, err := r.resolve(expr, file, pkgPath)
+		if err != nil {
+			return nil, err
+		}
+		applyBinding(option, rules)
+		s.OneOf = append(s.OneOf, option)
+	}
+	return s, nil
+}
+
+// deref 返回组件引用对应的结构
+func (r *typeResolver) deref(s *Schema) *Schema {
+	if name, ok := strings.CutPrefix(s.Ref, schemaRefPrefix); ok {
+		return r.schemas[name]
+	}
+	return s
+}
+
+// applyBinding 将 gin binding 标签转换为 Schema 约束，返回字段是否必填
+// dive 之后的规则作用于数组元素
+func applyBinding(s *Schema, binding string) bool {
+	if binding == "" {
+		return false
+	}
+	required := false
+	target := s
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			if target == s {
+				required = true
+			}
+		case "dive":
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+		case "oneof":
+			target.Enum = strings.Fields(value)
+		case "min", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			setLimit(target, key == "min", n)
+		}
+	}
+	return required
+}
+
+// setLimit 按类型设置长度、数量或数值范围
+func setLimit(s *Schema, isMin bool, n int) {
+	switch s.Type {
+	case "string":
+		if isMin {
+			s.MinLength = &n
+		} else {
+			s.MaxLength = &n
+		}
+	case "array":
+		if isMin {
+			s.MinItems = &n
+		} else {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		v := float64(n)
+		if isMin {
+			s.Minimum = &v
+		} else {
+			s.Maximum = &v
+		}
+	}
+}
+
+// basicSchema 内置类型对应的 Schema
+func basicSchema(name string) (*Schema, bool) {
+	switch name {
+	case "string":
+		return &Schema{Type: "string"}, true
+	case "bool":
+		return &Schema{Type: "boolean"}, true
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+		return &Schema{Type: "integer"}, true
+	case "int64", "uint64":
+		return &Schema{Type: "integer", Format: "int64"}, true
+	case "float32":
+		return &Schema{Type: "number", Format: "float"}, true
+	case "float64":
+		return &Schema{Type: "number", Format: "double"}, true
+	case "any":
+		return &Schema{}, true
+	}
+	return nil, false
+}
+
+// docDescription 去掉文档注释开头的类型名
+func docDescription(doc, name string) string {
+	doc = strings.TrimSpace(doc)
+	if rest, ok := strings.CutPrefix(doc, name); ok {
+		doc = strings.TrimSpace(rest)
+	}
+	return doc
+}
+
+// fieldDescription 返回字段的行尾注释或文档注释
+func fieldDescription(field *ast.Field) string {
+	if field.Comment != nil {
+		return strings.TrimSpace(field.Comment.Text())
+	}
+	if field.Doc != nil {
+		return strings.TrimSpace(field.Doc.Text())
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// responseRefPrefix 组件响应的引用前缀
+const responseRefPrefix = "#/components/responses/"
+
+// Violation 值与文档不符的一处
+// Rule 与 binding 标签的规则名一致（required、oneof、min、max、gte、lte），类型不符时为 type
+type Violation struct {
+	Field string // JSON 路径，如 messages[0].role；值本身不符时为空
+	Rule  string
+	Param string // 规则参数：长度或数量上下限、取值范围、可选值（空格分隔）或期望的 JSON 类型
+}
+
+// Operation 按方法和路由查找操作，路由可以是文档中的 {param} 写法或 gin 的 :param 写法
+func (d *Document) Operation(method, route string) *Operation {
+	return d.Paths[TemplatePath(route)][strings.ToLower(method)]
+}
+
+// TemplatePath 将 gin 路由的 :param、*param 转换为 OpenAPI 的 {param}
+func TemplatePath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, s := range segments {
+		if len(s) > 1 && (s[0] == ':' || s[0] == '*') {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// RequestSchema 返回操作在 contentType 下的请求体结构，没有时返回 nil
+func (op *Operation) RequestSchema(contentType string) *Schema {
+	if op.RequestBody == nil {
+		return nil
+	}
+	return mediaSchema(op.RequestBody.Content, contentType)
+}
+
+// ResponseSchema 返回操作在状态码 status、内容类型 contentType 下的响应结构，未声明的状态码使用 default
+func (d *Document) ResponseSchema(op *Operation, status int, contentType string) *Schema {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return nil
+	}
+	if name, ok := strings.CutPrefix(resp.Ref, responseRefPrefix); ok {
+		if resp = d.Components.Responses[name]; resp == nil {
+			return nil
+		}
+	}
+	return mediaSchema(resp.Content, contentType)
+}
+
+// mediaSchema 按内容类型（忽略 charset 等参数）查找结构
+func mediaSchema(content map[string]*MediaType, contentType string) *Schema {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	if m := content[mediaType]; m != nil {
+		return m.Schema
+	}
+	return nil
+}
+
+// IsJSON 判断内容类型是否为 JSON（包括 application/problem+json 等 +json 类型）
+func IsJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// ValidateJSON 解析 JSON 文本并按 s 校验，JSON 格式错误时返回 error
+func (d *Document) ValidateJSON(s *Schema, data []byte) ([]Violation, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return d.Validate(s, v), nil
+}
+
+// Validate 按 s 校验已解析的 JSON 值，数字需以 json.Number 表示（json.Decoder.UseNumber）
+func (d *Document) Validate(s *Schema, v any) []Violation {
+	var out []Violation
+	d.validate(s, v, "", &out)
+	return out
+}
+
+func (d *Document) validate(s *Schema, v any, path string, out *[]Violation) {
+	if s == nil {
+		return
+	}
+	if name, ok := strings.CutPrefix(s.Ref, schemaRefPrefix); ok {
+		d.validate(d.Components.Schemas[name], v, path, out)
+		return
+	}
+	for _, sub := range s.AllOf {
+		d.validate(sub, v, path, out)
+	}
+	if len(s.OneOf) > 0 {
+		d.validateOneOf(s.OneOf, v, path, out)
+		return
+	}
+
+	if v == nil {
+		if s.Type != "" && !s.Nullable {
+			*out = append(*out, Violation{Field: path, Rule: "type", Param: s.Type})
+		}
+		return
+	}
+	if s.Type != "" && !matchesType(s.Type, v) {
+		*out = append(*out, Violation{Field: path, Rule: "type", Param: s.Type})
+		return
+	}
+
+	switch val := v.(type) {
+	case string:
+		if len(s.Enum) > 0 && !contains(s.Enum, val) {
+			*out = append(*out, Violation{Field: path, Rule: "oneof", Param: strings.Join(s.Enum, " ")})
+		}
+		checkCount(utf8.RuneCountInString(val), s.MinLength, s.MaxLength, path, out)
+	case json.Number:
+		n, _ := val.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			*out = append(*out, Violation{Field: path, Rule: "gte", Param: formatNumber(*s.Minimum)})
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			*out = append(*out, Violation{Field: path, Rule: "lte", Param: formatNumber(*s.Maximum)})
+		}
+	case []any:
+		checkCount(len(val), s.MinItems, s.MaxItems, path, out)
+		for i, item := range val {
+			d.validate(s.Items, item, path+"["+strconv.Itoa(i)+"]", out)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if value, ok := val[name]; !ok || value == nil || value == "" {
+				*out = append(*out, Violation{Field: joinPath(path, name), Rule: "required"})
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			if val[k] == nil && contains(s.Required, k) {
+				continue // 已报告为必填
+			}
+			d.validate(prop, val[k], joinPath(path, k), out)
+		}
+	}
+}
+
+// validateOneOf 按值的 JSON 类型选择候选结构校验，没有类型匹配的候选时报告类型错误
+// 有多个类型匹配的候选（如两种对象）时，通过任意一个即可，都不通过时报告第一个候选的错误
+func (d *Document) validateOneOf(options []*Schema, v any, path string, out *[]Violation) {
+	if v == nil {
+		return // 是否必填由外层对象判断
+	}
+	var types []string
+	var first []Violation
+	matched := false
+	for _, option := range options {
+		resolved := option
+		if name, ok := strings.CutPrefix(option.Ref, schemaRefPrefix); ok {
+			resolved = d.Components.Schemas[name]
+		}
+		if resolved != nil && resolved.Type != "" && !matchesType(resolved.Type, v) {
+			types = append(types, resolved.Type)
+			continue
+		}
+		var violations []Violation
+		d.validate(option, v, path, &violations)
+		if len(violations) == 0 {
+			return
+		}
+		if !matched {
+			matched, first = true, violations
+		}
+	}
+	if matched {
+		*out = append(*out, first...)
+		return
+	}
+	*out = append(*out, Violation{Field: path, Rule: "type", Param: strings.Join(types, " | ")})
+}
+
+// checkCount 校验长度或数量上下限
+func checkCount(n int, min, max *int, path string, out *[]Violation) {
+	if min != nil && n < *min {
+		*out = append(*out, Violation{Field: path, Rule: "min", Param: strconv.Itoa(*min)})
+	}
+	if max != nil && n > *max {
+		*out = append(*out, Violation{Field: path, Rule: "max", Param: strconv.Itoa(*max)})
+	}
+}
+
+// matchesType 判断 JSON 值是否为指定类型
+func matchesType(typ string, v any) bool {
+	switch val := v.(type) {
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		if typ == "integer" {
+			_, err := val.Int64()
+			return err == nil
+		}
+		return typ == "number"
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
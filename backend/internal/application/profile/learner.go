@@ -0,0 +1,90 @@
+package profile
+
+import (
+	"ai-note-service/internal/application/schema"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Learner 单次请求生效的学习者画像：已保存的画像叠加请求中的覆盖
+type Learner struct {
+	GradeLevel string
+	Strengths  []string
+	Style      string
+	Version    string // 画像版本，如 v3；请求覆盖了画像时为 v3+override，没有保存的画像时为 override
+}
+
+// FromProfile 由已保存的画像创建
+func FromProfile(p *schema.LearnerProfile) *Learner {
+	return &Learner{
+		GradeLevel: p.GradeLevel,
+		Strengths:  p.Strengths,
+		Style:      p.Style,
+		Version:    fmt.Sprintf("v%d", p.Version),
+	}
+}
+
+// Apply 叠加请求中的覆盖，override 为空时原样返回；l 为 nil 时只使用覆盖的字段
+func (l *Learner) Apply(override schema.LearnerOverride) *Learner {
+	strengths := splitStrengths(override.Strengths)
+	if override.GradeLevel == "" && override.Style == "" && len(strengths) == 0 {
+		return l
+	}
+
+	out := &Learner{Version: "override"}
+	if l != nil {
+		*out = *l
+		out.Version = l.Version + "+override"
+	}
+	if override.GradeLevel != "" {
+		out.GradeLevel = override.GradeLevel
+	}
+	if len(strengths) > 0 {
+		out.Strengths = strengths
+	}
+	if override.Style != "" {
+		out.Style = override.Style
+	}
+	return out
+}
+
+// Empty 画像中没有任何会影响提示词的字段
+func (l *Learner) Empty() bool {
+	return l == nil || (l.GradeLevel == "" && len(l.Strengths) == 0 && l.Style == "")
+}
+
+// CacheKey 画像中影响提示词的字段，作为分析结果缓存键的一部分；与版本无关，内容相同的画像共用缓存
+func (l *Learner) CacheKey() string {
+	if l.Empty() {
+		return "learner:"
+	}
+	return fmt.Sprintf("learner:%s/%s/%s", l.GradeLevel, strings.Join(l.Strengths, ","), l.Style)
+}
+
+// splitStrengths 拆分逗号分隔的科目，去掉空白和空项
+func splitStrengths(s string) []string {
+	var out []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+type learnerKey struct{}
+
+// WithLearner 将生效的画像写入 context
+func WithLearner(ctx context.Context, l *Learner) context.Context {
+	return context.WithValue(ctx, learnerKey{}, l)
+}
+
+// LearnerFrom 从 context 读取生效的画像，没有时返回 nil
+func LearnerFrom(ctx context.Context) *Learner {
+	if ctx == nil {
+		return nil
+	}
+	l, _ := ctx.Value(learnerKey{}).(*Learner)
+	return l
+}
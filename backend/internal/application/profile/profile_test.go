@@ -0,0 +1,78 @@
+package profile
+
+import (
+	"ai-note-service/internal/application/schema"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStoreReplaysFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.jsonl")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	if _, err := store.Put("alice", &schema.LearnerProfileRequest{GradeLevel: schema.GradePrimary}, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put("bob", &schema.LearnerProfileRequest{GradeLevel: schema.GradeUniversity}, now); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := store.Put("alice", &schema.LearnerProfileRequest{GradeLevel: schema.GradeMiddle, Strengths: []string{"数学"}}, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Version != 2 {
+		t.Errorf("version = %d, want 2", saved.Version)
+	}
+	saved.Strengths[0] = "modified"
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后以每个用户的最后一条记录为准
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	got, ok := store.Get("alice")
+	want := &schema.LearnerProfile{GradeLevel: schema.GradeMiddle, Strengths: []string{"数学"}, Version: 2, UpdatedAt: now.Add(time.Hour)}
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("alice = %+v, want %+v", got, want)
+	}
+	if got, ok := store.Get("bob"); !ok || got.Version != 1 {
+		t.Errorf("bob = %+v", got)
+	}
+	if _, ok := store.Get("carol"); ok {
+		t.Error("unknown user has a profile")
+	}
+}
+
+func TestLearnerApply(t *testing.T) {
+	stored := FromProfile(&schema.LearnerProfile{GradeLevel: schema.GradeHigh, Style: schema.StyleConcise, Version: 3})
+
+	if got := stored.Apply(schema.LearnerOverride{}); got != stored {
+		t.Errorf("empty override = %+v, want the stored profile", got)
+	}
+
+	got := stored.Apply(schema.LearnerOverride{Style: schema.StyleExamples, Strengths: " 物理，化学, "})
+	want := &Learner{GradeLevel: schema.GradeHigh, Strengths: []string{"物理", "化学"}, Style: schema.StyleExamples, Version: "v3+override"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("override = %+v, want %+v", got, want)
+	}
+	if stored.Style != schema.StyleConcise {
+		t.Errorf("stored profile modified: %+v", stored)
+	}
+
+	var none *Learner
+	if got := none.Apply(schema.LearnerOverride{GradeLevel: schema.GradePrimary}); got.Version != "override" || got.GradeLevel != schema.GradePrimary {
+		t.Errorf("override without profile = %+v", got)
+	}
+	if !none.Empty() || none.CacheKey() != (&Learner{Version: "v1"}).CacheKey() {
+		t.Error("profiles without prompt fields should share the cache key")
+	}
+}
@@ -0,0 +1,144 @@
+// Package profile 学习者画像：按用户保存年级、擅长科目、偏好语言和讲解方式，分析和对话时注入提示词
+package profile
+
+import (
+	"ai-note-service/internal/application/schema"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxRecordSize 单条画像记录的最大字节数（读取文件时使用）
+const maxRecordSize = 64 << 10
+
+// record 画像记录，以 JSONL 格式保存，每次保存追加一行
+type record struct {
+	UserID string `json:"userId"`
+	schema.LearnerProfile
+}
+
+// Store 画像存储
+// path 不为空时每次保存追加写入 JSONL 文件，启动时按顺序重放，同一用户以最后一条为准；为空时只保存在内存中
+type Store struct {
+	mu       sync.RWMutex
+	file     *os.File
+	profiles map[string]*schema.LearnerProfile
+}
+
+// NewMemoryStore 创建内存画像存储
+func NewMemoryStore() *Store {
+	return &Store{profiles: make(map[string]*schema.LearnerProfile)}
+}
+
+// Open 打开文件画像存储，文件不存在时创建
+func Open(path string) (*Store, error) {
+	if path == "" {
+		return NewMemoryStore(), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create profile directory: %w", err)
+	}
+
+	s := NewMemoryStore()
+	if err := s.load(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("load profiles: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open profile file: %w", err)
+	}
+	s.file = file
+	return s, nil
+}
+
+// load 重放 JSONL 文件中的画像记录
+func (s *Store) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		s.profiles[r.UserID] = &r.LearnerProfile
+	}
+	return scanner.Err()
+}
+
+// Get 返回用户的画像副本，未保存过时返回 false
+func (s *Store) Get(userID string) (*schema.LearnerProfile, bool) {
+	if userID == "" {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.profiles[userID]
+	if !ok {
+		return nil, false
+	}
+	copied := *p
+	copied.Strengths = slices.Clone(p.Strengths)
+	return &copied, true
+}
+
+// Put 整体替换用户的画像，版本号在上一版本基础上加 1，返回保存后的画像
+func (s *Store) Put(userID string, req *schema.LearnerProfileRequest, now time.Time) (*schema.LearnerProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &schema.LearnerProfile{
+		GradeLevel: req.GradeLevel,
+		Strengths:  slices.Clone(req.Strengths),
+		Language:   req.Language,
+		Style:      req.Style,
+		Version:    1,
+		UpdatedAt:  now.UTC(),
+	}
+	if prev, ok := s.profiles[userID]; ok {
+		p.Version = prev.Version + 1
+	}
+
+	if s.file != nil {
+		line, err := json.Marshal(record{UserID: userID, LearnerProfile: *p})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.file.Write(append(line, '\n')); err != nil {
+			return nil, fmt.Errorf("write profile: %w", err)
+		}
+	}
+	s.profiles[userID] = p
+
+	copied := *p
+	copied.Strengths = slices.Clone(p.Strengths)
+	return &copied, nil
+}
+
+// Close 关闭文件
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
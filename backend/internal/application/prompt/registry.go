@@ -0,0 +1,337 @@
+package prompt
+
+import (
+	"ai-note-service/internal/application/i18n"
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 提示词片段名称，模板通过 {{define "system"}} / {{define "user"}} 定义
+const (
+	SectionSystem = "system"
+	SectionUser   = "user"
+)
+
+// fileExt 模板文件扩展名
+const fileExt = ".tmpl"
+
+// frontMatterDelimiter 模板文件头部元数据分隔符
+const frontMatterDelimiter = "---"
+
+// variantPattern 模板变体名称格式
+var variantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+// Template 提示词模板
+type Template struct {
+	Name        string        // 模板名称，如 analysis
+	Variant     string        // 模板变体，用于 A/B 实验，为空表示默认版本
+	Language    i18n.Language // 模板语言，为空表示与语言无关
+	Version     string        // 模板文件中声明的版本号
+	Description string
+	Digest      string   // 模板内容摘要，未修改版本号的内容变更也会体现在 VersionID 中
+	Sections    []string // 模板定义的片段
+	Source      string   // 模板来源（embedded 或文件路径）
+
+	tmpl *template.Template
+}
+
+// VersionID 返回模板版本标识，如 analysis/zh-CN@v1:3f2a9c1b0d4e，变体为 analysis+concise/zh-CN@v1:...
+// 与分析结果一起保存，并作为缓存键的一部分
+func (t *Template) VersionID() string {
+	lang := string(t.Language)
+	if lang == "" {
+		lang = "any"
+	}
+	name := t.Name
+	if t.Variant != "" {
+		name += "+" + t.Variant
+	}
+	return fmt.Sprintf("%s/%s@%s:%s", name, lang, t.Version, t.Digest)
+}
+
+// Prompt 渲染后的提示词
+type Prompt struct {
+	VersionID string
+	System    string
+	User      string
+}
+
+// CacheKey 基于模板版本和其他输入（模型、图片摘要等）生成缓存键
+// 模板内容变化后缓存键随之变化，旧结果不会被误用
+func (p *Prompt) CacheKey(parts ...string) string {
+	return CacheKey(append([]string{p.VersionID}, parts...)...)
+}
+
+// CacheKey 将各部分拼接后计算 sha256 作为缓存键
+func CacheKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// templateKey 注册表索引
+type templateKey struct {
+	name    string
+	variant string
+	lang    i18n.Language
+}
+
+// Registry 提示词模板注册表，创建后只读
+type Registry struct {
+	templates map[templateKey]*Template
+}
+
+// Load 加载提示词模板：先加载内置模板，再用 dir 目录中的同名模板覆盖
+// 模板文件命名为 <name>.<lang>.tmpl 或 <name>.tmpl（与语言无关），A/B 实验变体命名为 <name>+<variant>.<lang>.tmpl，
+// 文件以 YAML 头部声明 version 和 description；dir 为空时只使用内置模板
+func Load(dir string) (*Registry, error) {
+	r := &Registry{templates: make(map[templateKey]*Template)}
+
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadFS(sub, "embedded"); err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		if err := r.loadFS(os.DirFS(dir), dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// loadFS 加载文件系统根目录下的所有模板，错误一次性返回
+func (r *Registry) loadFS(fsys fs.FS, source string) error {
+	files, err := fs.Glob(fsys, "*"+fileExt)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t, err := parseTemplate(file, data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path.Join(source, file), err))
+			continue
+		}
+		t.Source = source
+		r.templates[templateKey{name: t.Name, variant: t.Variant, lang: t.Language}] = t
+	}
+	return errors.Join(errs...)
+}
+
+// frontMatter 模板文件头部元数据
+type frontMatter struct {
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+}
+
+// parseTemplate 解析模板文件，并使用变量类型的零值试渲染，提前发现字段名错误
+func parseTemplate(file string, data []byte) (*Template, error) {
+	base := strings.TrimSuffix(file, fileExt)
+	fullName, lang, hasLang := strings.Cut(base, ".")
+	name, variant, hasVariant := strings.Cut(fullName, "+")
+
+	t := &Template{Name: name, Variant: variant}
+	if hasVariant && !variantPattern.MatchString(variant) {
+		return nil, fmt.Errorf("invalid variant %q in file name, expected lowercase letters, digits, '-' or '_'", variant)
+	}
+	if hasLang {
+		l, ok := i18n.Match(lang)
+		if !ok || string(l) != lang {
+			return nil, fmt.Errorf("unsupported language %q in file name, expected one of %v", lang, i18n.Supported())
+		}
+		t.Language = l
+	}
+
+	varsType, ok := variables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q, expected one of %v", name, Names())
+	}
+
+	meta, body, err := splitFrontMatter(data)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Version == "" {
+		return nil, errors.New("version is required in front matter")
+	}
+	t.Version = meta.Version
+	t.Description = meta.Description
+
+	sum := sha256.Sum256(data)
+	t.Digest = hex.EncodeToString(sum[:6])
+
+	t.tmpl, err = template.New(name).Option("missingkey=error").Funcs(funcs).Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, section := range []string{SectionSystem, SectionUser} {
+		if t.tmpl.Lookup(section) != nil {
+			t.Sections = append(t.Sections, section)
+		}
+	}
+	if t.tmpl.Lookup(SectionSystem) == nil {
+		return nil, fmt.Errorf("template must define %q", SectionSystem)
+	}
+
+	if _, err := t.render(reflect.Zero(varsType).Interface()); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// splitFrontMatter 拆分 YAML 头部和模板正文
+func splitFrontMatter(data []byte) (frontMatter, string, error) {
+	var meta frontMatter
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(content, frontMatterDelimiter+"\n") {
+		return meta, "", errors.New("missing front matter")
+	}
+	header, body, found := strings.Cut(content[len(frontMatterDelimiter)+1:], "\n"+frontMatterDelimiter+"\n")
+	if !found {
+		return meta, "", errors.New("unterminated front matter")
+	}
+	if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+		return meta, "", fmt.Errorf("invalid front matter: %w", err)
+	}
+	return meta, body, nil
+}
+
+// render 渲染模板的各个片段
+func (t *Template) render(vars interface{}) (*Prompt, error) {
+	p := &Prompt{VersionID: t.VersionID()}
+	for _, section := range t.Sections {
+		var buf bytes.Buffer
+		if err := t.tmpl.ExecuteTemplate(&buf, section, vars); err != nil {
+			return nil, err
+		}
+		switch section {
+		case SectionSystem:
+			p.System = strings.TrimSpace(buf.String())
+		case SectionUser:
+			p.User = strings.TrimSpace(buf.String())
+		}
+	}
+	return p, nil
+}
+
+// Lookup 查找默认版本的模板
+func (r *Registry) Lookup(name string, lang i18n.Language) (*Template, error) {
+	return r.LookupVariant(name, "", lang)
+}
+
+// LookupVariant 查找模板：优先匹配语言，其次使用与语言无关的模板，最后回退到中文模板；
+// 变体没有对应语言（或与语言无关）的模板时使用默认版本，实际使用的版本可通过 VersionID 确认
+func (r *Registry) LookupVariant(name, variant string, lang i18n.Language) (*Template, error) {
+	if variant != "" {
+		for _, l := range []i18n.Language{lang, ""} {
+			if t, ok := r.templates[templateKey{name: name, variant: variant, lang: l}]; ok {
+				return t, nil
+			}
+		}
+	}
+	for _, l := range []i18n.Language{lang, "", i18n.ZhCN} {
+		if t, ok := r.templates[templateKey{name: name, lang: l}]; ok {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("prompt template %q not found", name)
+}
+
+// HasVariant 判断模板变体是否存在（任意语言）
+func (r *Registry) HasVariant(name, variant string) bool {
+	for key := range r.templates {
+		if key.name == name && key.variant == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// Render 渲染默认版本的提示词，vars 的类型必须与模板声明的变量类型一致
+func (r *Registry) Render(name string, lang i18n.Language, vars interface{}) (*Prompt, error) {
+	return r.RenderVariant(name, "", lang, vars)
+}
+
+// RenderVariant 渲染指定变体的提示词，variant 为空时使用默认版本
+func (r *Registry) RenderVariant(name, variant string, lang i18n.Language, vars interface{}) (*Prompt, error) {
+	varsType, ok := variables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown prompt template %q", name)
+	}
+	if got := reflect.TypeOf(vars); got != varsType {
+		return nil, fmt.Errorf("prompt template %q expects variables of type %s, got %v", name, varsType, got)
+	}
+
+	t, err := r.LookupVariant(name, variant, lang)
+	if err != nil {
+		return nil, err
+	}
+	p, err := t.render(vars)
+	if err != nil {
+		return nil, fmt.Errorf("render prompt template %s: %w", t.VersionID(), err)
+	}
+	return p, nil
+}
+
+// List 返回所有模板，按名称、变体和语言排序
+func (r *Registry) List() []*Template {
+	list := make([]*Template, 0, len(r.templates))
+	for _, t := range r.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		if list[i].Variant != list[j].Variant {
+			return list[i].Variant < list[j].Variant
+		}
+		return list[i].Language < list[j].Language
+	})
+	return list
+}
+
+// funcs 模板中可用的函数
+var funcs = template.FuncMap{
+	// seq 返回 1 到 n 的序列，用于按数量生成示例条目：{{range $i, $n := seq .Count}}
+	"seq": func(n int) []int {
+		out := make([]int, max(n, 0))
+		for i := range out {
+			out[i] = i + 1
+		}
+		return out
+	},
+}
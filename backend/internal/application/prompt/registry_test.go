@@ -0,0 +1,163 @@
+package prompt
+
+import (
+	"ai-note-service/internal/application/i18n"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedTemplates(t *testing.T) {
+	r, err := Load("")
+	if err != nil {
+		t.Fatalf("Load embedded: %v", err)
+	}
+
+	p, err := r.Render(NameAnalysis, i18n.En, AnalysisVars{})
+	if err != nil {
+		t.Fatalf("Render analysis: %v", err)
+	}
+	if !strings.HasPrefix(p.System, "You are a professional educational content analyst") || p.User == "" {
+		t.Errorf("unexpected analysis prompt: %+v", p)
+	}
+	if !strings.HasPrefix(p.VersionID, "analysis/en@v1:") {
+		t.Errorf("VersionID = %q", p.VersionID)
+	}
+
+	p, err = r.Render(NameDialogue, i18n.ZhCN, DialogueVars{Title: "勾股定理", Description: "直角三角形三边关系"})
+	if err != nil {
+		t.Fatalf("Render dialogue: %v", err)
+	}
+	if !strings.Contains(p.System, "当前讨论的知识点是：勾股定理") || !strings.Contains(p.System, "知识点描述：直角三角形三边关系") {
+		t.Errorf("dialogue variables not rendered: %q", p.System)
+	}
+
+	// 与语言无关的模板对所有语言生效
+	p, err = r.Render(NameChat, i18n.En, ChatVars{})
+	if err != nil {
+		t.Fatalf("Render chat: %v", err)
+	}
+	if p.System != "You are a helpful assistant." || !strings.HasPrefix(p.VersionID, "chat/any@v1:") {
+		t.Errorf("unexpected chat prompt: %+v", p)
+	}
+}
+
+func TestRenderRejectsWrongVariableType(t *testing.T) {
+	r, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := r.Render(NameDialogue, i18n.ZhCN, AnalysisVars{}); err == nil {
+		t.Fatal("expected error for mismatched variable type")
+	}
+}
+
+func TestDirOverridesEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "dialogue.en.tmpl", "---\nversion: v2\n---\n{{define \"system\"}}Tutor for {{.Title}}{{end}}\n")
+
+	r, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	p, err := r.Render(NameDialogue, i18n.En, DialogueVars{Title: "limits"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if p.System != "Tutor for limits" || !strings.HasPrefix(p.VersionID, "dialogue/en@v2:") {
+		t.Errorf("override not applied: %+v", p)
+	}
+
+	// 未覆盖的模板仍使用内置版本
+	if tmpl, _ := r.Lookup(NameDialogue, i18n.ZhCN); tmpl.Source != "embedded" {
+		t.Errorf("zh-CN dialogue source = %q, want embedded", tmpl.Source)
+	}
+}
+
+func TestRenderVariant(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "dialogue+socratic.en.tmpl", "---\nversion: v1\n---\n{{define \"system\"}}Ask about {{.Title}}{{end}}\n")
+
+	r, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !r.HasVariant(NameDialogue, "socratic") || r.HasVariant(NameDialogue, "missing") {
+		t.Error("HasVariant mismatch")
+	}
+
+	p, err := r.RenderVariant(NameDialogue, "socratic", i18n.En, DialogueVars{Title: "limits"})
+	if err != nil {
+		t.Fatalf("RenderVariant: %v", err)
+	}
+	if p.System != "Ask about limits" || !strings.HasPrefix(p.VersionID, "dialogue+socratic/en@v1:") {
+		t.Errorf("variant not applied: %+v", p)
+	}
+
+	// 变体没有中文模板时回退到默认版本
+	p, err = r.RenderVariant(NameDialogue, "socratic", i18n.ZhCN, DialogueVars{Title: "极限"})
+	if err != nil {
+		t.Fatalf("RenderVariant zh-CN: %v", err)
+	}
+	if !strings.HasPrefix(p.VersionID, "dialogue/zh-CN@") {
+		t.Errorf("expected fallback to default zh-CN template, got %s", p.VersionID)
+	}
+}
+
+func TestVersionIDChangesWithContent(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "chat.tmpl", "---\nversion: v1\n---\n{{define \"system\"}}A{{end}}\n")
+	a, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	writeTemplate(t, dir, "chat.tmpl", "---\nversion: v1\n---\n{{define \"system\"}}B{{end}}\n")
+	b, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	pa, _ := a.Render(NameChat, i18n.En, ChatVars{})
+	pb, _ := b.Render(NameChat, i18n.En, ChatVars{})
+	if pa.VersionID == pb.VersionID {
+		t.Errorf("VersionID should change when content changes: %s", pa.VersionID)
+	}
+	if pa.CacheKey("model", "img") == pb.CacheKey("model", "img") {
+		t.Error("CacheKey should change when template version changes")
+	}
+}
+
+func TestLoadRejectsInvalidTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"unknown field", "dialogue.en.tmpl", "---\nversion: v1\n---\n{{define \"system\"}}{{.Titel}}{{end}}\n"},
+		{"unknown template", "summary.en.tmpl", "---\nversion: v1\n---\n{{define \"system\"}}x{{end}}\n"},
+		{"missing version", "chat.tmpl", "---\ndescription: x\n---\n{{define \"system\"}}x{{end}}\n"},
+		{"missing system", "chat.tmpl", "---\nversion: v1\n---\n{{define \"user\"}}x{{end}}\n"},
+		{"invalid variant", "chat+Bad Name.tmpl", "---\nversion: v1\n---\n{{define \"system\"}}x{{end}}\n"},
+		{"unsupported language", "chat.fr.tmpl", "---\nversion: v1\n---\n{{define \"system\"}}x{{end}}\n"},
+		{"syntax error", "chat.tmpl", "---\nversion: v1\n---\n{{define \"system\"}}{{if}}{{end}}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTemplate(t, dir, tt.file, tt.content)
+			if _, err := Load(dir); err == nil {
+				t.Fatal("expected load error")
+			}
+		})
+	}
+}
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+package prompt
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// 模板名称
+const (
+	NameAnalysis = "analysis" // 图片知识点分析
+	NameDialogue = "dialogue" // 知识点对话
+	NameChat     = "chat"     // 简化聊天接口
+	NameCheck    = "check"    // 手写作业批改
+	NameRelated  = "related"  // 补充数量不足的前置、后置知识点
+)
+
+// AnalysisVars 图片分析模板变量
+type AnalysisVars struct {
+	Pages     int `json:"pages"`     // 本次请求包含的图片数，多于 1 张时按连续页面分析并标注来源页码
+	FirstPage int `json:"firstPage"` // 第一张图片的页码，分批请求时后续批次不从 1 开始
+	LastPage  int `json:"lastPage"`  // 最后一张图片的页码
+	TextPages int `json:"textPages"` // 以文字（PDF 文字层）而非图片给出的页数
+
+	MinKeyPoints   int `json:"minKeyPoints"`   // 重点知识点数量下限
+	KeyPoints      int `json:"keyPoints"`      // 重点知识点数量上限
+	Prerequisites  int `json:"prerequisites"`  // 前置知识点数量
+	Postrequisites int `json:"postrequisites"` // 后置知识点数量
+	FunExamples    int `json:"funExamples"`    // 每个重点知识点的趣味示例数量
+
+	Learner *Learner `json:"learner,omitempty"` // 学习者画像，没有画像时为空
+}
+
+// Learner 学习者画像变量，均为按输出语言本地化的描述，未设置的字段为空
+type Learner struct {
+	Grade     string `json:"grade"`     // 年级，如 小学生（约 6-12 岁）
+	Strengths string `json:"strengths"` // 擅长的科目
+	Style     string `json:"style"`     // 偏好的讲解方式
+}
+
+// DialogueVars 知识点对话模板变量
+type DialogueVars struct {
+	Title       string `json:"title"`       // 知识点标题
+	Description string `json:"description"` // 知识点描述
+	HasImage    bool   `json:"hasImage"`    // 第一条用户消息是否附带知识点所在的原图或区域截图
+
+	Learner *Learner `json:"learner,omitempty"` // 学习者画像，没有画像时为空
+
+	Strategy    string `json:"strategy"`    // 辅导策略：direct、socratic、worked_example、check_understanding，为空时按 direct
+	Hint        bool   `json:"hint"`        // 学生已连续多轮没有进展，需要给出更具体的提示
+	GoalReached bool   `json:"goalReached"` // 学生已达成辅导目标，进入总结和巩固
+}
+
+// CheckVars 作业批改模板变量
+type CheckVars struct {
+	Pages int `json:"pages"` // 作业页数，多于 1 页时每页前标注页码
+}
+
+// RelatedVars 补充前置、后置知识点模板变量
+type RelatedVars struct {
+	KeyPoints      string `json:"keyPoints"`      // 重点知识点标题，每行一个
+	Existing       string `json:"existing"`       // 已有的前置、后置知识点标题，每行一个，补充的不能与之重复
+	Prerequisites  int    `json:"prerequisites"`  // 需要补充的前置知识点数量
+	Postrequisites int    `json:"postrequisites"` // 需要补充的后置知识点数量
+}
+
+// ChatVars 简化聊天模板变量
+type ChatVars struct{}
+
+// variables 模板名称 -> 变量类型
+// 模板加载时使用变量类型的零值试渲染，引用不存在的字段会在启动或热加载时报错
+var variables = map[string]reflect.Type{
+	NameAnalysis: reflect.TypeOf(AnalysisVars{}),
+	NameDialogue: reflect.TypeOf(DialogueVars{}),
+	NameChat:     reflect.TypeOf(ChatVars{}),
+	NameCheck:    reflect.TypeOf(CheckVars{}),
+	NameRelated:  reflect.TypeOf(RelatedVars{}),
+}
+
+// Names 返回所有模板名称
+func Names() []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewVars 返回模板变量类型的新实例指针，用于从 JSON 解码变量
+func NewVars(name string) (interface{}, error) {
+	varsType, ok := variables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown prompt template %q", name)
+	}
+	return reflect.New(varsType).Interface(), nil
+}
+
+// VarNames 返回模板变量的 JSON 字段名
+func VarNames(name string) []string {
+	varsType, ok := variables[name]
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, varsType.NumField())
+	for i := 0; i < varsType.NumField(); i++ {
+		names = append(names, varsType.Field(i).Tag.Get("json"))
+	}
+	return names
+}
@@ -4,20 +4,20 @@ import "encoding/json"
 
 // Message 消息结构（支持文本和图片）
 type Message struct {
-	Role    string      `json:"role" binding:"required"`
-	Content interface{} `json:"content" binding:"required"` // 可以是 string 或 []ContentPart
+	Role    string      `json:"role" binding:"required,oneof=system user assistant"`
+	Content interface{} `json:"content" binding:"required" openapi:"oneOf=string,max=8000|[]ContentPart,min=1,max=10"` // 可以是 string 或 []ContentPart
 }
 
 // ContentPart 内容部分（用于多模态消息）
 type ContentPart struct {
-	Type     string    `json:"type"`                // "text" 或 "image_url"
-	Text     string    `json:"text,omitempty"`      // 文本内容
-	ImageURL *ImageURL `json:"image_url,omitempty"` // 图片URL
+	Type     string    `json:"type" binding:"required,oneof=text image_url"` // "text" 或 "image_url"
+	Text     string    `json:"text,omitempty" binding:"max=8000"`            // 文本内容
+	ImageURL *ImageURL `json:"image_url,omitempty"`                          // 图片URL
 }
 
 // ImageURL 图片URL结构
 type ImageURL struct {
-	URL string `json:"url"` // 支持 http(s):// 或 data:image/...;base64,...
+	URL string `json:"url" binding:"required"` // 支持 http(s):// 或 data:image/...;base64,...
 }
 
 // NewTextMessage 创建文本消息
@@ -28,23 +28,26 @@ func NewTextMessage(role, content string) Message {
 	}
 }
 
-// NewVisionMessage 创建包含图片的消息
-func NewVisionMessage(role, text, imageURL string) Message {
-	return Message{
-		Role: role,
-		Content: []ContentPart{
-			{
-				Type: "text",
-				Text: text,
-			},
-			{
-				Type: "image_url",
-				ImageURL: &ImageURL{
-					URL: imageURL,
-				},
-			},
+// NewVisionMessage 创建包含图片的消息，多张图片按顺序排在文本之后
+func NewVisionMessage(role, text string, imageURLs ...string) Message {
+	parts := []ContentPart{
+		{
+			Type: "text",
+			Text: text,
 		},
 	}
+	for _, imageURL := range imageURLs {
+		parts = append(parts, ContentPart{
+			Type: "image_url",
+			ImageURL: &ImageURL{
+				URL: imageURL,
+			},
+		})
+	}
+	return Message{
+		Role:    role,
+		Content: parts,
+	}
 }
 
 // MarshalJSON 自定义JSON序列化
@@ -59,13 +62,19 @@ func (m Message) MarshalJSON() ([]byte, error) {
 
 // ChatRequest 聊天请求
 type ChatRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages" binding:"required,min=1"`
-	Temperature float64   `json:"temperature,omitempty"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Model       string    `json:"model" binding:"max=128"`
+	Messages    []Message `json:"messages" binding:"required,min=1,max=50,dive"`
+	Temperature float64   `json:"temperature,omitempty" binding:"omitempty,min=0,max=2"`
+	MaxTokens   int       `json:"max_tokens,omitempty" binding:"omitempty,min=1"`
 	Stream      bool      `json:"stream,omitempty"`
 }
 
+// SimpleChatRequest 简化聊天请求，系统提示词由服务端模板提供
+type SimpleChatRequest struct {
+	Message string `json:"message" binding:"required,max=8000"`
+	Model   string `json:"model,omitempty" binding:"max=128"` // 为空时使用默认模型
+}
+
 // ChatResponse 聊天响应
 type ChatResponse struct {
 	ID      string   `json:"id"`
@@ -92,8 +101,9 @@ type Usage struct {
 
 // Response 统一响应结构
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     *ErrorInfo  `json:"error,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
 }
-
@@ -0,0 +1,24 @@
+package schema
+
+import "ai-note-service/internal/application/errcode"
+
+// ErrorInfo 错误详情（统一响应结构中的 error 字段）
+type ErrorInfo struct {
+	Reason    string               `json:"reason"`           // 稳定的错误标识，如 AI_SERVICE_TIMEOUT
+	Retryable bool                 `json:"retryable"`        // 是否可以重试
+	Fields    []errcode.FieldError `json:"fields,omitempty"` // 字段级校验错误
+}
+
+// ProblemDetails RFC 7807 错误响应（application/problem+json）
+type ProblemDetails struct {
+	Type      string               `json:"type"`
+	Title     string               `json:"title"`
+	Status    int                  `json:"status"`
+	Detail    string               `json:"detail,omitempty"`
+	Instance  string               `json:"instance,omitempty"`
+	Code      int                  `json:"code"`
+	Reason    string               `json:"reason"`
+	Retryable bool                 `json:"retryable"`
+	Errors    []errcode.FieldError `json:"errors,omitempty"`
+	RequestID string               `json:"requestId,omitempty"`
+}
@@ -0,0 +1,50 @@
+package schema
+
+// ExperimentAssignment 结果所属的实验变体
+type ExperimentAssignment struct {
+	Name    string `json:"name"`
+	Variant string `json:"variant"`
+}
+
+// RatingRequest 结果评分请求
+type RatingRequest struct {
+	Score int `json:"score" binding:"required,min=1,max=5"` // 1-5 分
+}
+
+// ExperimentReport 实验报告
+type ExperimentReport struct {
+	Name     string          `json:"name"`
+	Target   string          `json:"target,omitempty"`
+	Enabled  bool            `json:"enabled"` // 已从配置中移除的实验仍会列出历史数据，enabled 为 false
+	Variants []VariantReport `json:"variants"`
+}
+
+// VariantReport 实验变体指标
+type VariantReport struct {
+	Name          string `json:"name"`
+	Weight        int    `json:"weight"`
+	PromptVariant string `json:"promptVariant,omitempty"`
+	Model         string `json:"model,omitempty"`
+
+	Requests         int64   `json:"requests"`
+	CacheHits        int64   `json:"cacheHits"`
+	Successes        int64   `json:"successes"`
+	ParseErrors      int64   `json:"parseErrors"`      // AI 返回内容无法解析或不完整
+	Errors           int64   `json:"errors"`           // 调用失败（超时、上游错误等）
+	SuccessRate      float64 `json:"successRate"`      // successes / (requests - cacheHits)
+	ParseSuccessRate float64 `json:"parseSuccessRate"` // successes / (successes + parseErrors)
+
+	Latency             LatencySummary `json:"latencyMs"` // 不含缓存命中
+	AvgPromptTokens     float64        `json:"avgPromptTokens"`
+	AvgCompletionTokens float64        `json:"avgCompletionTokens"`
+
+	Ratings   int64   `json:"ratings"`
+	AvgRating float64 `json:"avgRating"`
+}
+
+// LatencySummary 延迟统计（毫秒）
+type LatencySummary struct {
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+}
@@ -0,0 +1,44 @@
+package schema
+
+// 反馈原因分类
+const (
+	FeedbackReasonIncorrect     = "incorrect"     // 内容错误
+	FeedbackReasonOffTopic      = "off_topic"     // 偏离主题
+	FeedbackReasonTooHard       = "too_hard"      // 太难
+	FeedbackReasonTooEasy       = "too_easy"      // 太简单
+	FeedbackReasonUnclear       = "unclear"       // 表述不清
+	FeedbackReasonIncomplete    = "incomplete"    // 不完整
+	FeedbackReasonInappropriate = "inappropriate" // 不当内容
+	FeedbackReasonOther         = "other"
+)
+
+// FeedbackRequest 反馈请求
+type FeedbackRequest struct {
+	Rating     string   `json:"rating" binding:"required,oneof=up down"` // up | down
+	Reasons    []string `json:"reasons,omitempty" binding:"omitempty,max=8,dive,oneof=incorrect off_topic too_hard too_easy unclear incomplete inappropriate other"`
+	Comment    string   `json:"comment,omitempty" binding:"max=2000"`
+	KeyPointID string   `json:"keyPointId,omitempty" binding:"max=64"` // 仅分析反馈：指出有问题的知识点
+}
+
+// FeedbackResponse 反馈响应
+type FeedbackResponse struct {
+	ID string `json:"id"`
+}
+
+// FeedbackExportQuery 反馈导出查询参数
+type FeedbackExportQuery struct {
+	Kind   string `form:"kind" binding:"omitempty,oneof=analysis message"`
+	Rating string `form:"rating" binding:"omitempty,oneof=up down"`
+	Since  string `form:"since"` // RFC 3339，如 2025-01-01T00:00:00Z
+}
+
+// FeedbackSummary 按对象类型、提示词版本和实验变体汇总的反馈
+type FeedbackSummary struct {
+	Kind          string                `json:"kind"` // analysis | message
+	PromptVersion string                `json:"promptVersion,omitempty"`
+	Experiment    *ExperimentAssignment `json:"experiment,omitempty"`
+	Total         int64                 `json:"total"`
+	Up            int64                 `json:"up"`
+	Down          int64                 `json:"down"`
+	Reasons       map[string]int64      `json:"reasons"`
+}
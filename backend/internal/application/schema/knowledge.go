@@ -7,6 +7,22 @@ type KnowledgePoint struct {
 	Description string   `json:"description"`
 	Category    string   `json:"category,omitempty"`
 	Confidence  *float64 `json:"confidence,omitempty"`
+	Sources     []Source `json:"sources,omitempty"` // 知识点出现的位置：来源页码，图片页还给出所在区域
+}
+
+// Source 知识点在分析材料中的来源
+type Source struct {
+	Page int          `json:"page"`           // 页码，从 1 开始，与上传顺序一致
+	BBox *BoundingBox `json:"bbox,omitempty"` // 知识点在该页图片中的区域，文字页没有
+	Text string       `json:"text,omitempty"` // 该区域中识别出的原文
+}
+
+// BoundingBox 归一化的矩形区域，以图片左上角为原点，坐标和宽高均为相对图片宽高的比例（0-1）
+type BoundingBox struct {
+	X      float64 `json:"x" binding:"min=0,max=1"`
+	Y      float64 `json:"y" binding:"min=0,max=1"`
+	Width  float64 `json:"width" binding:"min=0,max=1"`
+	Height float64 `json:"height" binding:"min=0,max=1"`
 }
 
 // Position 位置坐标
@@ -37,6 +53,15 @@ type KnowledgeGraph struct {
 	Edges []KnowledgeGraphEdge `json:"edges"`
 }
 
+// Formula 材料中的公式
+type Formula struct {
+	ID          string   `json:"id"`                    // 公式ID，格式为 f-001
+	LaTeX       string   `json:"latex"`                 // LaTeX 表达式，不含 $ 等定界符，已校验可以用 KaTeX 渲染
+	Description string   `json:"description,omitempty"` // 公式的含义或各符号的说明
+	KeyPointIDs []string `json:"keyPointIds,omitempty"` // 相关的重点知识点ID
+	Page        int      `json:"page,omitempty"`        // 公式所在页码
+}
+
 // FunExample 趣味示例
 type FunExample struct {
 	KnowledgePointID string `json:"knowledgePointId"`
@@ -45,35 +70,155 @@ type FunExample struct {
 	ImageURL         string `json:"imageUrl,omitempty"`
 }
 
+// 分析深度预设
+const (
+	DepthQuick    = "quick"    // 快速：少量知识点，适合预览
+	DepthStandard = "standard" // 标准：2-5 个重点知识点，前置、后置知识点各 5 个
+	DepthDeep     = "deep"     // 深入：更多知识点和示例
+)
+
+// AnalysisDepth 分析深度和各部分数量，通过分析接口的查询参数传入
+// 数量为 0 时使用深度预设，深度为空时使用配置的默认值
+type AnalysisDepth struct {
+	Depth          string `form:"depth" binding:"omitempty,oneof=quick standard deep"`
+	KeyPoints      int    `form:"keyPoints" binding:"omitempty,min=1,max=10"`      // 重点知识点数量上限
+	Prerequisites  int    `form:"prerequisites" binding:"omitempty,min=1,max=10"`  // 前置知识点数量
+	Postrequisites int    `form:"postrequisites" binding:"omitempty,min=1,max=10"` // 后置知识点数量
+	FunExamples    int    `form:"funExamples" binding:"omitempty,min=1,max=3"`     // 每个重点知识点的趣味示例数量
+}
+
+// AnalyzeURLRequest 链接分析请求
+type AnalyzeURLRequest struct {
+	URL   string `json:"url" binding:"required,max=2048"`   // http(s) 链接，指向图片、PDF、网页或纯文本
+	Pages string `json:"pages,omitempty" binding:"max=200"` // 页码范围，如 1-3,5，仅对 PDF 生效
+}
+
+// AnalyzeTextRequest 文本分析请求
+type AnalyzeTextRequest struct {
+	Text string `json:"text" binding:"required,max=50000"` // 笔记或 Markdown 文本
+}
+
 // KnowledgeAnalysisResponse 图片分析响应（新版本）
 type KnowledgeAnalysisResponse struct {
-	DetailedExplanation string           `json:"detailedExplanation"` // 完整的对这张图片的知识点详解
-	Prerequisites       []KnowledgePoint `json:"prerequisites"`        // 前置知识点
-	KeyPoints           []KnowledgePoint `json:"keyPoints"`            // 这张图片中的重点知识点
-	FunExamples         []FunExample     `json:"funExamples"`          // 重点知识点对应的趣味示例
-	Postrequisites      []KnowledgePoint `json:"postrequisites"`       // 这张图片对应的后置知识点
-	Conclusion          string           `json:"conclusion"`           // 最后的汇总
+	ID                  string                `json:"id"`                       // 分析结果ID，用于评分和反馈
+	DetailedExplanation string                `json:"detailedExplanation"`      // 完整的对这张图片的知识点详解
+	Prerequisites       []KnowledgePoint      `json:"prerequisites"`            // 前置知识点
+	KeyPoints           []KnowledgePoint      `json:"keyPoints"`                // 这张图片中的重点知识点
+	FunExamples         []FunExample          `json:"funExamples"`              // 重点知识点对应的趣味示例
+	Formulas            []Formula             `json:"formulas,omitempty"`       // 材料中的公式，按出现顺序排列
+	Postrequisites      []KnowledgePoint      `json:"postrequisites"`           // 这张图片对应的后置知识点
+	Conclusion          string                `json:"conclusion"`               // 最后的汇总
+	Pages               int                   `json:"pages,omitempty"`          // 分析的页数（多张图片或 PDF 选中的页面）
+	SkippedPages        []int                 `json:"skippedPages,omitempty"`   // PDF 中没有文字层且无法渲染而未分析的页码
+	Language            string                `json:"language,omitempty"`       // 分析内容的语言，如 zh-CN、en
+	PromptVersion       string                `json:"promptVersion,omitempty"`  // 生成结果使用的提示词模板版本
+	ProfileVersion      string                `json:"profileVersion,omitempty"` // 生成结果使用的学习者画像版本，如 v3、v3+override（请求覆盖了画像）
+	Experiment          *ExperimentAssignment `json:"experiment,omitempty"`     // 参与的 A/B 实验变体
 }
 
 // ConversationMessage 对话消息
 type ConversationMessage struct {
 	ID        string `json:"id"`
-	Sender    string `json:"sender"` // "user" | "ai"
-	Content   string `json:"content"`
+	Sender    string `json:"sender" binding:"required,oneof=user ai"` // "user" | "ai"
+	Content   string `json:"content" binding:"max=8000"`
 	Timestamp string `json:"timestamp"`
 }
 
 // DialogueRequest 对话请求
+// 回复语言由 lang 查询参数或 Accept-Language 请求头决定
 type DialogueRequest struct {
-	Message             string                `json:"message" binding:"required"`
-	ConversationHistory []ConversationMessage `json:"conversationHistory,omitempty"`
-	KnowledgePointTitle string                `json:"knowledgePointTitle,omitempty"` // 知识点标题（前端传递）
-	KnowledgePointDesc  string                `json:"knowledgePointDesc,omitempty"`  // 知识点描述（前端传递）
+	Message             string                `json:"message" binding:"required,max=4000"`
+	ConversationHistory []ConversationMessage `json:"conversationHistory,omitempty" binding:"omitempty,max=100,dive"`
+	KnowledgePointTitle string                `json:"knowledgePointTitle" binding:"required,max=200"`                                                  // 知识点标题（前端传递）
+	KnowledgePointDesc  string                `json:"knowledgePointDesc" binding:"required,max=2000"`                                                  // 知识点描述（前端传递）
+	ConversationID      string                `json:"conversationId,omitempty" binding:"omitempty,max=64"`                                             // 会话ID，首轮为空时由服务端生成，后续轮次原样传回
+	AnalysisID          string                `json:"analysisId,omitempty" binding:"omitempty,max=64"`                                                 // 知识点所属的分析结果ID，附带原图时必填，后续轮次可省略
+	ImageContext        string                `json:"imageContext,omitempty" binding:"omitempty,oneof=none page crop"`                                 // 附带原图：none 不附带（默认），page 知识点所在页，crop 知识点区域截图（没有区域时附带整页）；首轮选定后沿用到整个会话，后续轮次可省略
+	Strategy            string                `json:"strategy,omitempty" binding:"omitempty,oneof=direct socratic worked_example check_understanding"` // 辅导策略，首轮选定后沿用到整个会话，后续轮次可省略；传入不同的策略时重新开始
+}
+
+// 对话附带原图的方式
+const (
+	ImageContextNone = "none"
+	ImageContextPage = "page"
+	ImageContextCrop = "crop"
+)
+
+// 对话辅导策略
+const (
+	StrategyDirect             = "direct"              // 直接讲解（默认）
+	StrategySocratic           = "socratic"            // 苏格拉底式提问：不直接给出最终答案，用引导性问题让学生自己得出结论
+	StrategyWorkedExample      = "worked_example"      // 例题示范：先完整演示一道例题，再让学生做一道类似的题
+	StrategyCheckUnderstanding = "check_understanding" // 检查理解：逐个提问检查学生是否真正理解
+)
+
+// 辅导进度状态
+const (
+	TutoringInProgress  = "in_progress"  // 进行中
+	TutoringStruggling  = "struggling"   // 学生最近一轮没有进展
+	TutoringGoalReached = "goal_reached" // 学生已达成本次辅导的目标
+)
+
+// TutoringProgress 辅导进度，直接讲解策略不跟踪进度
+type TutoringProgress struct {
+	State      string `json:"state"`      // in_progress、struggling 或 goal_reached
+	Turns      int    `json:"turns"`      // 本会话中 AI 的回复轮数（含本轮）
+	StuckTurns int    `json:"stuckTurns"` // 连续没有进展的轮数，达到一定轮数后提示会更具体
 }
 
 // DialogueResponse 对话响应
 type DialogueResponse struct {
-	Message   string `json:"message"`
-	Timestamp string `json:"timestamp"`
+	ConversationID string                `json:"conversationId"` // 会话ID
+	MessageID      string                `json:"messageId"`      // 回复消息ID，用于评分和反馈
+	Message        string                `json:"message"`
+	Timestamp      string                `json:"timestamp"`
+	Language       string                `json:"language,omitempty"`       // 回复语言
+	PromptVersion  string                `json:"promptVersion,omitempty"`  // 生成回复使用的提示词模板版本
+	ProfileVersion string                `json:"profileVersion,omitempty"` // 生成回复使用的学习者画像版本
+	Experiment     *ExperimentAssignment `json:"experiment,omitempty"`     // 参与的 A/B 实验变体
+	ImageContext   string                `json:"imageContext,omitempty"`   // 实际附带的原图（page 或 crop），原图已过期或不可用时为空
+	Strategy       string                `json:"strategy"`                 // 本会话使用的辅导策略
+	Tutoring       *TutoringProgress     `json:"tutoring,omitempty"`       // 辅导进度，直接讲解时为空
 }
 
+// 作业批改中的错误类型
+const (
+	MistakeConceptual = "conceptual" // 概念或方法错误
+	MistakeArithmetic = "arithmetic" // 计算错误
+	MistakeNotation   = "notation"   // 书写或符号错误
+)
+
+// AnalysisModeCheck 分析接口的批改模式
+const AnalysisModeCheck = "check"
+
+// HomeworkCheckResponse 手写作业批改结果（mode=check）
+type HomeworkCheckResponse struct {
+	ID             string         `json:"id"`                                  // 批改结果ID
+	Mode           string         `json:"mode" binding:"required,oneof=check"` // 固定为 check，用于与知识点分析结果区分
+	Problem        string         `json:"problem,omitempty"`                   // 识别出的题目
+	Steps          []SolutionStep `json:"steps" binding:"required,min=1"`      // 学生解答的逐步转写
+	Correct        bool           `json:"correct"`                             // 所有步骤都正确
+	FirstErrorStep int            `json:"firstErrorStep,omitempty"`            // 第一个错误步骤的序号（从 1 开始），全部正确时省略
+	Mistake        *Mistake       `json:"mistake,omitempty"`                   // 第一个错误的分析，全部正确时省略
+	Correction     string         `json:"correction,omitempty"`                // 从第一个错误步骤开始的正确解法
+	Summary        string         `json:"summary,omitempty"`                   // 整体评价和建议
+	Pages          int            `json:"pages,omitempty"`                     // 批改的页数
+	Language       string         `json:"language,omitempty"`                  // 批改内容的语言
+	PromptVersion  string         `json:"promptVersion,omitempty"`             // 生成结果使用的提示词模板版本
+}
+
+// SolutionStep 解答中的一步
+type SolutionStep struct {
+	Index   int    `json:"index"`             // 步骤序号，从 1 开始
+	Content string `json:"content"`           // 学生写下的内容，公式以 $...$ 包裹的 LaTeX 表示
+	Correct bool   `json:"correct"`           // 该步骤是否正确
+	Comment string `json:"comment,omitempty"` // 批注
+	Page    int    `json:"page,omitempty"`    // 步骤所在页码，多页时给出
+}
+
+// Mistake 解答中第一个错误的分析
+type Mistake struct {
+	Type         string          `json:"type" binding:"required,oneof=conceptual arithmetic notation"` // 错误类型：conceptual 概念或方法，arithmetic 计算，notation 书写或符号
+	Description  string          `json:"description"`                                                  // 错在哪里、为什么错
+	Prerequisite *KnowledgePoint `json:"prerequisite,omitempty"`                                       // 需要复习的前置知识点
+}
@@ -0,0 +1,44 @@
+package schema
+
+import "time"
+
+// 学习者年级
+const (
+	GradePrimary    = "primary"    // 小学
+	GradeMiddle     = "middle"     // 初中
+	GradeHigh       = "high"       // 高中
+	GradeUniversity = "university" // 大学
+	GradeAdult      = "adult"      // 成人自学、职业学习
+)
+
+// 讲解方式偏好
+const (
+	StyleConcise  = "concise"  // 简洁：直接给出要点
+	StyleDetailed = "detailed" // 详细：完整推导和解释
+	StyleExamples = "examples" // 多举例：用生活中的例子和类比讲解
+)
+
+// LearnerProfileRequest 学习者画像，整体替换已保存的画像
+type LearnerProfileRequest struct {
+	GradeLevel string   `json:"gradeLevel,omitempty" binding:"omitempty,oneof=primary middle high university adult"` // 年级
+	Strengths  []string `json:"strengths,omitempty" binding:"omitempty,max=10,dive,min=1,max=50"`                    // 擅长的科目，如 数学、物理
+	Language   string   `json:"language,omitempty" binding:"omitempty,oneof=zh-CN en"`                               // 偏好的输出语言，请求未指定 lang 时优先于 Accept-Language
+	Style      string   `json:"style,omitempty" binding:"omitempty,oneof=concise detailed examples"`                 // 偏好的讲解方式
+}
+
+// LearnerProfile 按用户保存的学习者画像，用于调整分析和对话的难度与讲解方式
+type LearnerProfile struct {
+	GradeLevel string    `json:"gradeLevel,omitempty"`
+	Strengths  []string  `json:"strengths,omitempty"`
+	Language   string    `json:"language,omitempty"`
+	Style      string    `json:"style,omitempty"`
+	Version    int       `json:"version"` // 每次保存加 1，记录在分析结果和对话回复的 profileVersion 中
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// LearnerOverride 单次请求覆盖画像中的字段，通过分析和对话接口的查询参数传入，为空的字段不覆盖
+type LearnerOverride struct {
+	GradeLevel string `form:"gradeLevel" binding:"omitempty,oneof=primary middle high university adult"`
+	Strengths  string `form:"strengths" binding:"max=200"` // 逗号分隔的科目
+	Style      string `form:"style" binding:"omitempty,oneof=concise detailed examples"`
+}
@@ -0,0 +1,30 @@
+package schema
+
+import "encoding/json"
+
+// PromptTemplateInfo 提示词模板信息
+type PromptTemplateInfo struct {
+	Name        string   `json:"name"`
+	Variant     string   `json:"variant,omitempty"`  // A/B 实验变体，为空表示默认版本
+	Language    string   `json:"language,omitempty"` // 为空表示与语言无关
+	Version     string   `json:"version"`
+	VersionID   string   `json:"versionId"` // 保存在结果中并参与缓存键计算的版本标识
+	Description string   `json:"description,omitempty"`
+	Sections    []string `json:"sections"`  // 模板定义的片段，如 system、user
+	Variables   []string `json:"variables"` // 模板可用的变量
+	Source      string   `json:"source"`    // embedded 或模板目录
+}
+
+// PromptPreviewRequest 提示词预览请求
+type PromptPreviewRequest struct {
+	Variant   string          `json:"variant"`   // 为空时使用默认版本
+	Language  string          `json:"language"`  // 为空时使用请求协商出的语言
+	Variables json.RawMessage `json:"variables"` // 模板变量，字段见模板信息中的 variables
+}
+
+// PromptPreviewResponse 提示词预览响应
+type PromptPreviewResponse struct {
+	VersionID string `json:"versionId"`
+	System    string `json:"system"`
+	User      string `json:"user,omitempty"`
+}
@@ -1,87 +1,169 @@
 package service
 
 import (
+	"ai-note-service/internal/application/errcode"
 	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/logger"
 	"ai-note-service/internal/application/schema"
+	"ai-note-service/internal/application/telemetry"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// maxLoggedBodyLength 日志中保留的上游响应体最大长度
+const maxLoggedBodyLength = 512
+
 // AIService AI服务接口
 type AIService struct {
-	client  *http.Client
-	baseURL string
-	apiKey  string
-	model   string
+	client *http.Client
+	config func() global.AIConfig
 }
 
-// NewAIService 创建AI服务实例
-func NewAIService() *AIService {
-	cfg := global.Config.AI
+// NewAIService 创建AI服务实例，client 为空时使用默认 HTTP 客户端，测试中可传入录制/回放传输层
+// 配置在每次请求时从 config 读取，热加载后的地址、密钥、模型和超时对后续请求立即生效
+func NewAIService(config global.Provider, client *http.Client) *AIService {
+	if client == nil {
+		client = &http.Client{}
+	}
 	return &AIService{
-		client: &http.Client{
-			Timeout: time.Duration(cfg.Timeout) * time.Second,
+		client: client,
+		config: func() global.AIConfig {
+			return config().AI
 		},
-		baseURL: cfg.BaseURL,
-		apiKey:  cfg.APIKey,
-		model:   cfg.DefaultModel,
 	}
 }
 
 // Chat 调用聊天接口
-func (s *AIService) Chat(req *schema.ChatRequest) (*schema.ChatResponse, error) {
+func (s *AIService) Chat(ctx context.Context, req *schema.ChatRequest) (resp *schema.ChatResponse, err error) {
+	cfg := s.config()
+
 	// 如果请求中没有指定模型，使用默认模型
 	if req.Model == "" {
-		req.Model = s.model
+		req.Model = cfg.DefaultModel
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "AIService.Chat",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.request.model", req.Model),
+			attribute.Int("gen_ai.request.message_count", len(req.Messages)),
+		),
+	)
+	defer func() {
+		telemetry.RecordError(span, err)
+		span.End()
+	}()
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+		defer cancel()
 	}
 
 	// 构建请求URL
-	url := fmt.Sprintf("%s/chat/completions", s.baseURL)
+	url := fmt.Sprintf("%s/chat/completions", cfg.BaseURL)
 
 	// 序列化请求体
 	body, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("marshal request failed: %w", err)
+		return nil, errcode.Wrap(errcode.InternalError, fmt.Errorf("marshal request failed: %w", err), "")
 	}
 
 	// 创建HTTP请求
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
-		return nil, fmt.Errorf("create request failed: %w", err)
+		return nil, errcode.Wrap(errcode.InternalError, fmt.Errorf("create request failed: %w", err), "")
 	}
 
 	// 设置请求头
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
+
+	// 透传 W3C trace-context 到上游模型服务
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
 
 	// 发送请求
-	resp, err := s.client.Do(httpReq)
+	httpResp, err := s.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("send request failed: %w", err)
+		return nil, classifyTransportError(ctx, fmt.Errorf("send request failed: %w", err))
 	}
-	defer resp.Body.Close()
+	defer httpResp.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.response.status_code", httpResp.StatusCode))
 
 	// 读取响应体
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(httpResp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response failed: %w", err)
+		return nil, classifyTransportError(ctx, fmt.Errorf("read response failed: %w", err))
 	}
 
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	// 检查HTTP状态码（响应体只在 debug 日志中保留脱敏后的片段，不写入错误）
+	if httpResp.StatusCode != http.StatusOK {
+		slog.DebugContext(ctx, "upstream returned non-200 status",
+			"status", httpResp.StatusCode,
+			"body", logger.Truncate(string(respBody), maxLoggedBodyLength),
+		)
+		return nil, classifyStatusError(httpResp.StatusCode)
 	}
 
 	// 解析响应
 	var chatResp schema.ChatResponse
 	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return nil, fmt.Errorf("unmarshal response failed: %w, body: %s", err, string(respBody))
+		slog.DebugContext(ctx, "upstream response is not valid JSON",
+			"body", logger.Truncate(string(respBody), maxLoggedBodyLength),
+		)
+		return nil, errcode.Wrap(errcode.AIResponseInvalid, fmt.Errorf("unmarshal response failed: %w", err), "")
 	}
 
+	span.SetAttributes(
+		attribute.String("gen_ai.response.model", chatResp.Model),
+		attribute.Int("gen_ai.usage.input_tokens", chatResp.Usage.PromptTokens),
+		attribute.Int("gen_ai.usage.output_tokens", chatResp.Usage.CompletionTokens),
+		attribute.Int("gen_ai.usage.total_tokens", chatResp.Usage.TotalTokens),
+	)
+
 	return &chatResp, nil
 }
 
+// classifyTransportError 将网络层错误归类为超时、取消或上游不可用
+func classifyTransportError(ctx context.Context, err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return errcode.Wrap(errcode.AIServiceTimeout, err, "")
+	case errors.Is(ctx.Err(), context.Canceled):
+		return errcode.Wrap(errcode.AIServiceCancelled, err, "")
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errcode.Wrap(errcode.AIServiceTimeout, err, "")
+	default:
+		return errcode.Wrap(errcode.AIServiceError, err, "")
+	}
+}
+
+// classifyStatusError 将上游非 200 状态码归类：限流/过载可重试，其他 4xx 为请求被拒绝
+func classifyStatusError(status int) error {
+	cause := fmt.Errorf("API returned non-200 status: %d", status)
+	code := errcode.AIServiceError
+	switch {
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		code = errcode.AIServiceBusy
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		code = errcode.AIServiceTimeout
+	case status >= 400 && status < 500:
+		code = errcode.AIServiceRejected
+	}
+	return errcode.WrapLocalized(code, cause, "ai.upstream_status", status)
+}
@@ -1,14 +1,19 @@
 package service
 
 import (
+	"ai-note-service/internal/application/aitest"
+	"ai-note-service/internal/application/errcode"
 	"ai-note-service/internal/application/global"
 	"ai-note-service/internal/application/schema"
+	"context"
+	"net/http"
 	"testing"
+	"time"
 )
 
 func TestNewAIService(t *testing.T) {
 	// 初始化配置
-	global.Config = &global.AppConfig{
+	appConfig := &global.AppConfig{
 		AI: global.AIConfig{
 			BaseURL:      "http://test.example.com",
 			APIKey:       "test-key",
@@ -17,21 +22,33 @@ func TestNewAIService(t *testing.T) {
 		},
 	}
 
-	service := NewAIService()
+	service := NewAIService(global.Static(appConfig), nil)
 	if service == nil {
 		t.Fatal("NewAIService returned nil")
 	}
 
-	if service.baseURL != global.Config.AI.BaseURL {
-		t.Errorf("Expected baseURL %s, got %s", global.Config.AI.BaseURL, service.baseURL)
+	cfg := service.config()
+	if cfg.BaseURL != appConfig.AI.BaseURL {
+		t.Errorf("Expected baseURL %s, got %s", appConfig.AI.BaseURL, cfg.BaseURL)
 	}
 
-	if service.apiKey != global.Config.AI.APIKey {
-		t.Errorf("Expected apiKey %s, got %s", global.Config.AI.APIKey, service.apiKey)
+	if cfg.APIKey != appConfig.AI.APIKey {
+		t.Errorf("Expected apiKey %s, got %s", appConfig.AI.APIKey, cfg.APIKey)
 	}
 
-	if service.model != global.Config.AI.DefaultModel {
-		t.Errorf("Expected model %s, got %s", global.Config.AI.DefaultModel, service.model)
+	if cfg.DefaultModel != appConfig.AI.DefaultModel {
+		t.Errorf("Expected model %s, got %s", appConfig.AI.DefaultModel, cfg.DefaultModel)
+	}
+}
+
+func TestAIServiceReadsReloadedConfig(t *testing.T) {
+	current := &global.AppConfig{AI: global.AIConfig{DefaultModel: "model-a", Timeout: 30}}
+	service := NewAIService(func() *global.AppConfig { return current }, nil)
+
+	current = &global.AppConfig{AI: global.AIConfig{DefaultModel: "model-b", Timeout: 10}}
+
+	if got := service.config().DefaultModel; got != "model-b" {
+		t.Errorf("Expected reloaded model model-b, got %s", got)
 	}
 }
 
@@ -60,3 +77,93 @@ func TestChatRequest(t *testing.T) {
 	}
 }
 
+func useFakeAI(t *testing.T, timeout int) (*aitest.Server, global.Provider) {
+	t.Helper()
+	fake := aitest.NewServer()
+	t.Cleanup(fake.Close)
+	return fake, global.Static(&global.AppConfig{AI: global.AIConfig{BaseURL: fake.URL, APIKey: "test-key", DefaultModel: "test-model", Timeout: timeout}})
+}
+
+func TestChatAgainstFakeServer(t *testing.T) {
+	fake, config := useFakeAI(t, 30)
+	fake.Enqueue(aitest.Reply{Content: "hello", Usage: &schema.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}})
+
+	resp, err := NewAIService(config, nil).Chat(context.Background(), &schema.ChatRequest{
+		Messages: []schema.Message{schema.NewTextMessage("user", "hi")},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "hello" || resp.Usage.TotalTokens != 4 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if got := fake.LastRequest().Model; got != "test-model" {
+		t.Errorf("request model = %q, want default model", got)
+	}
+}
+
+func TestChatClassifiesUpstreamFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply aitest.Reply
+		want  *errcode.ErrCode
+	}{
+		{"rate limited", aitest.Reply{Status: http.StatusTooManyRequests}, errcode.AIServiceBusy},
+		{"bad request", aitest.Reply{Status: http.StatusBadRequest}, errcode.AIServiceRejected},
+		{"server error", aitest.Reply{Status: http.StatusInternalServerError}, errcode.AIServiceError},
+		{"gateway timeout", aitest.Reply{Status: http.StatusGatewayTimeout}, errcode.AIServiceTimeout},
+		{"malformed JSON", aitest.Reply{Malformed: true}, errcode.AIResponseInvalid},
+	}
+	fake, config := useFakeAI(t, 30)
+	service := NewAIService(config, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.Enqueue(tt.reply)
+			_, err := service.Chat(context.Background(), &schema.ChatRequest{
+				Messages: []schema.Message{schema.NewTextMessage("user", "hi")},
+			})
+			if got := errcode.From(err).Code; got != tt.want {
+				t.Errorf("error code = %s, want %s (err: %v)", got.Reason, tt.want.Reason, err)
+			}
+		})
+	}
+}
+
+func TestChatTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the 1s AI timeout")
+	}
+	fake, config := useFakeAI(t, 1)
+	fake.Enqueue(aitest.Reply{Content: "too late", Latency: 5 * time.Second})
+
+	_, err := NewAIService(config, nil).Chat(context.Background(), &schema.ChatRequest{
+		Messages: []schema.Message{schema.NewTextMessage("user", "hi")},
+	})
+	if got := errcode.From(err).Code; got != errcode.AIServiceTimeout {
+		t.Errorf("error code = %s, want AI_SERVICE_TIMEOUT (err: %v)", got.Reason, err)
+	}
+}
+
+func TestChatReplaysRecordedResponse(t *testing.T) {
+	dir := t.TempDir()
+	fake, config := useFakeAI(t, 30)
+	fake.Enqueue(aitest.Reply{Content: "recorded"})
+	req := func() *schema.ChatRequest {
+		return &schema.ChatRequest{Messages: []schema.Message{schema.NewTextMessage("user", "hi")}}
+	}
+
+	recorder := NewAIService(config, &http.Client{Transport: aitest.NewRecorder(dir, nil)})
+	if _, err := recorder.Chat(context.Background(), req()); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	fake.Close()
+
+	replayer := NewAIService(config, &http.Client{Transport: aitest.NewReplayer(dir)})
+	resp, err := replayer.Chat(context.Background(), req())
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "recorded" {
+		t.Errorf("replayed content = %v", resp.Choices[0].Message.Content)
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"ai-note-service/internal/application/schema"
+	"container/list"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AnalysisCache 图片分析结果缓存（LRU + 过期时间）
+// 缓存键包含提示词模板版本，模板更新后旧结果自然失效
+type AnalysisCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+	now     func() time.Time
+}
+
+// analysisCacheEntry 缓存条目
+type analysisCacheEntry struct {
+	key       string
+	value     *schema.KnowledgeAnalysisResponse
+	expiresAt time.Time
+}
+
+// NewAnalysisCache 创建分析结果缓存，size 为最大条目数，ttl 为 0 表示不过期
+func NewAnalysisCache(size int, ttl time.Duration) *AnalysisCache {
+	return &AnalysisCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get 读取缓存，返回结果的深拷贝
+func (c *AnalysisCache) Get(key string) (*schema.KnowledgeAnalysisResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*analysisCacheEntry)
+	if !entry.expiresAt.IsZero() && c.now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+
+	return cloneAnalysis(entry.value), true
+}
+
+// Put 写入结果的深拷贝，超过容量时淘汰最久未使用的条目
+func (c *AnalysisCache) Put(key string, value *schema.KnowledgeAnalysisResponse) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+	stored := cloneAnalysis(value)
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &analysisCacheEntry{key: key, value: stored, expiresAt: expiresAt}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&analysisCacheEntry{key: key, value: stored, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*analysisCacheEntry).key)
+	}
+}
+
+// cloneAnalysis 深拷贝分析结果，调用方修改返回值或写入后的原值都不会影响缓存
+func cloneAnalysis(value *schema.KnowledgeAnalysisResponse) *schema.KnowledgeAnalysisResponse {
+	copied := *value
+	copied.Prerequisites = cloneKnowledgePoints(value.Prerequisites)
+	copied.KeyPoints = cloneKnowledgePoints(value.KeyPoints)
+	copied.Postrequisites = cloneKnowledgePoints(value.Postrequisites)
+	copied.FunExamples = slices.Clone(value.FunExamples)
+	copied.Formulas = slices.Clone(value.Formulas)
+	for i := range copied.Formulas {
+		copied.Formulas[i].KeyPointIDs = slices.Clone(copied.Formulas[i].KeyPointIDs)
+	}
+	copied.SkippedPages = slices.Clone(value.SkippedPages)
+	if value.Experiment != nil {
+		experiment := *value.Experiment
+		copied.Experiment = &experiment
+	}
+	return &copied
+}
+
+// cloneKnowledgePoints 深拷贝知识点，包括置信度和来源区域
+func cloneKnowledgePoints(points []schema.KnowledgePoint) []schema.KnowledgePoint {
+	points = slices.Clone(points)
+	for i := range points {
+		if points[i].Confidence != nil {
+			confidence := *points[i].Confidence
+			points[i].Confidence = &confidence
+		}
+		points[i].Sources = slices.Clone(points[i].Sources)
+		for j := range points[i].Sources {
+			if bbox := points[i].Sources[j].BBox; bbox != nil {
+				copied := *bbox
+				points[i].Sources[j].BBox = &copied
+			}
+		}
+	}
+	return points
+}
+
+// Len 返回缓存条目数
+func (c *AnalysisCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
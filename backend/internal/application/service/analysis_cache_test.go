@@ -0,0 +1,89 @@
+package service
+
+import (
+	"ai-note-service/internal/application/schema"
+	"testing"
+	"time"
+)
+
+func TestAnalysisCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewAnalysisCache(2, 0)
+	cache.Put("a", &schema.KnowledgeAnalysisResponse{Conclusion: "a"})
+	cache.Put("b", &schema.KnowledgeAnalysisResponse{Conclusion: "b"})
+
+	// 访问 a 后，b 成为最久未使用的条目
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	cache.Put("c", &schema.KnowledgeAnalysisResponse{Conclusion: "c"})
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if got, ok := cache.Get("c"); !ok || got.Conclusion != "c" {
+		t.Errorf("Get(c) = %+v, %v", got, ok)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+}
+
+func TestAnalysisCacheExpires(t *testing.T) {
+	now := time.Now()
+	cache := NewAnalysisCache(10, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Put("a", &schema.KnowledgeAnalysisResponse{Conclusion: "a"})
+	now = now.Add(2 * time.Minute)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Error("expected entry to expire")
+	}
+}
+
+func TestAnalysisCacheReturnsCopy(t *testing.T) {
+	cache := NewAnalysisCache(10, 0)
+	cache.Put("a", &schema.KnowledgeAnalysisResponse{Conclusion: "a"})
+
+	got, _ := cache.Get("a")
+	got.Conclusion = "changed"
+
+	if again, _ := cache.Get("a"); again.Conclusion != "a" {
+		t.Errorf("cached value was modified: %q", again.Conclusion)
+	}
+}
+
+func TestAnalysisCacheCopiesNestedValues(t *testing.T) {
+	cache := NewAnalysisCache(10, 0)
+	value := &schema.KnowledgeAnalysisResponse{
+		KeyPoints: []schema.KnowledgePoint{{
+			ID:      "kp-1",
+			Title:   "勾股定理",
+			Sources: []schema.Source{{Page: 1, BBox: &schema.BoundingBox{X: 0.1, Width: 0.5}}},
+		}},
+		FunExamples:  []schema.FunExample{{KnowledgePointID: "kp-1", Title: "梯子"}},
+		Formulas:     []schema.Formula{{ID: "f-001", LaTeX: "a^2+b^2=c^2", KeyPointIDs: []string{"kp-1"}}},
+		SkippedPages: []int{3},
+	}
+	cache.Put("a", value)
+
+	// 写入后修改原值不影响缓存
+	value.KeyPoints[0].Title = "changed"
+	value.KeyPoints[0].Sources[0].BBox.X = 0.9
+
+	got, _ := cache.Get("a")
+	got.KeyPoints[0].Sources[0].Page = 2
+	got.KeyPoints[0].Sources[0].BBox.Width = 1
+	got.FunExamples[0].Title = "changed"
+	got.Formulas[0].KeyPointIDs[0] = "kp-2"
+	got.SkippedPages[0] = 4
+
+	again, _ := cache.Get("a")
+	point := again.KeyPoints[0]
+	if point.Title != "勾股定理" || point.Sources[0].Page != 1 || *point.Sources[0].BBox != (schema.BoundingBox{X: 0.1, Width: 0.5}) {
+		t.Errorf("cached key point was modified: %+v %+v", point, point.Sources[0].BBox)
+	}
+	if again.FunExamples[0].Title != "梯子" || again.Formulas[0].KeyPointIDs[0] != "kp-1" || again.SkippedPages[0] != 3 {
+		t.Errorf("cached slices were modified: %+v %+v %v", again.FunExamples, again.Formulas, again.SkippedPages)
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/logger"
+	"ai-note-service/internal/application/prompt"
+	"ai-note-service/internal/application/schema"
+	"ai-note-service/internal/application/telemetry"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// analysisCounts 一次分析要求的各部分数量
+type analysisCounts struct {
+	MinKeyPoints   int // 重点知识点数量下限
+	KeyPoints      int // 重点知识点数量上限
+	Prerequisites  int
+	Postrequisites int
+	FunExamples    int // 每个重点知识点的趣味示例数量
+}
+
+// depthPresets 各分析深度的默认数量
+var depthPresets = map[string]analysisCounts{
+	schema.DepthQuick:    {MinKeyPoints: 1, KeyPoints: 3, Prerequisites: 3, Postrequisites: 3, FunExamples: 1},
+	schema.DepthStandard: {MinKeyPoints: 2, KeyPoints: 5, Prerequisites: 5, Postrequisites: 5, FunExamples: 1},
+	schema.DepthDeep:     {MinKeyPoints: 3, KeyPoints: 8, Prerequisites: 8, Postrequisites: 8, FunExamples: 2},
+}
+
+// resolveCounts 确定分析数量，优先级：请求中的数量 > 请求的深度预设 > 配置中的数量 > 配置的深度预设（默认 standard）
+func resolveCounts(config global.AnalysisConfig, depth schema.AnalysisDepth) analysisCounts {
+	name := config.Depth
+	if depth.Depth != "" {
+		name = depth.Depth
+	}
+	counts, ok := depthPresets[name]
+	if !ok {
+		counts = depthPresets[schema.DepthStandard]
+	}
+
+	// 请求指定了深度时，该深度的预设优先于配置中的数量
+	if depth.Depth == "" {
+		counts.KeyPoints = pick(counts.KeyPoints, config.KeyPoints)
+		counts.Prerequisites = pick(counts.Prerequisites, config.Prerequisites)
+		counts.Postrequisites = pick(counts.Postrequisites, config.Postrequisites)
+		counts.FunExamples = pick(counts.FunExamples, config.FunExamples)
+	}
+	counts.KeyPoints = pick(counts.KeyPoints, depth.KeyPoints)
+	counts.Prerequisites = pick(counts.Prerequisites, depth.Prerequisites)
+	counts.Postrequisites = pick(counts.Postrequisites, depth.Postrequisites)
+	counts.FunExamples = pick(counts.FunExamples, depth.FunExamples)
+	counts.MinKeyPoints = min(counts.MinKeyPoints, counts.KeyPoints)
+	return counts
+}
+
+// pick 返回 override，为 0 时返回 value
+func pick(value, override int) int {
+	if override > 0 {
+		return override
+	}
+	return value
+}
+
+// cacheKey 数量参与缓存键，同一材料不同数量的结果分别缓存
+func (c analysisCounts) cacheKey() string {
+	return fmt.Sprintf("counts:%d-%d/%d/%d/%d", c.MinKeyPoints, c.KeyPoints, c.Prerequisites, c.Postrequisites, c.FunExamples)
+}
+
+// limitCounts 将结果裁剪到要求的数量：多出的重点知识点连同其趣味示例和公式关联一起去掉，
+// 每个重点知识点最多保留 FunExamples 个趣味示例，前置、后置知识点最多保留要求的数量
+func limitCounts(result *schema.KnowledgeAnalysisResponse, counts analysisCounts) {
+	kept := make(map[string]bool)
+	if len(result.KeyPoints) > counts.KeyPoints {
+		result.KeyPoints = result.KeyPoints[:counts.KeyPoints]
+	}
+	for _, kp := range result.KeyPoints {
+		kept[kp.ID] = true
+	}
+
+	examples := make(map[string]int)
+	funExamples := result.FunExamples[:0]
+	for _, ex := range result.FunExamples {
+		if !kept[ex.KnowledgePointID] || examples[ex.KnowledgePointID] == counts.FunExamples {
+			continue
+		}
+		examples[ex.KnowledgePointID]++
+		funExamples = append(funExamples, ex)
+	}
+	result.FunExamples = funExamples
+
+	for i := range result.Formulas {
+		f := &result.Formulas[i]
+		ids := f.KeyPointIDs[:0]
+		for _, id := range f.KeyPointIDs {
+			if kept[id] {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			ids = nil
+		}
+		f.KeyPointIDs = ids
+	}
+
+	if len(result.Prerequisites) > counts.Prerequisites {
+		result.Prerequisites = result.Prerequisites[:counts.Prerequisites]
+	}
+	if len(result.Postrequisites) > counts.Postrequisites {
+		result.Postrequisites = result.Postrequisites[:counts.Postrequisites]
+	}
+}
+
+// shortOf 结果的前置或后置知识点仍少于要求的数量（补充失败），这样的结果不缓存，相同的请求下次重新分析和补充
+func (c analysisCounts) shortOf(result *schema.KnowledgeAnalysisResponse) bool {
+	return len(result.Prerequisites) < c.Prerequisites || len(result.Postrequisites) < c.Postrequisites
+}
+
+// pointTitles 将知识点标题拼为每行一个的列表，用于补充请求的提示词
+func pointTitles(lists ...[]schema.KnowledgePoint) string {
+	var lines []string
+	for _, points := range lists {
+		for _, kp := range points {
+			lines = append(lines, "- "+kp.Title)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// topUpRelated 前置或后置知识点少于要求的数量时请求模型补充一次，补充的知识点去重后追加在已有的之后
+// 补充失败时保留已有的知识点，只有仍然没有前置或后置知识点时才返回错误；返回补充请求的 token 用量
+func (s *ImageAnalysisService) topUpRelated(ctx context.Context, model string, lang i18n.Language, result *schema.KnowledgeAnalysisResponse, counts analysisCounts) (schema.Usage, error) {
+	vars := prompt.RelatedVars{
+		Prerequisites:  max(counts.Prerequisites-len(result.Prerequisites), 0),
+		Postrequisites: max(counts.Postrequisites-len(result.Postrequisites), 0),
+	}
+	if vars.Prerequisites == 0 && vars.Postrequisites == 0 {
+		return schema.Usage{}, nil
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "ImageAnalysisService.topUpRelated")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("analysis.missing_prerequisites", vars.Prerequisites),
+		attribute.Int("analysis.missing_postrequisites", vars.Postrequisites),
+	)
+
+	vars.KeyPoints = pointTitles(result.KeyPoints)
+	vars.Existing = pointTitles(result.Prerequisites, result.Postrequisites)
+	added, usage, err := s.requestRelated(ctx, model, lang, vars)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		slog.WarnContext(ctx, "related knowledge top-up failed, keeping partial result",
+			"prerequisites", len(result.Prerequisites), "postrequisites", len(result.Postrequisites), "error", err)
+	} else {
+		keyIndex := make(map[string]int, len(result.KeyPoints))
+		for i, kp := range result.KeyPoints {
+			keyIndex[pointKey(kp.Title)] = i
+		}
+		result.Prerequisites = mergeRelated([][]schema.KnowledgePoint{result.Prerequisites, added.Prerequisites}, keyIndex, counts.Prerequisites, "kp-p%03d")
+		result.Postrequisites = mergeRelated([][]schema.KnowledgePoint{result.Postrequisites, added.Postrequisites}, keyIndex, counts.Postrequisites, "kp-n%03d")
+	}
+
+	if len(result.Prerequisites) == 0 {
+		return usage, errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.prerequisite_count", counts.Prerequisites, 0)
+	}
+	if len(result.Postrequisites) == 0 {
+		return usage, errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.postrequisite_count", counts.Postrequisites, 0)
+	}
+	return usage, nil
+}
+
+// relatedResponse 补充请求的响应
+type relatedResponse struct {
+	Prerequisites  []schema.KnowledgePoint `json:"prerequisites"`
+	Postrequisites []schema.KnowledgePoint `json:"postrequisites"`
+}
+
+// requestRelated 请求模型补充前置、后置知识点
+func (s *ImageAnalysisService) requestRelated(ctx context.Context, model string, lang i18n.Language, vars prompt.RelatedVars) (*relatedResponse, schema.Usage, error) {
+	p, err := s.prompts().Render(prompt.NameRelated, lang, vars)
+	if err != nil {
+		return nil, schema.Usage{}, errcode.Wrap(errcode.InternalError, err, "")
+	}
+	chatResp, err := s.aiService.Chat(ctx, &schema.ChatRequest{
+		Model: model,
+		Messages: []schema.Message{
+			schema.NewTextMessage("system", p.System),
+			schema.NewTextMessage("user", p.User),
+		},
+	})
+	if err != nil {
+		return nil, schema.Usage{}, fmt.Errorf("AI补充知识点失败: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, chatResp.Usage, errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.empty_response")
+	}
+	content, ok := chatResp.Choices[0].Message.Content.(string)
+	if !ok {
+		return nil, chatResp.Usage, errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.invalid_content")
+	}
+
+	var added relatedResponse
+	if err := json.Unmarshal([]byte(s.extractJSON(content)), &added); err != nil {
+		slog.DebugContext(ctx, "AI top-up response is not valid JSON",
+			logger.UserContent("ai_response", logger.Truncate(content, maxLoggedBodyLength)),
+		)
+		return nil, chatResp.Usage, errcode.WrapLocalized(errcode.AIResponseInvalid, err, "ai.invalid_json")
+	}
+	return &added, chatResp.Usage, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/schema"
+	"reflect"
+	"testing"
+)
+
+func TestResolveCounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		config global.AnalysisConfig
+		depth  schema.AnalysisDepth
+		want   analysisCounts
+	}{
+		{"default", global.AnalysisConfig{}, schema.AnalysisDepth{}, depthPresets[schema.DepthStandard]},
+		{"config depth", global.AnalysisConfig{Depth: schema.DepthDeep}, schema.AnalysisDepth{}, depthPresets[schema.DepthDeep]},
+		{
+			"config counts",
+			global.AnalysisConfig{Prerequisites: 7, FunExamples: 3},
+			schema.AnalysisDepth{},
+			analysisCounts{MinKeyPoints: 2, KeyPoints: 5, Prerequisites: 7, Postrequisites: 5, FunExamples: 3},
+		},
+		{
+			// 请求的深度预设优先于配置中的数量
+			"request depth",
+			global.AnalysisConfig{Prerequisites: 7},
+			schema.AnalysisDepth{Depth: schema.DepthQuick},
+			depthPresets[schema.DepthQuick],
+		},
+		{
+			"request counts",
+			global.AnalysisConfig{Depth: schema.DepthDeep, Postrequisites: 4},
+			schema.AnalysisDepth{KeyPoints: 1, Prerequisites: 2},
+			analysisCounts{MinKeyPoints: 1, KeyPoints: 1, Prerequisites: 2, Postrequisites: 4, FunExamples: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveCounts(tt.config, tt.depth); got != tt.want {
+				t.Errorf("counts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitCounts(t *testing.T) {
+	result := &schema.KnowledgeAnalysisResponse{
+		KeyPoints: []schema.KnowledgePoint{{ID: "kp-001", Title: "勾股定理"}, {ID: "kp-002", Title: "余弦定理"}, {ID: "kp-003", Title: "正弦定理"}},
+		FunExamples: []schema.FunExample{
+			{KnowledgePointID: "kp-001", Title: "梯子"},
+			{KnowledgePointID: "kp-001", Title: "第二个示例"},
+			{KnowledgePointID: "kp-003", Title: "被裁掉的知识点"},
+		},
+		Formulas: []schema.Formula{
+			{ID: "f-001", LaTeX: "a^2 + b^2 = c^2", KeyPointIDs: []string{"kp-001", "kp-003"}},
+			{ID: "f-002", LaTeX: `\frac{a}{\sin A} = 2R`, KeyPointIDs: []string{"kp-003"}},
+		},
+		Prerequisites:  related("平方", "三角形", "平方根", "角", "余弦", "正弦"),
+		Postrequisites: related("三角函数", "向量"),
+	}
+	limitCounts(result, analysisCounts{KeyPoints: 2, Prerequisites: 5, Postrequisites: 5, FunExamples: 1})
+
+	if got := len(result.KeyPoints); got != 2 {
+		t.Errorf("key points = %d, want 2", got)
+	}
+	if got, want := len(result.FunExamples), 1; got != want || result.FunExamples[0].Title != "梯子" {
+		t.Errorf("fun examples = %+v", result.FunExamples)
+	}
+	if got := result.Formulas[0].KeyPointIDs; !reflect.DeepEqual(got, []string{"kp-001"}) {
+		t.Errorf("formula key points = %v", got)
+	}
+	if got := result.Formulas[1].KeyPointIDs; got != nil {
+		t.Errorf("formula of dropped key point = %v, want nil", got)
+	}
+	if got := len(result.Prerequisites); got != 5 {
+		t.Errorf("prerequisites = %d, want 5", got)
+	}
+	if got := len(result.Postrequisites); got != 2 {
+		t.Errorf("postrequisites = %d, want 2", got)
+	}
+}
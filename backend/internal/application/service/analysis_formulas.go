@@ -0,0 +1,115 @@
+package service
+
+import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/latex"
+	"ai-note-service/internal/application/logger"
+	"ai-note-service/internal/application/schema"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxFormulas 一份分析结果最多保留的公式数
+const maxFormulas = 50
+
+// normalizeFormulas 校验一批结果中的公式并按 f-001 起重新编号
+// 去掉定界符后无法通过 LaTeX 校验的公式被丢弃（前端无法渲染）；关联的重点知识点和页码只保留本批次内存在的，
+// 批次只有一页时没有页码的公式即来自该页；写法相同（忽略空白）的公式只保留第一个
+func normalizeFormulas(ctx context.Context, formulas []schema.Formula, keyPoints []schema.KnowledgePoint, pages []document.Page) []schema.Formula {
+	known := make(map[string]bool, len(keyPoints))
+	for _, kp := range keyPoints {
+		known[kp.ID] = true
+	}
+	inBatch := make(map[int]bool, len(pages))
+	for _, page := range pages {
+		inBatch[page.Number] = true
+	}
+
+	var out []schema.Formula
+	seen := make(map[string]bool)
+	invalid := 0
+	for _, f := range formulas {
+		f.LaTeX = latex.Normalize(f.LaTeX)
+		if err := latex.Validate(f.LaTeX); err != nil {
+			invalid++
+			slog.DebugContext(ctx, "invalid formula dropped", "error", err, logger.UserContent("latex", f.LaTeX))
+			continue
+		}
+		key := formulaKey(f.LaTeX)
+		if seen[key] || len(out) == maxFormulas {
+			continue
+		}
+		seen[key] = true
+
+		f.Description = strings.TrimSpace(f.Description)
+		var ids []string
+		for _, id := range f.KeyPointIDs {
+			if known[id] {
+				ids = appendUnique(ids, id)
+			}
+		}
+		f.KeyPointIDs = ids
+		if !inBatch[f.Page] {
+			f.Page = 0
+		}
+		if f.Page == 0 && len(pages) == 1 {
+			f.Page = pages[0].Number
+		}
+		f.ID = fmt.Sprintf("f-%03d", len(out)+1)
+		out = append(out, f)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("analysis.formulas", len(out)),
+		attribute.Int("analysis.invalid_formulas", invalid),
+	)
+	return out
+}
+
+// mergeFormulas 按批次顺序合并公式，关联的知识点ID换成合并后的ID；重复的公式合并关联的知识点，保留第一次出现的页码
+func mergeFormulas(results []*schema.KnowledgeAnalysisResponse, idMap []map[string]string) []schema.Formula {
+	var out []schema.Formula
+	index := make(map[string]int) // formulaKey -> out 下标
+	for i, r := range results {
+		for _, f := range r.Formulas {
+			var ids []string
+			for _, id := range f.KeyPointIDs {
+				if merged, ok := idMap[i][id]; ok {
+					ids = appendUnique(ids, merged)
+				}
+			}
+			key := formulaKey(f.LaTeX)
+			if j, ok := index[key]; ok {
+				for _, id := range ids {
+					out[j].KeyPointIDs = appendUnique(out[j].KeyPointIDs, id)
+				}
+				continue
+			}
+			if len(out) == maxFormulas {
+				continue
+			}
+			index[key] = len(out)
+			f.ID = fmt.Sprintf("f-%03d", len(out)+1)
+			f.KeyPointIDs = ids
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// formulaKey 公式去重使用的形式：忽略空白
+func formulaKey(expr string) string {
+	return strings.Join(strings.Fields(expr), "")
+}
+
+func appendUnique(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"ai-note-service/internal/application/schema"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeFormulas(t *testing.T) {
+	keyPoints := []schema.KnowledgePoint{{ID: "kp-001"}, {ID: "kp-002"}}
+	formulas := normalizeFormulas(context.Background(), []schema.Formula{
+		{LaTeX: "$a^2 + b^2 = c^2$", KeyPointIDs: []string{"kp-001", "kp-404", "kp-001"}, Page: 3},
+		{LaTeX: `\frac{a}{b`, KeyPointIDs: []string{"kp-001"}},
+		{LaTeX: "a^2+b^2=c^2", KeyPointIDs: []string{"kp-002"}},
+		{LaTeX: `\cos C = \frac{a^2 + b^2 - c^2}{2ab}`, Description: " 余弦定理 ", KeyPointIDs: []string{"kp-002"}, Page: 9},
+	}, keyPoints, imagePages(3, 4))
+
+	want := []schema.Formula{
+		{ID: "f-001", LaTeX: "a^2 + b^2 = c^2", KeyPointIDs: []string{"kp-001"}, Page: 3},
+		{ID: "f-002", LaTeX: `\cos C = \frac{a^2 + b^2 - c^2}{2ab}`, Description: "余弦定理", KeyPointIDs: []string{"kp-002"}},
+	}
+	if !reflect.DeepEqual(formulas, want) {
+		t.Errorf("formulas = %+v, want %+v", formulas, want)
+	}
+
+	// 只有一页时没有页码的公式来自该页
+	formulas = normalizeFormulas(context.Background(), []schema.Formula{{LaTeX: "F = ma"}}, nil, imagePages(2))
+	if len(formulas) != 1 || formulas[0].Page != 2 || formulas[0].KeyPointIDs != nil {
+		t.Errorf("single page formulas = %+v", formulas)
+	}
+}
+
+func TestMergeFormulas(t *testing.T) {
+	merged := mergeAnalyses([]*schema.KnowledgeAnalysisResponse{
+		{
+			KeyPoints: []schema.KnowledgePoint{{ID: "kp-001", Title: "勾股定理"}},
+			Formulas:  []schema.Formula{{ID: "f-001", LaTeX: "a^2 + b^2 = c^2", KeyPointIDs: []string{"kp-001"}, Page: 1}},
+		},
+		{
+			KeyPoints: []schema.KnowledgePoint{{ID: "kp-001", Title: "余弦定理"}, {ID: "kp-002", Title: "勾股定理"}},
+			Formulas: []schema.Formula{
+				{ID: "f-001", LaTeX: `c^2 = a^2 + b^2 - 2ab\cos C`, KeyPointIDs: []string{"kp-001"}, Page: 3},
+				{ID: "f-002", LaTeX: "a^2+b^2=c^2", KeyPointIDs: []string{"kp-001", "kp-002"}, Page: 4},
+			},
+		},
+	}, depthPresets[schema.DepthStandard])
+
+	want := []schema.Formula{
+		{ID: "f-001", LaTeX: "a^2 + b^2 = c^2", KeyPointIDs: []string{"kp-001", "kp-002"}, Page: 1},
+		{ID: "f-002", LaTeX: `c^2 = a^2 + b^2 - 2ab\cos C`, KeyPointIDs: []string{"kp-002"}, Page: 3},
+	}
+	if !reflect.DeepEqual(merged.Formulas, want) {
+		t.Errorf("formulas = %+v, want %+v", merged.Formulas, want)
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/schema"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// mergeAnalyses 合并分批分析的结果，批次按页码顺序排列
+// 同名重点知识点合并为一个并汇总来源页码，ID 重新编号；每个重点知识点最多保留 counts.FunExamples 个趣味示例；
+// 前置、后置知识点去重后最多保留要求的数量，前置知识点优先取前面的页，后置知识点优先取后面的页，
+// 已作为重点知识点出现的不再重复列出；重复的公式只保留一个
+func mergeAnalyses(results []*schema.KnowledgeAnalysisResponse, counts analysisCounts) *schema.KnowledgeAnalysisResponse {
+	if len(results) == 1 {
+		return results[0]
+	}
+
+	merged := &schema.KnowledgeAnalysisResponse{}
+	var explanations, conclusions []string
+	keyIndex := make(map[string]int)                 // 标题 -> merged.KeyPoints 下标
+	idMap := make([]map[string]string, len(results)) // 批次内 ID -> 合并后 ID
+	for i, r := range results {
+		explanations = appendNonEmpty(explanations, r.DetailedExplanation)
+		conclusions = appendNonEmpty(conclusions, r.Conclusion)
+
+		idMap[i] = make(map[string]string)
+		for _, kp := range r.KeyPoints {
+			batchID := kp.ID
+			key := pointKey(kp.Title)
+			j, ok := keyIndex[key]
+			if !ok {
+				j = len(merged.KeyPoints)
+				keyIndex[key] = j
+				kp.ID = fmt.Sprintf("kp-%03d", j+1)
+				kp.Sources = append([]schema.Source(nil), kp.Sources...)
+				merged.KeyPoints = append(merged.KeyPoints, kp)
+			} else {
+				existing := &merged.KeyPoints[j]
+				existing.Sources = mergeSources(existing.Sources, kp.Sources)
+				if kp.Confidence != nil && (existing.Confidence == nil || *kp.Confidence > *existing.Confidence) {
+					existing.Confidence = kp.Confidence
+				}
+			}
+			idMap[i][batchID] = merged.KeyPoints[j].ID
+		}
+	}
+	merged.DetailedExplanation = strings.Join(explanations, "\n\n")
+	merged.Conclusion = strings.Join(conclusions, "\n\n")
+
+	// 每个重点知识点按批次顺序保留前 counts.FunExamples 个趣味示例
+	examples := make(map[string]int)
+	for i, r := range results {
+		for _, ex := range r.FunExamples {
+			id, ok := idMap[i][ex.KnowledgePointID]
+			if !ok || examples[id] == counts.FunExamples {
+				continue
+			}
+			examples[id]++
+			ex.KnowledgePointID = id
+			merged.FunExamples = append(merged.FunExamples, ex)
+		}
+	}
+
+	merged.Formulas = mergeFormulas(results, idMap)
+
+	prerequisites := make([][]schema.KnowledgePoint, len(results))
+	postrequisites := make([][]schema.KnowledgePoint, len(results))
+	for i, r := range results {
+		prerequisites[i] = r.Prerequisites
+		postrequisites[len(results)-1-i] = r.Postrequisites
+	}
+	merged.Prerequisites = mergeRelated(prerequisites, keyIndex, counts.Prerequisites, "kp-p%03d")
+	merged.Postrequisites = mergeRelated(postrequisites, keyIndex, counts.Postrequisites, "kp-n%03d")
+
+	return merged
+}
+
+// mergeRelated 按批次顺序去重合并前置或后置知识点，跳过与重点知识点同名的，最多保留 limit 个并按 idFormat 重新编号
+func mergeRelated(batches [][]schema.KnowledgePoint, keyPoints map[string]int, limit int, idFormat string) []schema.KnowledgePoint {
+	seen := make(map[string]bool)
+	var out []schema.KnowledgePoint
+	for _, points := range batches {
+		for _, kp := range points {
+			key := pointKey(kp.Title)
+			if _, isKeyPoint := keyPoints[key]; isKeyPoint || seen[key] {
+				continue
+			}
+			if len(out) == limit {
+				return out
+			}
+			seen[key] = true
+			kp.ID = fmt.Sprintf(idFormat, len(out)+1)
+			out = append(out, kp)
+		}
+	}
+	return out
+}
+
+const (
+	// boxTolerance 区域超出图片边界不超过该比例时截断到边界内，超出更多时视为无效
+	boxTolerance = 0.02
+	// minBoxSize 区域宽高的下限，更小的区域无法截取出可辨认的内容
+	minBoxSize = 0.005
+	// maxSourceTextRunes 来源原文的长度上限
+	maxSourceTextRunes = 500
+)
+
+// normalizeSources 只保留批次内的来源页码并去重排序；批次只有一页时没有来源的知识点即来自该页
+// 区域只对图片页有效，文字页的区域和超出图片范围的区域被丢弃，来源本身保留
+func normalizeSources(points []schema.KnowledgePoint, pages []document.Page) {
+	isImage := make(map[int]bool, len(pages))
+	for _, page := range pages {
+		isImage[page.Number] = page.Text == ""
+	}
+	for i := range points {
+		var sources []schema.Source
+		for _, src := range points[i].Sources {
+			image, ok := isImage[src.Page]
+			if !ok {
+				continue
+			}
+			if image {
+				src.BBox = normalizeBox(src.BBox)
+			} else {
+				src.BBox = nil
+			}
+			if text := []rune(strings.TrimSpace(src.Text)); len(text) > maxSourceTextRunes {
+				src.Text = string(text[:maxSourceTextRunes])
+			} else {
+				src.Text = string(text)
+			}
+			sources = append(sources, src)
+		}
+		if len(sources) == 0 && len(pages) == 1 {
+			sources = []schema.Source{{Page: pages[0].Number}}
+		}
+		points[i].Sources = mergeSources(nil, sources)
+	}
+}
+
+// normalizeBox 校验区域在图片范围内并截断微小的越界，无效时返回 nil
+func normalizeBox(box *schema.BoundingBox) *schema.BoundingBox {
+	if box == nil || box.Width <= 0 || box.Height <= 0 {
+		return nil
+	}
+	x0, y0 := box.X, box.Y
+	x1, y1 := box.X+box.Width, box.Y+box.Height
+	if x0 < -boxTolerance || y0 < -boxTolerance || x1 > 1+boxTolerance || y1 > 1+boxTolerance {
+		return nil
+	}
+	x0, y0 = max(x0, 0), max(y0, 0)
+	x1, y1 = min(x1, 1), min(y1, 1)
+	if x1-x0 < minBoxSize || y1-y0 < minBoxSize {
+		return nil
+	}
+	return &schema.BoundingBox{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
+
+// mergeSources 合并来源并去重，按页码排序；同一页有区域时不再保留该页没有区域的来源
+func mergeSources(a, b []schema.Source) []schema.Source {
+	type sourceKey struct {
+		page int
+		box  schema.BoundingBox
+		text string
+	}
+	seen := make(map[sourceKey]bool)
+	boxed := make(map[int]bool)
+	var all []schema.Source
+	for _, src := range append(append([]schema.Source(nil), a...), b...) {
+		key := sourceKey{page: src.Page, text: src.Text}
+		if src.BBox != nil {
+			key.box = *src.BBox
+			boxed[src.Page] = true
+		}
+		if !seen[key] {
+			seen[key] = true
+			all = append(all, src)
+		}
+	}
+
+	var out []schema.Source
+	pageOnly := make(map[int]bool)
+	for _, src := range all {
+		if src.BBox == nil {
+			if boxed[src.Page] || pageOnly[src.Page] {
+				continue
+			}
+			pageOnly[src.Page] = true
+		}
+		out = append(out, src)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Page < out[j].Page })
+	return out
+}
+
+// pointKey 知识点去重使用的标题形式：忽略大小写和多余空白
+func pointKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+func appendNonEmpty(list []string, s string) []string {
+	if s = strings.TrimSpace(s); s != "" {
+		list = append(list, s)
+	}
+	return list
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/schema"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func titles(points []schema.KnowledgePoint) []string {
+	out := make([]string, len(points))
+	for i, kp := range points {
+		out[i] = kp.ID + ":" + kp.Title
+	}
+	return out
+}
+
+func related(titles ...string) []schema.KnowledgePoint {
+	out := make([]schema.KnowledgePoint, len(titles))
+	for i, title := range titles {
+		out[i] = schema.KnowledgePoint{ID: "x", Title: title}
+	}
+	return out
+}
+
+func TestMergeAnalyses(t *testing.T) {
+	high, low := 0.9, 0.5
+	merged := mergeAnalyses([]*schema.KnowledgeAnalysisResponse{
+		{
+			DetailedExplanation: "第一部分",
+			KeyPoints: []schema.KnowledgePoint{
+				{ID: "kp-001", Title: "勾股定理", Confidence: &low, Sources: []schema.Source{{Page: 2}}},
+				{ID: "kp-002", Title: "直角三角形", Sources: []schema.Source{{Page: 1}}},
+			},
+			FunExamples: []schema.FunExample{
+				{KnowledgePointID: "kp-001", Title: "梯子"},
+				{KnowledgePointID: "kp-404", Title: "无对应知识点"},
+			},
+			Prerequisites:  related("平方", "三角形", "余弦定理"),
+			Postrequisites: related("三角函数", "向量"),
+			Conclusion:     "总结一",
+		},
+		{
+			DetailedExplanation: "第二部分",
+			KeyPoints: []schema.KnowledgePoint{
+				{ID: "kp-001", Title: "余弦定理", Sources: []schema.Source{{Page: 3}}},
+				{ID: "kp-002", Title: " 勾股定理", Confidence: &high, Sources: []schema.Source{{Page: 4}, {Page: 2}}},
+			},
+			FunExamples: []schema.FunExample{
+				{KnowledgePointID: "kp-001", Title: "测量河宽"},
+				{KnowledgePointID: "kp-002", Title: "重复的示例"},
+			},
+			Prerequisites:  related("平方根", "平方"),
+			Postrequisites: related("解三角形", "向量"),
+		},
+	}, analysisCounts{KeyPoints: 5, Prerequisites: 3, Postrequisites: 2, FunExamples: 1})
+
+	if got, want := titles(merged.KeyPoints), []string{"kp-001:勾股定理", "kp-002:直角三角形", "kp-003:余弦定理"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("key points = %v, want %v", got, want)
+	}
+	if got := merged.KeyPoints[0].Sources; !reflect.DeepEqual(got, []schema.Source{{Page: 2}, {Page: 4}}) {
+		t.Errorf("merged sources = %v", got)
+	}
+	if *merged.KeyPoints[0].Confidence != high {
+		t.Errorf("confidence = %v, want the higher one", *merged.KeyPoints[0].Confidence)
+	}
+
+	// 示例改为引用合并后的 ID，每个知识点只保留第一个
+	var examples []string
+	for _, ex := range merged.FunExamples {
+		examples = append(examples, ex.KnowledgePointID+":"+ex.Title)
+	}
+	if want := []string{"kp-001:梯子", "kp-003:测量河宽"}; !reflect.DeepEqual(examples, want) {
+		t.Errorf("fun examples = %v, want %v", examples, want)
+	}
+
+	// 前置知识点前面的页优先，后置知识点后面的页优先，最多保留要求的数量；已是重点知识点的余弦定理不再列为前置
+	if got, want := titles(merged.Prerequisites), []string{"kp-p001:平方", "kp-p002:三角形", "kp-p003:平方根"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("prerequisites = %v, want %v", got, want)
+	}
+	if got, want := titles(merged.Postrequisites), []string{"kp-n001:解三角形", "kp-n002:向量"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("postrequisites = %v, want %v", got, want)
+	}
+	if merged.DetailedExplanation != "第一部分\n\n第二部分" || merged.Conclusion != "总结一" {
+		t.Errorf("explanation/conclusion = %q / %q", merged.DetailedExplanation, merged.Conclusion)
+	}
+}
+
+func imagePages(numbers ...int) []document.Page {
+	pages := make([]document.Page, len(numbers))
+	for i, n := range numbers {
+		pages[i] = document.Page{Number: n, Image: []byte("image")}
+	}
+	return pages
+}
+
+func TestNormalizeSources(t *testing.T) {
+	points := []schema.KnowledgePoint{
+		{Title: "批次外的页码被丢弃", Sources: []schema.Source{{Page: 9}, {Page: 6}, {Page: 5}, {Page: 7}, {Page: 6}}},
+		{Title: "没有页码"},
+	}
+	normalizeSources(points, imagePages(5, 6, 8))
+	if got := points[0].Sources; !reflect.DeepEqual(got, []schema.Source{{Page: 5}, {Page: 6}}) {
+		t.Errorf("sources = %v", got)
+	}
+	if points[1].Sources != nil {
+		t.Errorf("sources without pages = %v, want nil", points[1].Sources)
+	}
+
+	normalizeSources(points[1:], imagePages(3))
+	if got := points[1].Sources; !reflect.DeepEqual(got, []schema.Source{{Page: 3}}) {
+		t.Errorf("single page batch sources = %v", got)
+	}
+}
+
+func TestNormalizeSourceBoxes(t *testing.T) {
+	box := func(x, y, w, h float64) *schema.BoundingBox {
+		return &schema.BoundingBox{X: x, Y: y, Width: w, Height: h}
+	}
+	pages := append(imagePages(1), document.Page{Number: 2, Text: "extracted text"})
+	points := []schema.KnowledgePoint{{Sources: []schema.Source{
+		{Page: 1, BBox: box(0.1, 0.2, 0.3, 0.4), Text: "  a² + b² = c²  "},
+		{Page: 1, BBox: box(0.1, 0.2, 0.3, 0.4), Text: "a² + b² = c²"}, // 重复
+		{Page: 1, BBox: box(0.5, 0.9, 0.5, 0.11)},                      // 略微越界，截断到边界内
+		{Page: 1, BBox: box(0.5, 0.5, 0.8, 0.2)},                       // 超出图片范围，只保留页码
+		{Page: 1, BBox: box(0.2, 0.2, 0, 0.1)},                         // 空区域
+		{Page: 2, BBox: box(0.1, 0.1, 0.2, 0.2), Text: "proof"},        // 文字页没有区域
+	}}}
+	normalizeSources(points, pages)
+
+	want := []schema.Source{
+		{Page: 1, BBox: box(0.1, 0.2, 0.3, 0.4), Text: "a² + b² = c²"},
+		{Page: 1, BBox: box(0.5, 0.9, 0.5, 0.1)},
+		{Page: 2, Text: "proof"},
+	}
+	got := points[0].Sources
+	if len(got) != len(want) {
+		t.Fatalf("sources = %+v", got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Page != w.Page || g.Text != w.Text || (g.BBox == nil) != (w.BBox == nil) {
+			t.Errorf("source %d = %+v, want %+v", i, g, w)
+			continue
+		}
+		if w.BBox != nil && !nearBox(*g.BBox, *w.BBox) {
+			t.Errorf("source %d bbox = %+v, want %+v", i, *g.BBox, *w.BBox)
+		}
+	}
+}
+
+func nearBox(a, b schema.BoundingBox) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return near(a.X, b.X) && near(a.Y, b.Y) && near(a.Width, b.Width) && near(a.Height, b.Height)
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/experiment"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/fetch"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/prompt"
+	"ai-note-service/internal/application/tutor"
+)
+
+// Deps 业务服务的共享依赖，由应用容器构造后注入
+type Deps struct {
+	Config  global.Provider
+	AI      *AIService
+	Prompts func() *prompt.Registry // 当前生效的模板注册表，热加载时替换
+	Tracker *experiment.Tracker
+	Results *feedback.Results
+
+	Renderer document.Renderer // PDF 页面渲染器，为 nil 时跳过没有文字层的页面
+	Fetcher  *fetch.Fetcher    // 链接分析的内容抓取器
+	Pages    *PageStore        // 最近分析的页面图片，图片分析写入，截图和对话读取
+	Tutoring *tutor.Sessions   // 对话会话的辅导策略和进度
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/identity"
+	"ai-note-service/internal/application/logger"
+	"ai-note-service/internal/application/prompt"
+	"ai-note-service/internal/application/schema"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mistakeTypes 批改结果中允许的错误类型
+var mistakeTypes = []string{schema.MistakeConceptual, schema.MistakeArithmetic, schema.MistakeNotation}
+
+// CheckHomework 批改材料中的手写作业：逐步转写解答，找出第一个错误步骤并分类，给出需要复习的前置知识点
+// 同一份作业的所有页面在一次模型请求中批改，不分批
+func (s *ImageAnalysisService) CheckHomework(ctx context.Context, src document.Source, lang i18n.Language) (*schema.HomeworkCheckResponse, error) {
+	extraction, err := s.load(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	pages := extraction.Pages
+
+	p, err := s.prompts().Render(prompt.NameCheck, lang, prompt.CheckVars{Pages: len(pages)})
+	if err != nil {
+		return nil, errcode.Wrap(errcode.InternalError, err, "")
+	}
+	var user schema.Message
+	if len(pages) > 1 || pages[0].Text != "" {
+		user = s.pagesMessage(ctx, p.User, pages, lang)
+	} else {
+		user = schema.NewVisionMessage("user", p.User, s.encodeImage(ctx, pages[0].Image))
+	}
+	chatResp, err := s.aiService.Chat(ctx, &schema.ChatRequest{
+		Messages: []schema.Message{schema.NewTextMessage("system", p.System), user},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AI批改失败: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.empty_response")
+	}
+	content, ok := chatResp.Choices[0].Message.Content.(string)
+	if !ok {
+		return nil, errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.invalid_content")
+	}
+
+	var result schema.HomeworkCheckResponse
+	if err := json.Unmarshal([]byte(s.extractJSON(content)), &result); err != nil {
+		slog.DebugContext(ctx, "AI check response is not valid JSON",
+			logger.UserContent("ai_response", logger.Truncate(content, maxLoggedBodyLength)),
+		)
+		return nil, errcode.WrapLocalized(errcode.AIResponseInvalid, err, "ai.invalid_json")
+	}
+	if err := normalizeCheck(&result, pages); err != nil {
+		return nil, err
+	}
+
+	result.ID = identity.NewID()
+	result.Mode = schema.AnalysisModeCheck
+	result.Language = string(lang)
+	result.PromptVersion = p.VersionID
+	if len(pages) > 1 {
+		result.Pages = len(pages)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("check.steps", len(result.Steps)),
+		attribute.Int("check.first_error_step", result.FirstErrorStep),
+	)
+	if result.Mistake != nil {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("check.mistake_type", result.Mistake.Type))
+	}
+	return &result, nil
+}
+
+// normalizeCheck 校验并整理批改结果：去掉空步骤后重新编号，以第一个错误步骤为准统一 correct、firstErrorStep 和 mistake
+// firstErrorStep 取模型给出的步骤和第一个标记为错误的步骤中靠前的一个；有错误时必须给出合法的错误类型
+func normalizeCheck(result *schema.HomeworkCheckResponse, pages []document.Page) error {
+	inBatch := make(map[int]bool, len(pages))
+	for _, page := range pages {
+		inBatch[page.Number] = true
+	}
+
+	firstError := 0
+	var steps []schema.SolutionStep
+	for _, step := range result.Steps {
+		step.Content = strings.TrimSpace(step.Content)
+		if step.Content == "" {
+			continue
+		}
+		if result.FirstErrorStep > 0 && step.Index == result.FirstErrorStep && firstError == 0 {
+			firstError = len(steps) + 1
+		}
+		step.Index = len(steps) + 1
+		step.Comment = strings.TrimSpace(step.Comment)
+		if !inBatch[step.Page] {
+			step.Page = 0
+		}
+		if step.Page == 0 && len(pages) == 1 {
+			step.Page = pages[0].Number
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.no_steps")
+	}
+	if marked := slices.IndexFunc(steps, func(step schema.SolutionStep) bool { return !step.Correct }) + 1; marked > 0 && (firstError == 0 || marked < firstError) {
+		firstError = marked
+	}
+	result.Steps = steps
+	result.Problem = strings.TrimSpace(result.Problem)
+	result.Correct = firstError == 0
+	result.FirstErrorStep = firstError
+
+	if result.Correct {
+		result.Mistake = nil
+		return nil
+	}
+	result.Steps[firstError-1].Correct = false
+	if result.Mistake == nil {
+		return errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.missing_mistake")
+	}
+	result.Mistake.Type = strings.ToLower(strings.TrimSpace(result.Mistake.Type))
+	if !slices.Contains(mistakeTypes, result.Mistake.Type) {
+		return errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.invalid_mistake_type", result.Mistake.Type)
+	}
+	if kp := result.Mistake.Prerequisite; kp != nil {
+		if kp.Title = strings.TrimSpace(kp.Title); kp.Title == "" {
+			result.Mistake.Prerequisite = nil
+		} else {
+			kp.ID, kp.Sources = "kp-p001", nil
+		}
+	}
+	return nil
+}
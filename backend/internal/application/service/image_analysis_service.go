@@ -1,182 +1,574 @@
 package service
 
 import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/experiment"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/fetch"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/identity"
+	"ai-note-service/internal/application/logger"
+	"ai-note-service/internal/application/profile"
+	"ai-note-service/internal/application/prompt"
 	"ai-note-service/internal/application/schema"
+	"ai-note-service/internal/application/telemetry"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"mime/multipart"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ImageAnalysisService 图片分析服务
 type ImageAnalysisService struct {
+	config    global.Provider
 	aiService *AIService
+	prompts   func() *prompt.Registry
+	cache     *AnalysisCache // 未开启缓存时为 nil
+	tracker   *experiment.Tracker
+	results   *feedback.Results
+	renderer  document.Renderer // 渲染 PDF 中没有文字层的页面
+	fetcher   *fetch.Fetcher    // 链接分析时抓取内容
+	pages     *PageStore        // 最近分析的页面图片，用于截取知识点区域
 }
 
 // NewImageAnalysisService 创建图片分析服务实例
-func NewImageAnalysisService() *ImageAnalysisService {
-	return &ImageAnalysisService{
-		aiService: NewAIService(),
+// 缓存按创建时的配置初始化，修改缓存配置需要重启
+func NewImageAnalysisService(deps Deps) *ImageAnalysisService {
+	s := &ImageAnalysisService{
+		config:    deps.Config,
+		aiService: deps.AI,
+		prompts:   deps.Prompts,
+		tracker:   deps.Tracker,
+		results:   deps.Results,
+		renderer:  deps.Renderer,
+		fetcher:   deps.Fetcher,
+		pages:     deps.Pages,
+	}
+	if cfg := deps.Config().Cache; cfg.Enabled {
+		s.cache = NewAnalysisCache(cfg.Size, time.Duration(cfg.TTL)*time.Second)
+	}
+	return s
+}
+
+// 多页分析的默认限制，对应 analysis.max_images 和 analysis.images_per_request 未配置时
+const (
+	defaultMaxImages        = 8
+	defaultImagesPerRequest = 4
+	defaultMaxPDFPages      = 20
+)
+
+// MaxImageSizeMB 单张图片的大小上限，上传和链接抓取的图片都按此限制
+const MaxImageSizeMB = 10
+
+// MaxImages 单次分析最多接受的图片数
+func (s *ImageAnalysisService) MaxImages() int {
+	if n := s.config().Analysis.MaxImages; n > 0 {
+		return n
 	}
+	return defaultMaxImages
+}
+
+// MaxPDFPages 单次 PDF 分析最多选中的页数
+func (s *ImageAnalysisService) MaxPDFPages() int {
+	if n := s.config().PDF.MaxPages; n > 0 {
+		return n
+	}
+	return defaultMaxPDFPages
+}
+
+// imagesPerRequest 每次模型请求最多包含的页数
+func (s *ImageAnalysisService) imagesPerRequest() int {
+	if n := s.config().Analysis.ImagesPerRequest; n > 0 {
+		return n
+	}
+	return defaultImagesPerRequest
+}
+
+// AnalyzeImageData 分析已读取的单张图片，供离线评估等不经过 HTTP 上传的场景使用，数量使用配置的默认值
+func (s *ImageAnalysisService) AnalyzeImageData(ctx context.Context, imageData []byte, lang i18n.Language) (*schema.KnowledgeAnalysisResponse, error) {
+	return s.Analyze(ctx, document.ImageSource{imageData}, schema.AnalysisDepth{}, lang)
+}
+
+// AnalyzePDF 分析 PDF 中 pageRanges 选中的页面（如 "1-3,5"，为空时全部页面）
+// 有文字层的页面发送文字，其余页面渲染为图片发送；没有文字层又无法渲染的页面被跳过
+func (s *ImageAnalysisService) AnalyzePDF(ctx context.Context, data []byte, pageRanges string, depth schema.AnalysisDepth, lang i18n.Language) (*schema.KnowledgeAnalysisResponse, error) {
+	return s.Analyze(ctx, s.pdfSource(data, pageRanges), depth, lang)
+}
+
+// AnalyzeText 分析粘贴的笔记或 Markdown 文本，过长时按段落分页
+func (s *ImageAnalysisService) AnalyzeText(ctx context.Context, text string, depth schema.AnalysisDepth, lang i18n.Language) (*schema.KnowledgeAnalysisResponse, error) {
+	return s.Analyze(ctx, document.TextSource(text), depth, lang)
+}
+
+// urlContentTypes 链接分析支持的内容类型
+var urlContentTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp",
+	"application/pdf", "text/html", "text/plain", "text/markdown",
 }
 
-// AnalyzeImage 分析图片并提取知识点
-func (s *ImageAnalysisService) AnalyzeImage(file *multipart.FileHeader) (*schema.KnowledgeAnalysisResponse, error) {
-	// 1. 读取图片文件
-	imageData, err := s.readImageFile(file)
+// AnalyzeURL 抓取链接指向的图片、PDF、网页或纯文本并分析，pageRanges 仅对 PDF 生效
+func (s *ImageAnalysisService) AnalyzeURL(ctx context.Context, rawURL, pageRanges string, depth schema.AnalysisDepth, lang i18n.Language) (*schema.KnowledgeAnalysisResponse, error) {
+	resource, err := s.fetcher.Fetch(ctx, rawURL, urlContentTypes)
 	if err != nil {
-		return nil, fmt.Errorf("读取图片失败: %w", err)
+		return nil, s.fetchError(ctx, err)
 	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("source.content_type", resource.ContentType),
+		attribute.Int("source.size", len(resource.Data)),
+	)
+	slog.InfoContext(ctx, "source fetched", "content_type", resource.ContentType, "size", len(resource.Data))
 
-	// 2. 将图片转换为base64
-	base64Image := base64.StdEncoding.EncodeToString(imageData)
-	
-	// 3. 构建图片URL（data URI格式）
-	imageURL := fmt.Sprintf("data:image/jpeg;base64,%s", base64Image)
+	var src document.Source
+	switch {
+	case resource.ContentType == "application/pdf":
+		src = s.pdfSource(resource.Data, pageRanges)
+	case resource.ContentType == "text/html":
+		src = document.HTMLSource{Data: resource.Data, Charset: resource.Charset}
+	case strings.HasPrefix(resource.ContentType, "text/"):
+		src = document.TextSource(document.DecodeText(resource.Data, resource.Charset))
+	default:
+		if len(resource.Data) > MaxImageSizeMB*1024*1024 {
+			return nil, errcode.NewLocalizedError(errcode.PayloadTooLarge, "image.too_large", MaxImageSizeMB)
+		}
+		src = document.ImageSource{resource.Data}
+	}
+	return s.Analyze(ctx, src, depth, lang)
+}
 
-	// 4. 构建提示词
-	systemPrompt := s.buildAnalysisPrompt()
-	userPrompt := s.buildUserPrompt()
+// fetchError 将抓取错误转换为对应的错误码
+func (s *ImageAnalysisService) fetchError(ctx context.Context, err error) error {
+	var tooLarge *fetch.TooLargeError
+	var unsupported *fetch.UnsupportedTypeError
+	var status *fetch.StatusError
+	switch {
+	case errors.Is(err, fetch.ErrInvalidURL):
+		return errcode.WrapLocalized(errcode.InvalidParams, err, "url.invalid")
+	case errors.Is(err, fetch.ErrForbiddenAddress):
+		return errcode.WrapLocalized(errcode.InvalidParams, err, "url.forbidden")
+	case errors.As(err, &tooLarge):
+		return errcode.WrapLocalized(errcode.PayloadTooLarge, err, "url.too_large", tooLarge.MaxSizeMB)
+	case errors.As(err, &unsupported):
+		return errcode.WrapLocalized(errcode.UnsupportedMediaType, err, "url.unsupported_type", unsupported.ContentType)
+	case errors.As(err, &status):
+		return errcode.WrapLocalized(errcode.SourceFetchFailed, err, "url.status", status.Status)
+	case errors.Is(err, fetch.ErrTimeout):
+		return errcode.Wrap(errcode.SourceFetchTimeout, err, "")
+	case errors.Is(ctx.Err(), context.Canceled):
+		return errcode.Wrap(errcode.AIServiceCancelled, err, "")
+	default:
+		return errcode.WrapLocalized(errcode.SourceFetchFailed, err, "url.unreachable")
+	}
+}
 
-	// 5. 构建包含图片的消息（使用 Vision API 标准格式）
-	messages := []schema.Message{
-		schema.NewTextMessage("system", systemPrompt),
-		schema.NewVisionMessage("user", userPrompt, imageURL),
+// pdfSource 按当前配置的页数上限和渲染器创建 PDF 来源
+func (s *ImageAnalysisService) pdfSource(data []byte, pageRanges string) document.Source {
+	return document.PDFSource{Data: data, Options: document.PDFOptions{
+		Pages:    pageRanges,
+		MaxPages: s.MaxPDFPages(),
+		Renderer: s.renderer,
+	}}
+}
+
+// Analyze 读取材料来源并按 depth 要求的数量分析，图片、PDF、网页和文本都返回相同结构的结果
+func (s *ImageAnalysisService) Analyze(ctx context.Context, src document.Source, depth schema.AnalysisDepth, lang i18n.Language) (*schema.KnowledgeAnalysisResponse, error) {
+	extraction, err := s.load(ctx, src)
+	if err != nil {
+		return nil, err
 	}
+	result, err := s.AnalyzePages(ctx, extraction.Pages, depth, lang)
+	if err != nil {
+		return nil, err
+	}
+	result.SkippedPages = extraction.Skipped
+	return result, nil
+}
 
-	// 6. 调用AI服务
+// load 读取材料来源，将读取错误转换为对应的错误码
+func (s *ImageAnalysisService) load(ctx context.Context, src document.Source) (*document.Extraction, error) {
+	extraction, err := src.Load(ctx)
+	var rangeErr *document.PageRangeError
+	var tooMany *document.TooManyPagesError
+	switch {
+	case errors.As(err, &rangeErr):
+		return nil, errcode.WrapLocalized(errcode.InvalidParams, err, "pdf.invalid_pages", rangeErr.Spec, rangeErr.Total)
+	case errors.As(err, &tooMany):
+		return nil, errcode.WrapLocalized(errcode.InvalidParams, err, "pdf.too_many_pages", tooMany.Max)
+	case errors.Is(err, document.ErrInvalidPDF):
+		return nil, errcode.WrapLocalized(errcode.UnsupportedMediaType, err, "pdf.invalid")
+	case errors.Is(err, document.ErrNoContent):
+		if _, isPDF := src.(document.PDFSource); isPDF {
+			return nil, errcode.WrapLocalized(errcode.InvalidParams, err, "pdf.no_content")
+		}
+		return nil, errcode.WrapLocalized(errcode.InvalidParams, err, "text.no_content")
+	case err != nil:
+		return nil, errcode.Wrap(errcode.InternalError, fmt.Errorf("读取材料失败: %w", err), "")
+	}
+
+	textPages := 0
+	for _, page := range extraction.Pages {
+		if page.Text != "" {
+			textPages++
+		}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("source.total_pages", extraction.TotalPages),
+		attribute.Int("source.text_pages", textPages),
+		attribute.Int("source.skipped_pages", len(extraction.Skipped)),
+	)
+	if len(extraction.Skipped) > 0 {
+		slog.WarnContext(ctx, "pages skipped without text layer or renderer", "pages", extraction.Skipped)
+	}
+	return extraction, nil
+}
+
+// AnalyzePages 按顺序分析多页材料，页数超过 analysis.images_per_request 时分批并发请求模型，再合并为一个结果
+// 每批结果多于要求的数量时各自裁剪，合并后保留所有批次的重点知识点；前置、后置知识点不足时再请求一次模型补充
+func (s *ImageAnalysisService) AnalyzePages(ctx context.Context, pages []document.Page, depth schema.AnalysisDepth, lang i18n.Language) (*schema.KnowledgeAnalysisResponse, error) {
+	counts := resolveCounts(s.config().Analysis, depth)
+	learner := profile.LearnerFrom(ctx)
+
+	// 2. 分配实验变体，按批次渲染提示词模板
+	assignment := experiment.Assign(ctx, s.config().Experiments, experiment.TargetAnalysis)
+	var promptVariant string
+	model := s.aiService.config().DefaultModel
+	if assignment != nil {
+		promptVariant = assignment.PromptVariant
+		if assignment.Model != "" {
+			model = assignment.Model
+		}
+	}
+
+	// 多页、非第 1 页或包含文字时，每页前标注页码，结果中给出来源页码
+	labelled := len(pages) > 1 || pages[0].Number != 1 || pages[0].Text != ""
+	batches := splitPages(pages, s.imagesPerRequest())
+	for i := range batches {
+		b := &batches[i]
+		vars := prompt.AnalysisVars{
+			Pages:     len(b.pages),
+			FirstPage: b.pages[0].Number,
+			LastPage:  b.pages[len(b.pages)-1].Number,
+
+			MinKeyPoints:   counts.MinKeyPoints,
+			KeyPoints:      counts.KeyPoints,
+			Prerequisites:  counts.Prerequisites,
+			Postrequisites: counts.Postrequisites,
+			FunExamples:    counts.FunExamples,
+
+			Learner: learnerVars(learner, lang),
+		}
+		for _, page := range b.pages {
+			if page.Text != "" {
+				vars.TextPages++
+			}
+		}
+		p, err := s.prompts().RenderVariant(prompt.NameAnalysis, promptVariant, lang, vars)
+		if err != nil {
+			return nil, errcode.Wrap(errcode.InternalError, err, "")
+		}
+		b.prompt = p
+		b.labelled = labelled
+		b.counts = counts
+	}
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.Int("analysis.pages", len(pages)),
+		attribute.Int("analysis.batches", len(batches)),
+		attribute.Int("analysis.requested_key_points", counts.KeyPoints),
+		attribute.Int("analysis.requested_prerequisites", counts.Prerequisites),
+		attribute.Int("analysis.requested_postrequisites", counts.Postrequisites),
+	)
+
+	// 记录实验结果信号：解析是否成功、延迟和 token 用量
+	start := time.Now()
+	outcome := experiment.Outcome{Status: experiment.StatusError}
+	defer func() {
+		outcome.Latency = time.Since(start)
+		s.tracker.Record(assignment, outcome)
+	}()
+
+	// 3. 命中缓存时直接返回；缓存键包含模板版本、模型、语言、要求的数量、学习者画像和每页内容
+	keyParts := []string{model, string(lang), counts.cacheKey(), learner.CacheKey()}
+	for _, page := range pages {
+		if labelled {
+			keyParts = append(keyParts, strconv.Itoa(page.Number))
+		}
+		content := page.Image
+		if page.Text != "" {
+			content = []byte(page.Text)
+		}
+		sum := sha256.Sum256(content)
+		keyParts = append(keyParts, hex.EncodeToString(sum[:]))
+	}
+	cacheKey := batches[0].prompt.CacheKey(keyParts...)
+	if s.cache != nil {
+		if cached, ok := s.cache.Get(cacheKey); ok {
+			span.SetAttributes(attribute.Bool("analysis.cache_hit", true))
+			cached.ID = identity.NewID()
+			cached.Experiment = assignment.Info()
+			cached.ProfileVersion = learnerVersion(learner)
+			outcome.Status = experiment.StatusCacheHit
+			outcome.ResultID = cached.ID
+			s.remember(ctx, cached)
+			s.pages.Put(cached.ID, pages)
+			return cached, nil
+		}
+	}
+
+	// 4-8. 各批次并发请求模型并解析，全部成功后合并；任一批次失败时取消其余批次的请求
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg     sync.WaitGroup
+		once   sync.Once
+		failed *pageBatch // 最先失败的批次，其余批次随后因取消而失败
+	)
+	for i := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := &batches[i]
+			s.analyzeBatch(batchCtx, model, lang, b)
+			if b.err != nil {
+				once.Do(func() {
+					failed = b
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	results := make([]*schema.KnowledgeAnalysisResponse, 0, len(batches))
+	for _, b := range batches {
+		outcome.PromptTokens += b.usage.PromptTokens
+		outcome.CompletionTokens += b.usage.CompletionTokens
+		results = append(results, b.result)
+	}
+	outcome.Status = experiment.StatusParseError
+	if failed != nil {
+		if !failed.parsed {
+			outcome.Status = experiment.StatusError
+		}
+		return nil, failed.err
+	}
+
+	// 每批已各自裁剪，合并后的重点知识点上限按批次数放大，避免按批次顺序截掉后面页面的知识点
+	knowledgeData := mergeAnalyses(results, counts)
+	limits := counts
+	limits.KeyPoints *= len(batches)
+	limitCounts(knowledgeData, limits)
+	usage, err := s.topUpRelated(ctx, model, lang, knowledgeData, counts)
+	outcome.PromptTokens += usage.PromptTokens
+	outcome.CompletionTokens += usage.CompletionTokens
+	if err != nil {
+		return nil, err
+	}
+	knowledgeData.ID = identity.NewID()
+	knowledgeData.Language = string(lang)
+	knowledgeData.PromptVersion = batches[0].prompt.VersionID
+	knowledgeData.Experiment = assignment.Info()
+	knowledgeData.ProfileVersion = learnerVersion(learner)
+	if labelled {
+		knowledgeData.Pages = len(pages)
+	}
+
+	outcome.Status = experiment.StatusSuccess
+	outcome.ResultID = knowledgeData.ID
+	s.remember(ctx, knowledgeData)
+	s.pages.Put(knowledgeData.ID, pages)
+
+	if s.cache != nil && !counts.shortOf(knowledgeData) {
+		s.cache.Put(cacheKey, knowledgeData)
+	}
+
+	return knowledgeData, nil
+}
+
+// pageBatch 一次模型请求包含的页面及其结果
+type pageBatch struct {
+	pages    []document.Page
+	prompt   *prompt.Prompt
+	labelled bool           // 每页前标注页码，结果中给出来源页码
+	counts   analysisCounts // 要求的数量，结果多出时裁剪
+
+	result *schema.KnowledgeAnalysisResponse
+	usage  schema.Usage
+	parsed bool // 模型调用成功，err 不为空时表示响应无法解析
+	err    error
+}
+
+// splitPages 将页面按每批最多 size 页切分
+func splitPages(pages []document.Page, size int) []pageBatch {
+	var batches []pageBatch
+	for start := 0; start < len(pages); start += size {
+		end := min(start+size, len(pages))
+		batches = append(batches, pageBatch{pages: pages[start:end]})
+	}
+	return batches
+}
+
+// analyzeBatch 请求模型分析一批页面，结果和错误写入 b
+func (s *ImageAnalysisService) analyzeBatch(ctx context.Context, model string, lang i18n.Language, b *pageBatch) {
+	// 4-5. 将图片转换为base64 data URI，构建包含图片的消息（使用 Vision API 标准格式）
+	var user schema.Message
+	if b.labelled {
+		user = s.pagesMessage(ctx, b.prompt.User, b.pages, lang)
+	} else {
+		user = schema.NewVisionMessage("user", b.prompt.User, s.encodeImage(ctx, b.pages[0].Image))
+	}
 	chatReq := &schema.ChatRequest{
-		Messages: messages,
+		Model: model,
+		Messages: []schema.Message{
+			schema.NewTextMessage("system", b.prompt.System),
+			user,
+		},
 	}
 
-	chatResp, err := s.aiService.Chat(chatReq)
+	// 6. 调用AI服务
+	chatResp, err := s.aiService.Chat(ctx, chatReq)
 	if err != nil {
-		return nil, fmt.Errorf("AI分析失败: %w", err)
+		b.err = fmt.Errorf("AI分析失败: %w", err)
+		return
 	}
+	b.usage = chatResp.Usage
+	b.parsed = true
 
 	// 7. 解析AI响应
 	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("AI未返回任何响应")
+		b.err = errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.empty_response")
+		return
 	}
 
-	aiResponse := chatResp.Choices[0].Message.Content
-	
 	// Content 可能是 string 或其他类型，需要转换
-	var aiResponseStr string
-	switch v := aiResponse.(type) {
-	case string:
-		aiResponseStr = v
-	default:
-		return nil, fmt.Errorf("AI返回的内容格式不正确")
+	aiResponseStr, ok := chatResp.Choices[0].Message.Content.(string)
+	if !ok {
+		b.err = errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.invalid_content")
+		return
 	}
 
-	// 8. 解析JSON响应
-	knowledgeData, err := s.parseAIResponse(aiResponseStr)
+	// 8. 解析JSON响应，来源页码限定在本批次范围内，区域限定在图片范围内，丢弃无法渲染的公式
+	result, err := s.parseAIResponse(ctx, aiResponseStr)
 	if err != nil {
-		return nil, fmt.Errorf("解析AI响应失败: %w", err)
+		b.err = fmt.Errorf("解析AI响应失败: %w", err)
+		return
 	}
+	normalizeSources(result.KeyPoints, b.pages)
+	result.Formulas = normalizeFormulas(ctx, result.Formulas, result.KeyPoints, b.pages)
+	limitCounts(result, b.counts)
+	b.result = result
+}
 
-	return knowledgeData, nil
+// pagesMessage 构建多页消息：提示词之后每页先给出页码标签，再给出页面图片或文字
+func (s *ImageAnalysisService) pagesMessage(ctx context.Context, text string, pages []document.Page, lang i18n.Language) schema.Message {
+	parts := []schema.ContentPart{{Type: "text", Text: text}}
+	for _, page := range pages {
+		label := i18n.T(lang, "analysis.page_label", page.Number)
+		if page.Text != "" {
+			parts = append(parts, schema.ContentPart{Type: "text", Text: label + "\n" + page.Text})
+			continue
+		}
+		parts = append(parts,
+			schema.ContentPart{Type: "text", Text: label},
+			schema.ContentPart{Type: "image_url", ImageURL: &schema.ImageURL{URL: s.encodeImage(ctx, page.Image)}},
+		)
+	}
+	return schema.Message{Role: "user", Content: parts}
 }
 
-// readImageFile 读取图片文件
-func (s *ImageAnalysisService) readImageFile(file *multipart.FileHeader) ([]byte, error) {
-	src, err := file.Open()
+// KeyPointCrop 截取分析结果中知识点来源区域的图片，返回 PNG 和对应的来源
+// source 为知识点 sources 中的下标，小于 0 时使用第一个带区域的来源
+func (s *ImageAnalysisService) KeyPointCrop(ctx context.Context, analysisID, keyPointID string, source int) ([]byte, *schema.Source, error) {
+	point, err := lookupKeyPoint(s.results, analysisID, keyPointID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer src.Close()
-
-	return io.ReadAll(src)
-}
-
-// buildAnalysisPrompt 构建分析提示词
-func (s *ImageAnalysisService) buildAnalysisPrompt() string {
-	return `你是一个专业的教育内容分析助手。你的任务是分析图片中的知识点，并提取结构化信息。
-
-分析要求：
-1. 识别图片中的主要知识点（1-3个核心知识点）
-2. 为每个知识点生成5个前置知识点（学习这个知识点需要先掌握的内容）
-3. 为每个知识点生成5个后置知识点（掌握这个知识点后可以学习的内容）
-4. 所有ID必须唯一，格式为：kp-001, kp-002（主要知识点），kp-p001（前置），kp-n001（后置）
-5. 置信度（confidence）范围：0-1，表示识别的准确性
-
-注意：
-- 必须严格按照JSON格式返回
-- 前置知识点和后置知识点各必须有且仅有5个
-- 分类（category）要准确，如：数学、物理、化学、编程、人工智能等
-- 描述要清晰、准确、简洁`
-}
-
-// buildUserPrompt 构建用户提示词
-func (s *ImageAnalysisService) buildUserPrompt() string {
-	return `请分析这张图片中的知识点内容。
-
-要求：
-1. 提供一段完整的详细解释（detailedExplanation），解释图片中的主要知识内容
-2. 识别图片中的重点知识点（keyPoints），2-5个
-3. 为每个重点知识点提供一个趣味示例（funExamples），帮助理解
-4. 提供5个前置知识点（prerequisites），学习所需的基础
-5. 提供5个后置知识点（postrequisites），掌握后可以学习的内容
-6. 提供一段总结（conclusion），汇总学习建议
-
-请严格按照以下JSON格式返回（只返回JSON，不要其他说明文字）：
-
-{
-  "detailedExplanation": "这是一段完整的详细解释，介绍图片中的主要知识内容...",
-  "keyPoints": [
-    {
-      "id": "kp-001",
-      "title": "重点知识点标题",
-      "description": "详细描述",
-      "category": "分类名称",
-      "confidence": 0.95
-    }
-  ],
-  "funExamples": [
-    {
-      "knowledgePointId": "kp-001",
-      "title": "趣味示例标题",
-      "content": "生动有趣的示例内容，帮助理解知识点..."
-    }
-  ],
-  "prerequisites": [
-    {"id": "kp-p001", "title": "前置知识1", "description": "描述", "category": "分类"},
-    {"id": "kp-p002", "title": "前置知识2", "description": "描述", "category": "分类"},
-    {"id": "kp-p003", "title": "前置知识3", "description": "描述", "category": "分类"},
-    {"id": "kp-p004", "title": "前置知识4", "description": "描述", "category": "分类"},
-    {"id": "kp-p005", "title": "前置知识5", "description": "描述", "category": "分类"}
-  ],
-  "postrequisites": [
-    {"id": "kp-n001", "title": "后置知识1", "description": "描述", "category": "分类"},
-    {"id": "kp-n002", "title": "后置知识2", "description": "描述", "category": "分类"},
-    {"id": "kp-n003", "title": "后置知识3", "description": "描述", "category": "分类"},
-    {"id": "kp-n004", "title": "后置知识4", "description": "描述", "category": "分类"},
-    {"id": "kp-n005", "title": "后置知识5", "description": "描述", "category": "分类"}
-  ],
-  "conclusion": "总结：通过学习这些知识点，你将能够..."
-}`
+
+	if source < 0 {
+		source = firstRegion(point)
+	}
+	if source < 0 || source >= len(point.Sources) || point.Sources[source].BBox == nil {
+		return nil, nil, errcode.NewLocalizedError(errcode.NotFound, "crop.no_region", keyPointID)
+	}
+	src := point.Sources[source]
+
+	image, ok := s.pages.Get(analysisID, src.Page)
+	if !ok {
+		return nil, nil, errcode.NewLocalizedError(errcode.NotFound, "crop.image_expired", analysisID)
+	}
+	box := src.BBox
+	cropped, err := document.Crop(image, box.X, box.Y, box.Width, box.Height)
+	if err != nil {
+		return nil, nil, errcode.Wrap(errcode.InternalError, fmt.Errorf("截取知识点区域失败: %w", err), "")
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("crop.page", src.Page), attribute.Int("crop.size", len(cropped)))
+	return cropped, &src, nil
+}
+
+// remember 保存分析结果快照，供反馈时关联上下文
+func (s *ImageAnalysisService) remember(ctx context.Context, result *schema.KnowledgeAnalysisResponse) {
+	s.results.Remember(&feedback.Snapshot{
+		Kind:           feedback.KindAnalysis,
+		ID:             result.ID,
+		UserID:         identity.UserIDFrom(ctx),
+		Language:       result.Language,
+		PromptVersion:  result.PromptVersion,
+		ProfileVersion: result.ProfileVersion,
+		Experiment:     result.Experiment,
+		CreatedAt:      time.Now(),
+		Analysis:       result,
+	})
+}
+
+// encodeImage 将图片编码为 base64 data URI
+func (s *ImageAnalysisService) encodeImage(ctx context.Context, imageData []byte) string {
+	_, span := telemetry.StartSpan(ctx, "ImageAnalysisService.encodeImage")
+	defer span.End()
+
+	base64Image := base64.StdEncoding.EncodeToString(imageData)
+	span.SetAttributes(attribute.Int("image.base64_length", len(base64Image)))
+
+	return fmt.Sprintf("data:image/jpeg;base64,%s", base64Image)
 }
 
 // parseAIResponse 解析AI响应
-func (s *ImageAnalysisService) parseAIResponse(aiResponse string) (*schema.KnowledgeAnalysisResponse, error) {
+func (s *ImageAnalysisService) parseAIResponse(ctx context.Context, aiResponse string) (_ *schema.KnowledgeAnalysisResponse, err error) {
+	_, span := telemetry.StartSpan(ctx, "ImageAnalysisService.parseAIResponse")
+	span.SetAttributes(attribute.Int("ai.response_length", len(aiResponse)))
+	defer func() {
+		telemetry.RecordError(span, err)
+		span.End()
+	}()
+
 	// 尝试提取JSON（AI可能在JSON前后添加了其他文字）
 	jsonStr := s.extractJSON(aiResponse)
-	
+
 	var result schema.KnowledgeAnalysisResponse
-	err := json.Unmarshal([]byte(jsonStr), &result)
+	err = json.Unmarshal([]byte(jsonStr), &result)
 	if err != nil {
-		return nil, fmt.Errorf("JSON解析失败: %w, 响应内容: %s", err, aiResponse)
+		slog.DebugContext(ctx, "AI response is not valid JSON",
+			logger.UserContent("ai_response", logger.Truncate(aiResponse, maxLoggedBodyLength)),
+		)
+		return nil, errcode.WrapLocalized(errcode.AIResponseInvalid, err, "ai.invalid_json")
 	}
 
-	// 验证数据完整性
-	if len(result.Prerequisites) != 5 {
-		return nil, fmt.Errorf("前置知识点数量不正确，期望5个，实际%d个", len(result.Prerequisites))
-	}
-	if len(result.Postrequisites) != 5 {
-		return nil, fmt.Errorf("后置知识点数量不正确，期望5个，实际%d个", len(result.Postrequisites))
-	}
+	span.SetAttributes(
+		attribute.Int("analysis.key_points", len(result.KeyPoints)),
+		attribute.Int("analysis.prerequisites", len(result.Prerequisites)),
+		attribute.Int("analysis.postrequisites", len(result.Postrequisites)),
+	)
 
 	return &result, nil
 }
@@ -186,7 +578,7 @@ func (s *ImageAnalysisService) extractJSON(text string) string {
 	// 查找第一个 { 和最后一个 }
 	start := -1
 	end := -1
-	
+
 	for i, ch := range text {
 		if ch == '{' && start == -1 {
 			start = i
@@ -195,11 +587,10 @@ func (s *ImageAnalysisService) extractJSON(text string) string {
 			end = i
 		}
 	}
-	
+
 	if start != -1 && end != -1 && end > start {
 		return text[start : end+1]
 	}
-	
+
 	return text
 }
-
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/schema"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// analysisJSON 构造分析结果 JSON，前置和后置知识点数量可调
+func analysisJSON(t *testing.T, prerequisites, postrequisites int) string {
+	t.Helper()
+	points := func(n int) []schema.KnowledgePoint {
+		list := make([]schema.KnowledgePoint, n)
+		for i := range list {
+			list[i] = schema.KnowledgePoint{ID: "p", Title: "知识点"}
+		}
+		return list
+	}
+	data, err := json.Marshal(schema.KnowledgeAnalysisResponse{
+		DetailedExplanation: "详解",
+		Prerequisites:       points(prerequisites),
+		KeyPoints:           []schema.KnowledgePoint{{ID: "kp_1", Title: "勾股定理"}},
+		Postrequisites:      points(postrequisites),
+		Conclusion:          "总结",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseAIResponse(t *testing.T) {
+	valid := analysisJSON(t, 5, 5)
+	tests := []struct {
+		name     string
+		response string
+		wantCode *errcode.ErrCode // nil 表示解析成功
+	}{
+		{"plain JSON", valid, nil},
+		{"markdown fence", "以下是分析结果：\n```json\n" + valid + "\n```", nil},
+		{"not JSON", "抱歉，我无法识别这张图片", errcode.AIResponseInvalid},
+		{"truncated", valid[:len(valid)/2], errcode.AIResponseInvalid},
+		// 数量不符时由 limitCounts 裁剪或 topUpRelated 补充，解析本身不失败
+		{"too few prerequisites", analysisJSON(t, 3, 5), nil},
+		{"too many postrequisites", analysisJSON(t, 5, 6), nil},
+	}
+
+	s := &ImageAnalysisService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.parseAIResponse(context.Background(), tt.response)
+			if tt.wantCode == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(result.KeyPoints) != 1 || result.KeyPoints[0].Title != "勾股定理" {
+					t.Errorf("unexpected key points: %+v", result.KeyPoints)
+				}
+				return
+			}
+			if got := errcode.From(err).Code; got != tt.wantCode {
+				t.Errorf("error code = %v, want %s (err: %v)", got.Reason, tt.wantCode.Reason, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/schema"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// lookupKeyPoint 在保存的分析结果中查找重点知识点
+func lookupKeyPoint(results *feedback.Results, analysisID, keyPointID string) (*schema.KnowledgePoint, error) {
+	snapshot, ok := results.Lookup(feedback.KindAnalysis, analysisID)
+	if !ok || snapshot.Analysis == nil {
+		return nil, errcode.NewLocalizedError(errcode.NotFound, "crop.unknown_analysis", analysisID)
+	}
+	for i := range snapshot.Analysis.KeyPoints {
+		if snapshot.Analysis.KeyPoints[i].ID == keyPointID {
+			return &snapshot.Analysis.KeyPoints[i], nil
+		}
+	}
+	return nil, errcode.NewLocalizedError(errcode.NotFound, "crop.unknown_keypoint", keyPointID)
+}
+
+// firstRegion 第一个带图片区域的来源下标，没有时返回 -1
+func firstRegion(point *schema.KnowledgePoint) int {
+	return slices.IndexFunc(point.Sources, func(src schema.Source) bool { return src.BBox != nil })
+}
+
+// keyPointImage 生成对话中附带的知识点原图 data URI
+// crop 优先截取知识点区域，没有区域时与 page 相同，使用知识点来源中第一张仍保存的页面原图；
+// 没有来源页码时使用第 1 页。找不到可用的图片时返回空字符串
+func keyPointImage(point *schema.KnowledgePoint, mode string, pages map[int][]byte) (string, error) {
+	if mode == schema.ImageContextCrop {
+		if i := firstRegion(point); i >= 0 {
+			src := point.Sources[i]
+			if image, ok := pages[src.Page]; ok {
+				cropped, err := document.Crop(image, src.BBox.X, src.BBox.Y, src.BBox.Width, src.BBox.Height)
+				if err != nil {
+					return "", fmt.Errorf("截取知识点区域失败: %w", err)
+				}
+				return dataURL(cropped), nil
+			}
+		}
+	}
+
+	candidates := []int{1}
+	if len(point.Sources) > 0 {
+		candidates = candidates[:0]
+		for _, src := range point.Sources {
+			candidates = append(candidates, src.Page)
+		}
+	}
+	for _, page := range candidates {
+		if image, ok := pages[page]; ok {
+			return dataURL(image), nil
+		}
+	}
+	return "", nil
+}
+
+// dataURL 将图片编码为 data URI，按内容识别图片类型
+func dataURL(image []byte) string {
+	return fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(image), base64.StdEncoding.EncodeToString(image))
+}
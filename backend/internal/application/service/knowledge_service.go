@@ -1,37 +1,99 @@
 package service
 
 import (
+	"ai-note-service/internal/application/errcode"
+	"ai-note-service/internal/application/experiment"
+	"ai-note-service/internal/application/feedback"
+	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/identity"
+	"ai-note-service/internal/application/profile"
+	"ai-note-service/internal/application/prompt"
 	"ai-note-service/internal/application/schema"
+	"ai-note-service/internal/application/tutor"
+	"context"
 	"fmt"
+	"log/slog"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // KnowledgeService 知识点服务
 type KnowledgeService struct {
+	config    global.Provider
 	aiService *AIService
+	prompts   func() *prompt.Registry
+	tracker   *experiment.Tracker
+	results   *feedback.Results
+	pages     *PageStore // 分析时保存的页面图片，对话中附带原图时读取
+	tutoring  *tutor.Sessions
 }
 
 // NewKnowledgeService 创建知识点服务实例
-func NewKnowledgeService() *KnowledgeService {
+func NewKnowledgeService(deps Deps) *KnowledgeService {
 	return &KnowledgeService{
-		aiService: NewAIService(),
+		config:    deps.Config,
+		aiService: deps.AI,
+		prompts:   deps.Prompts,
+		tracker:   deps.Tracker,
+		results:   deps.Results,
+		pages:     deps.Pages,
+		tutoring:  deps.Tutoring,
 	}
 }
 
-// GetDialogueResponse 获取知识点的AI对话响应
+// GetDialogueResponse 获取知识点的AI对话响应，lang 决定回复语言
+// imageContext 为 page 或 crop 时，在第一条用户消息中附带分析 analysisID 中该知识点的原图
+// strategy 为辅导策略；strategy、imageContext 和 analysisID 为空时沿用会话中已选的；非直接讲解的策略按回复末尾的进度标记推进会话的辅导状态
 func (s *KnowledgeService) GetDialogueResponse(
+	ctx context.Context,
+	conversationID string,
 	knowledgePointId string,
 	knowledgePointTitle string,
 	knowledgePointDesc string,
 	userMessage string,
 	conversationHistory []schema.ConversationMessage,
+	analysisID string,
+	imageContext string,
+	strategy string,
+	lang i18n.Language,
 ) (*schema.DialogueResponse, error) {
-	// 1. 构建系统提示词 - 根据知识点定制化
-	systemPrompt := s.buildSystemPrompt(knowledgePointTitle, knowledgePointDesc)
+	// 渲染提示词使用会话当前的状态，回复后在锁内基于最新状态推进
+	current, _ := s.tutoring.Get(conversationID)
+	session := current.Continue(strategy).WithImage(analysisID, imageContext)
+	imageURL, err := s.dialogueImage(ctx, session.AnalysisID, knowledgePointId, session.ImageContext)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 分配实验变体，渲染系统提示词模板 - 根据知识点和学习者画像定制化
+	learner := profile.LearnerFrom(ctx)
+	assignment := experiment.Assign(ctx, s.config().Experiments, experiment.TargetDialogue)
+	var promptVariant string
+	chatReq := &schema.ChatRequest{}
+	if assignment != nil {
+		promptVariant = assignment.PromptVariant
+		chatReq.Model = assignment.Model
+	}
+
+	p, err := s.prompts().RenderVariant(prompt.NameDialogue, promptVariant, lang, prompt.DialogueVars{
+		Title:       knowledgePointTitle,
+		Description: knowledgePointDesc,
+		HasImage:    imageURL != "",
+		Learner:     learnerVars(learner, lang),
+		Strategy:    session.Strategy,
+		Hint:        session.StuckTurns >= tutor.HintAfterStuck,
+		GoalReached: session.State == schema.TutoringGoalReached,
+	})
+	if err != nil {
+		return nil, errcode.Wrap(errcode.InternalError, err, "")
+	}
 
 	// 2. 构建消息列表
 	messages := []schema.Message{
-		schema.NewTextMessage("system", systemPrompt),
+		schema.NewTextMessage("system", p.System),
 	}
 
 	// 3. 添加历史对话（如果有）
@@ -46,19 +108,36 @@ func (s *KnowledgeService) GetDialogueResponse(
 	// 4. 添加当前用户消息
 	messages = append(messages, schema.NewTextMessage("user", userMessage))
 
-	// 5. 调用AI服务
-	chatReq := &schema.ChatRequest{
-		Messages: messages,
+	// 原图附在第一条用户消息中，每轮请求位置相同
+	if imageURL != "" {
+		for i := range messages {
+			if messages[i].Role == "user" {
+				messages[i] = schema.NewVisionMessage("user", messages[i].Content.(string), imageURL)
+				break
+			}
+		}
 	}
 
-	chatResp, err := s.aiService.Chat(chatReq)
+	// 5. 调用AI服务，记录实验结果信号
+	start := time.Now()
+	outcome := experiment.Outcome{Status: experiment.StatusError}
+	defer func() {
+		outcome.Latency = time.Since(start)
+		s.tracker.Record(assignment, outcome)
+	}()
+
+	chatReq.Messages = messages
+	chatResp, err := s.aiService.Chat(ctx, chatReq)
 	if err != nil {
 		return nil, fmt.Errorf("AI对话失败: %w", err)
 	}
+	outcome.PromptTokens = chatResp.Usage.PromptTokens
+	outcome.CompletionTokens = chatResp.Usage.CompletionTokens
 
 	// 6. 提取AI回复
+	outcome.Status = experiment.StatusParseError
 	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("AI未返回任何响应")
+		return nil, errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.empty_response")
 	}
 
 	// Content 可能是 string 或其他类型，需要转换
@@ -68,32 +147,96 @@ func (s *KnowledgeService) GetDialogueResponse(
 	case string:
 		aiMessageStr = v
 	default:
-		return nil, fmt.Errorf("AI返回的内容格式不正确")
+		return nil, errcode.NewLocalizedError(errcode.AIResponseInvalid, "ai.invalid_content")
 	}
 
-	// 7. 返回对话响应
-	return &schema.DialogueResponse{
-		Message:   aiMessageStr,
-		Timestamp: time.Now().Format(time.RFC3339),
-	}, nil
-}
-
-// buildSystemPrompt 构建系统提示词
-func (s *KnowledgeService) buildSystemPrompt(title, description string) string {
-	return fmt.Sprintf(`你是一个专业的教育助手，擅长解答学生的问题。
+	// 7. 取出进度标记，推进辅导状态
+	var signal tutor.Signal
+	if tutor.Tracked(session.Strategy) {
+		aiMessageStr, signal = tutor.ParseSignal(aiMessageStr)
+	}
 
-当前讨论的知识点是：%s
-知识点描述：%s
+	// 8. 返回对话响应
+	if conversationID == "" {
+		conversationID = identity.NewID()
+	}
+	session = s.tutoring.Update(conversationID, func(current tutor.Session) tutor.Session {
+		next := current.Continue(strategy).WithImage(analysisID, imageContext)
+		if tutor.Tracked(next.Strategy) {
+			next = next.Next(signal)
+		}
+		return next
+	})
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("dialogue.strategy", session.Strategy))
+	if session.Progress() != nil {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("dialogue.tutoring_state", session.State))
+	}
+	response := &schema.DialogueResponse{
+		ConversationID: conversationID,
+		MessageID:      identity.NewID(),
+		Message:        aiMessageStr,
+		Timestamp:      time.Now().Format(time.RFC3339),
+		Language:       string(lang),
+		PromptVersion:  p.VersionID,
+		ProfileVersion: learnerVersion(learner),
+		Experiment:     assignment.Info(),
+		Strategy:       session.Strategy,
+		Tutoring:       session.Progress(),
+	}
+	if imageURL != "" {
+		response.ImageContext = session.ImageContext
+	}
+	outcome.Status = experiment.StatusSuccess
+	outcome.ResultID = response.MessageID
+
+	// 保存结果快照，供反馈时关联上下文
+	s.results.Remember(&feedback.Snapshot{
+		Kind:                feedback.KindMessage,
+		ID:                  response.MessageID,
+		ConversationID:      conversationID,
+		KnowledgePointID:    knowledgePointId,
+		UserID:              identity.UserIDFrom(ctx),
+		Language:            response.Language,
+		PromptVersion:       response.PromptVersion,
+		ProfileVersion:      response.ProfileVersion,
+		Experiment:          response.Experiment,
+		CreatedAt:           time.Now(),
+		KnowledgePointTitle: knowledgePointTitle,
+		UserMessage:         userMessage,
+		Reply:               aiMessageStr,
+	})
+
+	return response, nil
+}
 
-请遵循以下原则：
-1. 用清晰、易懂的语言解释概念
-2. 提供具体的例子帮助理解
-3. 如果涉及复杂概念，分步骤讲解
-4. 鼓励学生提出更多问题
-5. 保持友好、耐心的态度
-6. 回答要准确、专业，但避免过于学术化
-7. 如果学生问的问题超出了当前知识点范围，可以简要说明并引导回到主题
+// dialogueImage 返回对话中附带的知识点原图 data URI，不附带或原图已过期时返回空字符串
+// 编码结果随页面图片缓存，同一会话的后续轮次不再重复截图和编码
+func (s *KnowledgeService) dialogueImage(ctx context.Context, analysisID, keyPointID, mode string) (string, error) {
+	if mode == "" || mode == schema.ImageContextNone {
+		return "", nil
+	}
+	if analysisID == "" {
+		return "", errcode.NewLocalizedError(errcode.InvalidParams, "dialogue.analysis_required")
+	}
+	point, err := lookupKeyPoint(s.results, analysisID, keyPointID)
+	if err != nil {
+		return "", err
+	}
 
-现在请开始回答学生的问题。`, title, description)
+	url, ok, err := s.pages.DataURL(analysisID, keyPointID+"/"+mode, func(pages map[int][]byte) (string, error) {
+		return keyPointImage(point, mode, pages)
+	})
+	if err != nil {
+		return "", errcode.Wrap(errcode.InternalError, err, "")
+	}
+	if !ok || url == "" {
+		// 原图过期不影响对话，只是不再附带图片
+		slog.WarnContext(ctx, "dialogue image unavailable", "analysis_id", analysisID, "knowledge_point_id", keyPointID)
+		return "", nil
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("dialogue.image_context", mode),
+		attribute.Int("dialogue.image_size", len(url)),
+	)
+	return url, nil
 }
-
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/profile"
+	"ai-note-service/internal/application/prompt"
+	"strings"
+)
+
+// learnerVars 将生效的学习者画像转换为提示词变量，描述按输出语言本地化；画像为空时返回 nil
+func learnerVars(l *profile.Learner, lang i18n.Language) *prompt.Learner {
+	if l.Empty() {
+		return nil
+	}
+	vars := &prompt.Learner{Strengths: strings.Join(l.Strengths, i18n.T(lang, "learner.separator"))}
+	if l.GradeLevel != "" {
+		vars.Grade = i18n.T(lang, "learner.grade."+l.GradeLevel)
+	}
+	if l.Style != "" {
+		vars.Style = i18n.T(lang, "learner.style."+l.Style)
+	}
+	return vars
+}
+
+// learnerVersion 返回画像版本，没有画像时为空
+func learnerVersion(l *profile.Learner) string {
+	if l == nil {
+		return ""
+	}
+	return l.Version
+}
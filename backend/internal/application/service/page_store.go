@@ -0,0 +1,131 @@
+package service
+
+import (
+	"ai-note-service/internal/application/document"
+	"ai-note-service/internal/application/global"
+	"container/list"
+	"sync"
+)
+
+// defaultPageStoreMB analysis.page_store_mb 未配置时的页面图片存储上限
+const defaultPageStoreMB = 64
+
+// PageStore 保存最近分析的页面图片，供截取知识点区域和在对话中附带原图（LRU，按总字节数淘汰）
+// 只保存在内存中，重启或淘汰后无法再使用
+type PageStore struct {
+	mu       sync.Mutex
+	maxBytes int64
+	used     int64
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+// pageStoreEntry 一次分析的页面图片，key 为页码
+type pageStoreEntry struct {
+	id      string
+	pages   map[int][]byte
+	encoded map[string]string // 已编码的 data URI，随分析一起淘汰
+	size    int64
+}
+
+// NewConfiguredPageStore 按 analysis.page_store_mb 创建页面图片存储，修改配置需要重启
+func NewConfiguredPageStore(config global.Provider) *PageStore {
+	mb := config().Analysis.PageStoreMB
+	if mb <= 0 {
+		mb = defaultPageStoreMB
+	}
+	return NewPageStore(int64(mb) << 20)
+}
+
+// NewPageStore 创建页面图片存储，maxBytes 为图片总字节数上限
+func NewPageStore(maxBytes int64) *PageStore {
+	return &PageStore{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Put 保存分析 id 的图片页，文字页不保存；图片总大小超过上限时不保存
+func (s *PageStore) Put(id string, pages []document.Page) {
+	entry := &pageStoreEntry{id: id, pages: make(map[int][]byte), encoded: make(map[string]string)}
+	for _, page := range pages {
+		if page.Text == "" && len(page.Image) > 0 {
+			entry.pages[page.Number] = page.Image
+			entry.size += int64(len(page.Image))
+		}
+	}
+	if len(entry.pages) == 0 || entry.size > s.maxBytes {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[id]; ok {
+		s.remove(elem)
+	}
+	s.entries[id] = s.order.PushFront(entry)
+	s.used += entry.size
+	for s.used > s.maxBytes {
+		s.remove(s.order.Back())
+	}
+}
+
+// Get 读取分析 id 第 page 页的图片
+func (s *PageStore) Get(id string, page int) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+	image, ok := elem.Value.(*pageStoreEntry).pages[page]
+	return image, ok
+}
+
+// DataURL 返回分析 id 中 key 对应图片的 data URI，首次使用时由 encode 生成并缓存
+// 分析的图片已淘汰时返回 false，不调用 encode
+func (s *PageStore) DataURL(id, key string, encode func(pages map[int][]byte) (string, error)) (string, bool, error) {
+	s.mu.Lock()
+	elem, ok := s.entries[id]
+	if !ok {
+		s.mu.Unlock()
+		return "", false, nil
+	}
+	s.order.MoveToFront(elem)
+	entry := elem.Value.(*pageStoreEntry)
+	if url, ok := entry.encoded[key]; ok {
+		s.mu.Unlock()
+		return url, true, nil
+	}
+	s.mu.Unlock()
+
+	// 页面图片只读，编码（可能包含截图）在锁外进行
+	url, err := encode(entry.pages)
+	if err != nil || url == "" {
+		return url, true, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries[id] == elem {
+		if _, ok := entry.encoded[key]; !ok {
+			entry.encoded[key] = url
+			entry.size += int64(len(url))
+			s.used += int64(len(url))
+			for s.used > s.maxBytes && s.order.Back() != elem {
+				s.remove(s.order.Back())
+			}
+		}
+	}
+	return url, true, nil
+}
+
+func (s *PageStore) remove(elem *list.Element) {
+	entry := s.order.Remove(elem).(*pageStoreEntry)
+	delete(s.entries, entry.id)
+	s.used -= entry.size
+}
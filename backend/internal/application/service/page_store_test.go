@@ -0,0 +1,60 @@
+package service
+
+import (
+	"ai-note-service/internal/application/document"
+	"testing"
+)
+
+func TestPageStoreEvictsBySize(t *testing.T) {
+	store := NewPageStore(10)
+	store.Put("a", []document.Page{{Number: 1, Image: []byte("aaaa")}, {Number: 2, Text: "文字页"}})
+	store.Put("b", []document.Page{{Number: 3, Image: []byte("bbbb")}})
+
+	// 文字页不保存
+	if _, ok := store.Get("a", 2); ok {
+		t.Error("text page should not be stored")
+	}
+	// 访问 a 后，b 成为最久未使用的条目
+	if image, ok := store.Get("a", 1); !ok || string(image) != "aaaa" {
+		t.Fatalf("Get(a, 1) = %q, %v", image, ok)
+	}
+	store.Put("c", []document.Page{{Number: 1, Image: []byte("cccc")}})
+
+	if _, ok := store.Get("b", 3); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := store.Get("a", 1); !ok {
+		t.Error("expected a to be kept")
+	}
+
+	// 超过上限的单次分析不保存，也不挤掉已有条目
+	store.Put("d", []document.Page{{Number: 1, Image: make([]byte, 11)}})
+	if _, ok := store.Get("d", 1); ok {
+		t.Error("oversized entry should not be stored")
+	}
+	if _, ok := store.Get("c", 1); !ok {
+		t.Error("expected c to be kept")
+	}
+}
+
+func TestPageStoreCachesDataURL(t *testing.T) {
+	store := NewPageStore(1 << 10)
+	store.Put("a", []document.Page{{Number: 1, Image: []byte("aaaa")}})
+
+	calls := 0
+	encode := func(pages map[int][]byte) (string, error) {
+		calls++
+		return "data:" + string(pages[1]), nil
+	}
+	for range 2 {
+		if url, ok, err := store.DataURL("a", "kp-001/page", encode); !ok || err != nil || url != "data:aaaa" {
+			t.Fatalf("DataURL = %q, %v, %v", url, ok, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("encode called %d times, want 1", calls)
+	}
+	if _, ok, _ := store.DataURL("b", "kp-001/page", encode); ok {
+		t.Error("expected missing analysis")
+	}
+}
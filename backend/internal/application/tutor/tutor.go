@@ -0,0 +1,190 @@
+// Package tutor 知识点对话的会话状态：按会话记录辅导策略和进度、附带原图的方式，根据模型每轮回复末尾的进度标记推进辅导状态
+package tutor
+
+import (
+	"ai-note-service/internal/application/schema"
+	"container/list"
+	"regexp"
+	"sync"
+)
+
+// DefaultMaxSessions 默认保留的会话数量
+const DefaultMaxSessions = 5000
+
+// HintAfterStuck 学生连续没有进展达到该轮数后，提示词要求给出更具体的提示
+const HintAfterStuck = 2
+
+// Signal 模型对学生本轮表现的判断，写在回复末尾的进度标记中，如 <<progress:stuck>>
+type Signal string
+
+const (
+	SignalNone     Signal = ""         // 回复中没有可识别的标记，状态不变
+	SignalProgress Signal = "progress" // 学生在向目标推进
+	SignalStuck    Signal = "stuck"    // 学生答错、困惑或没有进展
+	SignalReached  Signal = "reached"  // 学生已经独立达成目标
+)
+
+// markerPattern 回复末尾的进度标记
+var markerPattern = regexp.MustCompile(`\s*<<progress:\s*([a-z]+)\s*>>\s*$`)
+
+// ParseSignal 从回复末尾取出进度标记，返回去掉标记后的回复；未知的标记视为没有标记，但同样从回复中去掉
+func ParseSignal(reply string) (string, Signal) {
+	m := markerPattern.FindStringSubmatchIndex(reply)
+	if m == nil {
+		return reply, SignalNone
+	}
+	text := reply[:m[0]]
+	switch signal := Signal(reply[m[2]:m[3]]); signal {
+	case SignalProgress, SignalStuck, SignalReached:
+		return text, signal
+	default:
+		return text, SignalNone
+	}
+}
+
+// Tracked 策略是否跟踪辅导进度，直接讲解不跟踪
+func Tracked(strategy string) bool {
+	return strategy != "" && strategy != schema.StrategyDirect
+}
+
+// Session 会话的辅导状态和附带原图的设置
+type Session struct {
+	Strategy   string
+	State      string
+	Turns      int
+	StuckTurns int
+
+	AnalysisID   string // 附带原图时知识点所属的分析结果ID
+	ImageContext string // 附带原图的方式，首轮选定后沿用，与辅导策略无关
+}
+
+// NewSession 以 strategy 开始新的辅导会话
+func NewSession(strategy string) Session {
+	if strategy == "" {
+		strategy = schema.StrategyDirect
+	}
+	return Session{Strategy: strategy, State: schema.TutoringInProgress}
+}
+
+// Continue 返回本轮使用的辅导状态：没有会话（零值）或 strategy 与会话已选的策略不同时重新开始，strategy 为空时沿用会话的策略
+func (s Session) Continue(strategy string) Session {
+	if s.Strategy == "" || (strategy != "" && strategy != s.Strategy) {
+		next := NewSession(strategy)
+		next.AnalysisID, next.ImageContext = s.AnalysisID, s.ImageContext
+		return next
+	}
+	return s
+}
+
+// WithImage 记录请求中附带原图的设置，为空的字段沿用会话中已有的
+func (s Session) WithImage(analysisID, imageContext string) Session {
+	if analysisID != "" {
+		s.AnalysisID = analysisID
+	}
+	if imageContext != "" {
+		s.ImageContext = imageContext
+	}
+	return s
+}
+
+// Next 按本轮的进度标记推进状态：
+// reached 进入 goal_reached 且不再回退；stuck 进入 struggling 并累计连续没有进展的轮数；progress 回到 in_progress 并清零；没有标记时状态不变
+func (s Session) Next(signal Signal) Session {
+	s.Turns++
+	if s.State == schema.TutoringGoalReached {
+		return s
+	}
+	switch signal {
+	case SignalReached:
+		s.State = schema.TutoringGoalReached
+		s.StuckTurns = 0
+	case SignalStuck:
+		s.State = schema.TutoringStruggling
+		s.StuckTurns++
+	case SignalProgress:
+		s.State = schema.TutoringInProgress
+		s.StuckTurns = 0
+	}
+	return s
+}
+
+// Progress 返回响应中的辅导进度，不跟踪进度的策略返回 nil
+func (s Session) Progress() *schema.TutoringProgress {
+	if !Tracked(s.Strategy) {
+		return nil
+	}
+	return &schema.TutoringProgress{State: s.State, Turns: s.Turns, StuckTurns: s.StuckTurns}
+}
+
+// Sessions 最近会话的辅导状态（内存，超过容量时淘汰最久未使用的会话），重启后清空
+type Sessions struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// entry 会话ID和状态
+type entry struct {
+	id      string
+	session Session
+}
+
+// NewSessions 创建会话状态存储，size 为最大保留数量
+func NewSessions(size int) *Sessions {
+	return &Sessions{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get 返回会话的辅导状态
+func (s *Sessions) Get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[id]
+	if !ok {
+		return Session{}, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*entry).session, true
+}
+
+// Put 保存会话的辅导状态
+func (s *Sessions) Put(id string, session Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(id, session)
+}
+
+// Update 在锁内读取会话的辅导状态（不存在时为零值），保存并返回 fn 的结果
+// 同一会话的并发轮次依次推进，不会丢失更新
+func (s *Sessions) Update(id string, fn func(Session) Session) Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current Session
+	if elem, ok := s.entries[id]; ok {
+		current = elem.Value.(*entry).session
+	}
+	next := fn(current)
+	s.put(id, next)
+	return next
+}
+
+// put 保存会话的辅导状态，调用方持有锁
+func (s *Sessions) put(id string, session Session) {
+	if elem, ok := s.entries[id]; ok {
+		elem.Value.(*entry).session = session
+		s.order.MoveToFront(elem)
+		return
+	}
+	s.entries[id] = s.order.PushFront(&entry{id: id, session: session})
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*entry).id)
+	}
+}
@@ -0,0 +1,110 @@
+package tutor
+
+import (
+	"ai-note-service/internal/application/schema"
+	"sync"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	for _, tc := range []struct {
+		reply  string
+		text   string
+		signal Signal
+	}{
+		{"你觉得斜边是哪条？\n<<progress:progress>>", "你觉得斜边是哪条？", SignalProgress},
+		{"再想想直角对着哪条边。 <<progress: stuck >>\n", "再想想直角对着哪条边。", SignalStuck},
+		{"完全正确！\n\n<<progress:reached>>", "完全正确！", SignalReached},
+		{"好的。\n<<progress:done>>", "好的。", SignalNone},
+		{"没有标记的回复", "没有标记的回复", SignalNone},
+		{"<<progress:stuck>> 标记不在末尾", "<<progress:stuck>> 标记不在末尾", SignalNone},
+	} {
+		text, signal := ParseSignal(tc.reply)
+		if text != tc.text || signal != tc.signal {
+			t.Errorf("ParseSignal(%q) = %q, %q, want %q, %q", tc.reply, text, signal, tc.text, tc.signal)
+		}
+	}
+}
+
+func TestSessionNext(t *testing.T) {
+	s := NewSession(schema.StrategySocratic)
+	steps := []struct {
+		signal Signal
+		state  string
+		stuck  int
+	}{
+		{SignalProgress, schema.TutoringInProgress, 0},
+		{SignalStuck, schema.TutoringStruggling, 1},
+		{SignalNone, schema.TutoringStruggling, 1},
+		{SignalStuck, schema.TutoringStruggling, 2},
+		{SignalProgress, schema.TutoringInProgress, 0},
+		{SignalReached, schema.TutoringGoalReached, 0},
+		{SignalStuck, schema.TutoringGoalReached, 0},
+	}
+	for i, step := range steps {
+		s = s.Next(step.signal)
+		if s.State != step.state || s.StuckTurns != step.stuck || s.Turns != i+1 {
+			t.Fatalf("step %d (%q): got %+v, want state %s stuck %d", i, step.signal, s, step.state, step.stuck)
+		}
+	}
+	if p := s.Progress(); p == nil || p.State != schema.TutoringGoalReached || p.Turns != len(steps) {
+		t.Errorf("Progress() = %+v", p)
+	}
+
+	if direct := NewSession(""); direct.Strategy != schema.StrategyDirect || direct.Progress() != nil {
+		t.Errorf("default session = %+v, want direct without progress", direct)
+	}
+}
+
+func TestSessionsEviction(t *testing.T) {
+	s := NewSessions(2)
+	s.Put("a", NewSession(schema.StrategySocratic))
+	s.Put("b", NewSession(schema.StrategyWorkedExample))
+	s.Get("a")
+	s.Put("c", NewSession(schema.StrategyCheckUnderstanding))
+
+	if _, ok := s.Get("b"); ok {
+		t.Error("least recently used session b should be evicted")
+	}
+	if got, ok := s.Get("a"); !ok || got.Strategy != schema.StrategySocratic {
+		t.Errorf("Get(a) = %+v, %v", got, ok)
+	}
+	if _, ok := s.Get("c"); !ok {
+		t.Error("session c missing")
+	}
+}
+
+func TestSessionContinue(t *testing.T) {
+	s := NewSession(schema.StrategySocratic).Next(SignalStuck)
+	if got := s.Continue(""); got != s {
+		t.Errorf("Continue(\"\") = %+v, want the stored session", got)
+	}
+	if got := s.Continue(schema.StrategySocratic); got != s {
+		t.Errorf("Continue(same) = %+v, want the stored session", got)
+	}
+	if got := s.Continue(schema.StrategyWorkedExample); got != NewSession(schema.StrategyWorkedExample) {
+		t.Errorf("Continue(other) = %+v, want a new session", got)
+	}
+	if got := (Session{}).Continue(""); got.Strategy != schema.StrategyDirect {
+		t.Errorf("Continue on missing session = %+v, want direct", got)
+	}
+}
+
+func TestSessionsUpdateConcurrent(t *testing.T) {
+	s := NewSessions(10)
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Update("c1", func(current Session) Session {
+				return current.Continue(schema.StrategySocratic).Next(SignalStuck)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if got, _ := s.Get("c1"); got.Turns != 50 || got.StuckTurns != 50 {
+		t.Errorf("after concurrent updates = %+v, want 50 turns", got)
+	}
+}
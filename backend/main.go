@@ -1,36 +1,249 @@
 package main
 
 import (
+	"ai-note-service/internal/application/app"
 	"ai-note-service/internal/application/common"
 	"ai-note-service/internal/application/controller"
+	"ai-note-service/internal/application/experiment"
 	"ai-note-service/internal/application/global"
+	"ai-note-service/internal/application/i18n"
+	"ai-note-service/internal/application/lifecycle"
+	"ai-note-service/internal/application/logger"
+	"ai-note-service/internal/application/prompt"
+	"ai-note-service/internal/application/telemetry"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// 服务器超时默认值，配置为 0 时使用
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 60 * time.Second
+	defaultWriteTimeout      = 120 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultShutdownTimeout   = 30 * time.Second
+	defaultMaxHeaderBytes    = 1 << 20 // 1MB
 )
 
 func main() {
+	configPath := flag.String("config", envOrDefault(common.EnvPrefix+"_CONFIG", "config.yaml"), "配置文件路径，为空时只使用环境变量")
+	flag.Parse()
+
 	// 加载配置
-	if err := initConfig(); err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	_, configFromEnv := os.LookupEnv(common.EnvPrefix + "_CONFIG")
+	configRequired := flagPassed("config") || configFromEnv
+	if err := initConfig(*configPath, configRequired); err != nil {
+		fatal("failed to load config", err)
+	}
+	cfg := global.Load()
+
+	// 初始化日志
+	if err := logger.Init(cfg.Log); err != nil {
+		fatal("failed to init logger", err)
+	}
+	slog.Info("config loaded", "path", *configPath, "config", common.RedactedConfig(cfg))
+	if err := i18n.SetDefault(cfg.I18n.DefaultLanguage); err != nil {
+		fatal("failed to init i18n", err)
+	}
+	if cfg.AI.APIKey == "" {
+		slog.Warn("ai.api_key is empty, set AI_NOTE_AI_API_KEY or ai.api_key_file if the provider requires authentication")
+	}
+
+	// 构造应用容器：提示词模板、模型客户端、反馈存储和业务服务，配置每次请求从当前快照读取
+	container, err := app.New(global.Load, app.Options{})
+	if err != nil {
+		fatal("failed to init application", err)
+	}
+	for _, t := range container.Prompts().List() {
+		slog.Info("prompt template loaded", "version_id", t.VersionID(), "source", t.Source)
+	}
+	warnMissingPromptVariants(container.Prompts(), cfg.Experiments)
+
+	// 初始化链路追踪
+	shutdownTracer, err := telemetry.InitTracer(cfg.Tracing)
+	if err != nil {
+		fatal("failed to init tracer", err)
+	}
+
+	// 后台任务组
+	jobs := lifecycle.NewGroup()
+
+	// 配置热加载：SIGHUP 或配置文件变化时重新加载
+	reloader := common.NewConfigReloader(*configPath, configRequired)
+	reloader.OnReload(func(_, newCfg *global.AppConfig) {
+		if err := logger.Apply(newCfg.Log); err != nil {
+			slog.Error("failed to apply reloaded log config", "error", err)
+		}
+		if err := i18n.SetDefault(newCfg.I18n.DefaultLanguage); err != nil {
+			slog.Error("failed to apply reloaded i18n config", "error", err)
+		}
+		// 提示词模板随配置一起重新加载，加载失败时保留旧模板
+		if prompts, err := container.ReloadPrompts(newCfg.Prompt.Dir); err != nil {
+			slog.Error("failed to reload prompt templates, keeping previous templates", "error", err)
+		} else {
+			warnMissingPromptVariants(prompts, newCfg.Experiments)
+		}
+	})
+	jobs.Go("config-reload-signal", reloader.WatchSignal)
+	if cfg.Reload.WatchFile {
+		interval := seconds(cfg.Reload.Interval, 0)
+		jobs.Go("config-reload-file", func(ctx context.Context) {
+			reloader.WatchFile(ctx, interval)
+		})
 	}
 
 	// 初始化路由
-	router := controller.NewRouter()
+	router := controller.NewRouter(container)
 	engine := router.Setup()
 
 	// 启动服务器
-	addr := fmt.Sprintf("%s:%d", global.Config.Server.Host, global.Config.Server.Port)
-	log.Printf("Server starting on %s", addr)
-	log.Printf("AI Service Base URL: %s", global.Config.AI.BaseURL)
-	log.Printf("Default Model: %s", global.Config.AI.DefaultModel)
-	
-	if err := engine.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := newHTTPServer(cfg.Server, engine)
+	if aiTimeout := time.Duration(cfg.AI.Timeout) * time.Second; srv.WriteTimeout <= aiTimeout {
+		slog.Warn("server write timeout is not greater than AI timeout, slow analyses may be cut off",
+			"write_timeout", srv.WriteTimeout.String(),
+			"ai_timeout", aiTimeout.String(),
+		)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal("failed to start server", err)
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining in-flight requests")
+	}
+	// 恢复默认信号处理，再次收到信号时立即退出
+	stop()
+
+	err = shutdown(srv, jobs, shutdownTracer)
+	if closeErr := container.Close(); closeErr != nil {
+		slog.Error("failed to close feedback store", "error", closeErr)
+	}
+	if err != nil {
+		slog.Error("graceful shutdown incomplete", "error", err)
+		os.Exit(1)
 	}
+	slog.Info("server stopped")
 }
 
 // initConfig 初始化配置
-func initConfig() error {
-	global.Config = &global.AppConfig{}
-	return common.LoadConfig("config.yaml", global.Config)
-}
\ No newline at end of file
+// 显式指定配置文件时文件必须存在，否则允许只通过环境变量配置
+func initConfig(path string, required bool) error {
+	cfg, err := common.LoadAppConfig(path, required)
+	if err != nil {
+		return err
+	}
+	global.Store(cfg)
+	return nil
+}
+
+// warnMissingPromptVariants 实验引用的模板变体不存在时告警，此时该变体会使用默认模板
+func warnMissingPromptVariants(prompts *prompt.Registry, experiments []global.ExperimentConfig) {
+	names := map[string]string{
+		experiment.TargetAnalysis: prompt.NameAnalysis,
+		experiment.TargetDialogue: prompt.NameDialogue,
+	}
+	for _, exp := range experiments {
+		for _, v := range exp.Variants {
+			if v.PromptVariant != "" && !prompts.HasVariant(names[exp.Target], v.PromptVariant) {
+				slog.Warn("experiment prompt variant not found, default template will be used",
+					"experiment", exp.Name,
+					"variant", v.Name,
+					"prompt_variant", v.PromptVariant,
+				)
+			}
+		}
+	}
+}
+
+// flagPassed 判断命令行参数是否被显式指定
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// envOrDefault 读取环境变量，不存在时返回默认值
+func envOrDefault(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// newHTTPServer 根据服务器配置创建 HTTP 服务器
+func newHTTPServer(cfg global.ServerConfig, handler http.Handler) *http.Server {
+	maxHeaderBytes := cfg.MaxHeaderBytes
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = defaultMaxHeaderBytes
+	}
+
+	return &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: seconds(cfg.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		ReadTimeout:       seconds(cfg.ReadTimeout, defaultReadTimeout),
+		WriteTimeout:      seconds(cfg.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       seconds(cfg.IdleTimeout, defaultIdleTimeout),
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+}
+
+// shutdown 停止接收新请求，在截止时间内等待进行中的请求和后台任务结束，最后刷新链路追踪数据
+func shutdown(srv *http.Server, jobs *lifecycle.Group, shutdownTracer telemetry.ShutdownFunc) error {
+	timeout := seconds(global.Load().Server.ShutdownTimeout, defaultShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	}
+	if err := jobs.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	// 链路追踪数据单独给予刷新时间，避免被前面的步骤耗尽
+	tracerCtx, tracerCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer tracerCancel()
+	if err := shutdownTracer(tracerCtx); err != nil {
+		errs = append(errs, fmt.Errorf("tracer: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// seconds 将秒数配置转换为时间间隔，非正数时使用默认值
+func seconds(value int, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return time.Duration(value) * time.Second
+}
+
+// fatal 记录错误日志并退出进程
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}